@@ -22,8 +22,17 @@
 package main
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
+	"github.com/alan-mat/awe/internal/auth"
+	"github.com/alan-mat/awe/internal/provider"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/telemetry"
+	"github.com/alan-mat/awe/internal/usage"
+	"github.com/alan-mat/awe/server"
 	"github.com/goccy/go-yaml"
 )
 
@@ -40,21 +49,278 @@ type qdrantConfig struct {
 }
 
 type workerConfig struct {
-	Workers int `yaml:"workers"`
+	Workers        int  `yaml:"workers"`
+	WatchWorkflows bool `yaml:"watch_workflows"`
+	// MetricsPort serves the Prometheus metrics, disabled if not set
+	MetricsPort int `yaml:"metrics_port"`
+	// TraceRetention is how long traces are kept, unless set by their workflow
+	TraceRetention time.Duration `yaml:"trace_retention"`
 }
 
 type serverConfig struct {
 	ListenHost string `yaml:"listen_host"`
 	ListenPort int    `yaml:"listen_port"`
+	// HTTPPort enables the HTTP/JSON gateway on the port
+	HTTPPort    int         `yaml:"http_port"`
+	CORSOrigins []string    `yaml:"cors_origins"`
+	Quotas      quotaConfig `yaml:"quotas"`
+	TLS         tlsConfig   `yaml:"tls"`
+	Auth        authConfig  `yaml:"auth"`
+	// MetricsPort serves the Prometheus metrics, disabled if not set
+	MetricsPort int `yaml:"metrics_port"`
+
+	CancelOnDisconnect bool `yaml:"cancel_on_disconnect"`
+}
+
+type tlsConfig struct {
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ClientCAFile       string `yaml:"client_ca_file"`
+	ClientCertOptional bool   `yaml:"client_cert_optional"`
+}
+
+type authConfig struct {
+	APIKeys []apiKeyConfig `yaml:"api_keys"`
+	JWT     *jwtConfig     `yaml:"jwt"`
+	// ClientCertificates authenticates clients by their TLS client certificate
+	ClientCertificates bool     `yaml:"client_certificates"`
+	AdminGroups        []string `yaml:"admin_groups"`
+}
+
+type apiKeyConfig struct {
+	Key string `yaml:"key"`
+	// KeyEnv is the environment variable holding the key
+	KeyEnv string   `yaml:"key_env"`
+	User   string   `yaml:"user"`
+	Groups []string `yaml:"groups"`
+}
+
+type jwtConfig struct {
+	Secret string `yaml:"secret"`
+	// SecretEnv is the environment variable holding the secret
+	SecretEnv     string `yaml:"secret_env"`
+	PublicKeyFile string `yaml:"public_key_file"`
+	Issuer        string `yaml:"issuer"`
+	Audience      string `yaml:"audience"`
+	UserClaim     string `yaml:"user_claim"`
+	GroupsClaim   string `yaml:"groups_claim"`
+}
+
+func (c authConfig) options() ([]auth.Option, error) {
+	var opts []auth.Option
+	if len(c.APIKeys) > 0 {
+		keys := make([]auth.APIKey, 0, len(c.APIKeys))
+		for _, kc := range c.APIKeys {
+			key := kc.Key
+			if kc.KeyEnv != "" {
+				key = os.Getenv(kc.KeyEnv)
+			}
+			if key == "" {
+				return nil, fmt.Errorf("api key of user '%s' is not set", kc.User)
+			}
+			keys = append(keys, auth.APIKey{
+				Key:       key,
+				Principal: auth.Principal{User: kc.User, Groups: kc.Groups},
+			})
+		}
+		opts = append(opts, auth.WithAPIKeys(keys...))
+	}
+
+	if c.JWT != nil {
+		jc := auth.JWTConfig{
+			Secret:      []byte(c.JWT.Secret),
+			Issuer:      c.JWT.Issuer,
+			Audience:    c.JWT.Audience,
+			UserClaim:   c.JWT.UserClaim,
+			GroupsClaim: c.JWT.GroupsClaim,
+		}
+		if c.JWT.SecretEnv != "" {
+			jc.Secret = []byte(os.Getenv(c.JWT.SecretEnv))
+		}
+		if c.JWT.PublicKeyFile != "" {
+			key, err := os.ReadFile(c.JWT.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read jwt public key: %w", err)
+			}
+			jc.PublicKey = key
+		}
+		opts = append(opts, auth.WithJWT(jc))
+	}
+
+	if c.ClientCertificates {
+		opts = append(opts, auth.WithClientCertificates())
+	}
+	if len(c.AdminGroups) > 0 {
+		opts = append(opts, auth.WithAdminGroups(c.AdminGroups...))
+	}
+	return opts, nil
+}
+
+type quotaConfig struct {
+	// User are the limits of every user, unless replaced in Users
+	User        limitsConfig            `yaml:"user"`
+	Users       map[string]limitsConfig `yaml:"users"`
+	Workflows   map[string]limitsConfig `yaml:"workflows"`
+	SlotTimeout time.Duration           `yaml:"slot_timeout"`
+}
+
+type limitsConfig struct {
+	RequestsPerMinute int   `yaml:"requests_per_minute"`
+	Concurrent        int   `yaml:"concurrent"`
+	DailyTokens       int64 `yaml:"daily_tokens"`
+}
+
+func (c quotaConfig) config() server.QuotaConfig {
+	quota := server.QuotaConfig{
+		User:        server.Limits(c.User),
+		Users:       make(map[string]server.Limits, len(c.Users)),
+		Workflows:   make(map[string]server.Limits, len(c.Workflows)),
+		SlotTimeout: c.SlotTimeout,
+	}
+	for user, l := range c.Users {
+		quota.Users[user] = server.Limits(l)
+	}
+	for workflow, l := range c.Workflows {
+		quota.Workflows[workflow] = server.Limits(l)
+	}
+	return quota
+}
+
+type providersConfig struct {
+	// Defaults maps capabilities like 'lm' or 'embedder' to provider refs
+	Defaults map[string]string `yaml:"defaults"`
+
+	// Instances are named providers, referenced like builtin providers
+	Instances map[string]providerInstanceConfig `yaml:"instances"`
+
+	// Pricing maps 'provider/model', a model or a provider to its price
+	Pricing map[string]priceConfig `yaml:"pricing"`
+}
+
+type priceConfig struct {
+	// Input and Output are prices per million tokens
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+	Call   float64 `yaml:"call"`
+	Unit   float64 `yaml:"unit"`
+}
+
+func (c providersConfig) prices() usage.Prices {
+	prices := make(usage.Prices, len(c.Pricing))
+	for key, pc := range c.Pricing {
+		prices[key] = usage.Price(pc)
+	}
+	return prices
+}
+
+type providerInstanceConfig struct {
+	// Type is the provider implementation, defaults to the instance name
+	Type    string `yaml:"type"`
+	BaseURL string `yaml:"base_url"`
+	APIKey  string `yaml:"api_key"`
+	// APIKeyEnv is the environment variable holding the API key
+	APIKeyEnv   string        `yaml:"api_key_env"`
+	Model       string        `yaml:"model"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Retries     *int          `yaml:"retries"`
+	Concurrency int           `yaml:"concurrency"`
+	// Dimensions is the vector size of an embedding model
+	Dimensions uint `yaml:"dimensions"`
+}
+
+func (c providersConfig) instances() map[string]provider.Instance {
+	instances := make(map[string]provider.Instance, len(c.Instances))
+	for name, ic := range c.Instances {
+		apiKey := ic.APIKey
+		if ic.APIKeyEnv != "" {
+			apiKey = os.Getenv(ic.APIKeyEnv)
+			if apiKey == "" {
+				slog.Warn("api key environment variable is not set", "provider", name, "env", ic.APIKeyEnv)
+			}
+		}
+
+		opts := []options.Option{
+			options.WithModel(ic.Model),
+			options.WithBaseURL(ic.BaseURL),
+			options.WithAPIKey(apiKey),
+			options.WithTimeout(ic.Timeout),
+			options.WithConcurrency(ic.Concurrency),
+			options.WithDimensions(ic.Dimensions),
+		}
+		if ic.Retries != nil {
+			opts = append(opts, options.WithMaxRetries(*ic.Retries))
+		}
+
+		instances[name] = provider.Instance{
+			Type:    ic.Type,
+			Options: opts,
+		}
+	}
+	return instances
+}
+
+type logConfig struct {
+	// Level is one of debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is either text or json
+	Format string `yaml:"format"`
+}
+
+// handler returns the log handler writing to stdout.
+func (c logConfig) handler() (slog.Handler, error) {
+	var level slog.Level
+	if c.Level != "" {
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level '%s'", c.Level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch c.Format {
+	case "", "text":
+		return slog.NewTextHandler(os.Stdout, opts), nil
+	case "json":
+		return slog.NewJSONHandler(os.Stdout, opts), nil
+	}
+	return nil, fmt.Errorf("invalid log format '%s'", c.Format)
+}
+
+type telemetryConfig struct {
+	// Exporter is one of otlp, otlphttp, stdout or none
+	Exporter string            `yaml:"exporter"`
+	Endpoint string            `yaml:"endpoint"`
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+	// SampleRatio is the fraction of traces sampled, defaults to 1
+	SampleRatio *float64 `yaml:"sample_ratio"`
+}
+
+func (c telemetryConfig) config() telemetry.Config {
+	conf := telemetry.Config{
+		Exporter:    c.Exporter,
+		Endpoint:    c.Endpoint,
+		Insecure:    c.Insecure,
+		Headers:     c.Headers,
+		SampleRatio: 1,
+	}
+	if c.SampleRatio != nil {
+		conf.SampleRatio = *c.SampleRatio
+	}
+	return conf
 }
 
 type config struct {
 	Server serverConfig `yaml:"server"`
 	Worker workerConfig `yaml:"worker"`
 
+	Log       logConfig       `yaml:"log"`
+	Telemetry telemetryConfig `yaml:"telemetry"`
+
 	Transport   redisConfig  `yaml:"transport"`
 	VectorStore qdrantConfig `yaml:"vector_store"`
 
+	Providers providersConfig `yaml:"providers"`
+
 	WorkflowConfigPath string `yaml:"workflows"`
 }
 
@@ -22,12 +22,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
 	"strings"
+	"time"
 
+	wconfig "github.com/alan-mat/awe/internal/config"
+	"github.com/alan-mat/awe/internal/modules"
+	"github.com/alan-mat/awe/internal/tasks"
+	"github.com/alan-mat/awe/internal/telemetry"
+	"github.com/alan-mat/awe/internal/transport"
 	"github.com/alan-mat/awe/server"
 	"github.com/alan-mat/awe/worker"
 	"github.com/alexflint/go-arg"
@@ -43,21 +50,23 @@ type serveCmd struct{}
 
 type workerCmd struct{}
 
-/* type workflowCmd struct {
-	List    *workflowListCmd `arg:"subcommand" help:"list available workflows"`
-	Execute *workflowExecCmd `arg:"subcommand:exec" help:"execute a workflow on the server"`
-	Host    string           `arg:"--host,-H" default:"localhost" help:"server host address"`
-	Port    uint             `arg:"--port,-p" default:"50051" help:"server port"`
-}
+type runCmd struct{}
 
-type workflowListCmd struct{}
+type workflowCmd struct {
+	Validate *workflowValidateCmd `arg:"subcommand:validate" help:"validate workflow definitions"`
+	// List    *workflowListCmd `arg:"subcommand" help:"list available workflows"`
+	// Execute *workflowExecCmd `arg:"subcommand:exec" help:"execute a workflow on the server"`
+}
 
-type workflowExecCmd struct{} */
+type workflowValidateCmd struct {
+	Paths []string `arg:"positional" help:"workflow files or directories to validate, defaults to the workflows of the config file"`
+}
 
 type args struct {
-	Server *serveCmd  `arg:"subcommand:serve" help:"start the AWE server"`
-	Worker *workerCmd `arg:"subcommand:work" help:"start the AWE worker"`
-	// Workflow *workflowCmd `arg:"subcommand" help:"manage workflows"`
+	Server   *serveCmd    `arg:"subcommand:serve" help:"start the AWE server"`
+	Worker   *workerCmd   `arg:"subcommand:work" help:"start the AWE worker"`
+	Run      *runCmd      `arg:"subcommand:run" help:"start the AWE server and worker in a single process, without Redis or Qdrant"`
+	Workflow *workflowCmd `arg:"subcommand:workflow" help:"manage workflows"`
 
 	ConfigPath string `arg:"-c,--config" default:"awe-config.yaml" help:"path to the config file" placeholder:""`
 }
@@ -89,36 +98,65 @@ func main() {
 		fmt.Println(err)
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	slog.SetDefault(logger)
+	var logConf logConfig
+	if conf != nil {
+		logConf = conf.Log
+	}
+	handler, err := logConf.handler()
+	if err != nil {
+		log.Fatalf("invalid log config: %v", err)
+	}
+	slog.SetDefault(slog.New(handler))
 
 	var cmd func(any, *config) error
+	// service is the name of the traced service, commands without one are not traced
+	var service string
 
 	switch p.Subcommand().(type) {
 	case *serveCmd:
 		cmd = startServer
+		service = "awe-server"
 	case *workerCmd:
 		cmd = startWorker
+		service = "awe-worker"
+	case *runCmd:
+		cmd = startEmbedded
+		service = "awe"
+	case *workflowValidateCmd:
+		cmd = validateWorkflows
+	case *workflowCmd:
+		p.FailSubcommand("missing workflow command", "workflow")
 	default:
 		p.FailSubcommand("unrecognized command", p.SubcommandNames()...)
 	}
 
-	cmd(p.Subcommand(), conf)
+	shutdown := func(context.Context) error { return nil }
+	if service != "" && conf != nil {
+		shutdown, err = telemetry.Setup(context.Background(), conf.Telemetry.config(), service)
+		if err != nil {
+			slog.Error("failed to set up telemetry", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	err = cmd(p.Subcommand(), conf)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		slog.Warn("failed to flush telemetry", "err", err)
+	}
+
+	if err != nil {
+		slog.Error("command failed", "err", err)
+		os.Exit(1)
+	}
 }
 
 func startServer(args any, conf *config) error {
-	var serverConfig server.ServerConfig
-	if conf == nil {
-		serverConfig = server.DefaultConfig()
-	} else {
-		serverConfig = server.ServerConfig{
-			ListenHost:    conf.Server.ListenHost,
-			ListenPort:    conf.Server.ListenPort,
-			RedisAddr:     conf.Transport.Addr,
-			RedisUsername: conf.Transport.Username,
-			RedisPassword: conf.Transport.Password,
-			RedisDB:       conf.Transport.DB,
-		}
+	serverConfig, err := newServerConfig(conf)
+	if err != nil {
+		return err
 	}
 
 	srv := server.New(serverConfig)
@@ -126,22 +164,7 @@ func startServer(args any, conf *config) error {
 }
 
 func startWorker(args any, conf *config) error {
-	var workerConfig worker.WorkerConfig
-	var workflows string
-	if conf == nil {
-		workerConfig = worker.DefaultConfig()
-	} else {
-		workerConfig = worker.WorkerConfig{
-			Workers:       conf.Worker.Workers,
-			RedisAddr:     conf.Transport.Addr,
-			RedisUsername: conf.Transport.Username,
-			RedisPassword: conf.Transport.Password,
-			RedisDB:       conf.Transport.DB,
-			QdrantHost:    conf.VectorStore.Host,
-			QdrantPort:    conf.VectorStore.Port,
-		}
-		workflows = conf.WorkflowConfigPath
-	}
+	workerConfig, workflows := newWorkerConfig(conf)
 
 	worker := worker.New(workerConfig)
 	err := worker.RegisterWorkflows(workflows)
@@ -151,3 +174,146 @@ func startWorker(args any, conf *config) error {
 
 	return worker.Start()
 }
+
+// startEmbedded runs the server and the worker in a single process,
+// using an in-memory transport, task queue and vector store.
+func startEmbedded(args any, conf *config) error {
+	serverConfig, err := newServerConfig(conf)
+	if err != nil {
+		return err
+	}
+	workerConfig, workflows := newWorkerConfig(conf)
+	// the server and worker share the metrics of the process
+	if workerConfig.MetricsPort == serverConfig.MetricsPort {
+		workerConfig.MetricsPort = 0
+	}
+
+	w := worker.New(workerConfig)
+	err = w.RegisterWorkflows(workflows)
+	if err != nil {
+		return err
+	}
+
+	t := transport.NewMemoryTransport()
+	q := tasks.NewMemoryQueue()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := w.RunInMemory(ctx, q, t); err != nil && ctx.Err() == nil {
+			slog.Error("in-memory worker stopped", "err", err)
+		}
+	}()
+
+	srv := server.NewWithBackend(serverConfig, t, q)
+	return srv.Serve()
+}
+
+// validateWorkflows reports all problems found in the given workflow files.
+func validateWorkflows(args any, conf *config) error {
+	if err := modules.RegisterAll(); err != nil {
+		return err
+	}
+
+	paths := args.(*workflowValidateCmd).Paths
+	if len(paths) == 0 {
+		_, path := newWorkerConfig(conf)
+		if path == "" {
+			return fmt.Errorf("no workflow files given and no config file found")
+		}
+		paths = []string{path}
+	}
+
+	var files []string
+	for _, path := range paths {
+		found, err := wconfig.WorkflowFiles(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+	}
+
+	failed := 0
+	configs := make([]wconfig.WorkflowConfig, 0, len(files))
+	for _, path := range files {
+		wc, err := wconfig.ReadConfig(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			continue
+		}
+		configs = append(configs, wc)
+
+		problems := wc.Validate()
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, p)
+		}
+		if len(problems) > 0 {
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %d workflow(s) valid\n", path, len(wc.Workflows))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workflow file(s) invalid", failed, len(files))
+	}
+
+	// workflows are loaded together, calls and versions are checked across files
+	return wconfig.CheckWorkflows(configs)
+}
+
+func newServerConfig(conf *config) (server.ServerConfig, error) {
+	if conf == nil {
+		return server.DefaultConfig(), nil
+	}
+
+	authOpts, err := conf.Server.Auth.options()
+	if err != nil {
+		return server.ServerConfig{}, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	return server.ServerConfig{
+		ListenHost:    conf.Server.ListenHost,
+		ListenPort:    conf.Server.ListenPort,
+		HTTPPort:      conf.Server.HTTPPort,
+		CORSOrigins:   conf.Server.CORSOrigins,
+		MetricsPort:   conf.Server.MetricsPort,
+		RedisAddr:     conf.Transport.Addr,
+		RedisUsername: conf.Transport.Username,
+		RedisPassword: conf.Transport.Password,
+		RedisDB:       conf.Transport.DB,
+
+		Quota:              conf.Server.Quotas.config(),
+		CancelOnDisconnect: conf.Server.CancelOnDisconnect,
+
+		TLS:  server.TLSConfig(conf.Server.TLS),
+		Auth: authOpts,
+	}, nil
+}
+
+func newWorkerConfig(conf *config) (worker.WorkerConfig, string) {
+	if conf == nil {
+		return worker.DefaultConfig(), ""
+	}
+
+	workerConfig := worker.WorkerConfig{
+		Workers:       conf.Worker.Workers,
+		RedisAddr:     conf.Transport.Addr,
+		RedisUsername: conf.Transport.Username,
+		RedisPassword: conf.Transport.Password,
+		RedisDB:       conf.Transport.DB,
+		QdrantHost:    conf.VectorStore.Host,
+		QdrantPort:    conf.VectorStore.Port,
+		MetricsPort:   conf.Worker.MetricsPort,
+
+		WatchWorkflows: conf.Worker.WatchWorkflows,
+		TraceRetention: conf.Worker.TraceRetention,
+
+		Providers:        conf.Providers.instances(),
+		ProviderDefaults: conf.Providers.Defaults,
+		Prices:           conf.Providers.prices(),
+	}
+	return workerConfig, conf.WorkflowConfigPath
+}
@@ -30,11 +30,15 @@ type ChatMessageRole int
 const (
 	RoleUser ChatMessageRole = iota
 	RoleAssistant
+	RoleTool
+	RoleSystem
 )
 
 var roleName = map[ChatMessageRole]string{
 	RoleUser:      "user",
 	RoleAssistant: "assistant",
+	RoleTool:      "tool",
+	RoleSystem:    "system",
 }
 
 func (r ChatMessageRole) String() string {
@@ -44,6 +48,12 @@ func (r ChatMessageRole) String() string {
 type ChatMessage struct {
 	Role    ChatMessageRole
 	Content string
+
+	// ToolCalls are the calls requested by the model in assistant messages.
+	ToolCalls []*ToolCall
+	// ToolCallID and ToolName identify the call answered by tool messages.
+	ToolCallID string
+	ToolName   string
 }
 
 func ParseChatHistory(h []*pb.ChatMessage) []*ChatMessage {
@@ -52,6 +62,7 @@ func ParseChatHistory(h []*pb.ChatMessage) []*ChatMessage {
 		pb.ChatRole_ROLE_UNSPECIFIED: RoleUser,
 		pb.ChatRole_USER:             RoleUser,
 		pb.ChatRole_ASSISTANT:        RoleAssistant,
+		pb.ChatRole_SYSTEM:           RoleSystem,
 	}
 	for i, m := range h {
 		chatmsg := &ChatMessage{
@@ -62,3 +73,25 @@ func ParseChatHistory(h []*pb.ChatMessage) []*ChatMessage {
 	}
 	return msgs
 }
+
+// ChatHistoryToProto is the inverse of ParseChatHistory,
+// tool messages have no protobuf representation and are left out.
+func ChatHistoryToProto(h []*ChatMessage) []*pb.ChatMessage {
+	msgs := make([]*pb.ChatMessage, 0, len(h))
+	rolesMap := map[ChatMessageRole]pb.ChatRole{
+		RoleUser:      pb.ChatRole_USER,
+		RoleAssistant: pb.ChatRole_ASSISTANT,
+		RoleSystem:    pb.ChatRole_SYSTEM,
+	}
+	for _, m := range h {
+		role, ok := rolesMap[m.Role]
+		if !ok {
+			continue
+		}
+		msgs = append(msgs, &pb.ChatMessage{
+			Role:    role,
+			Content: m.Content,
+		})
+	}
+	return msgs
+}
@@ -23,8 +23,6 @@ package api
 
 import (
 	"context"
-	"errors"
-	"io"
 )
 
 type ChatRequest struct {
@@ -35,6 +33,8 @@ type ChatRequest struct {
 	ModelName    string
 	SystemPrompt string
 	History      []*ChatMessage
+	// Tools may be called by the model, see StreamEventToolCall.
+	Tools []*Tool
 }
 
 type GenerationRequest struct {
@@ -55,60 +55,18 @@ func FromPrompt(prompt string) *GenerationRequest {
 	}
 }
 
+// CompletionStream streams the response of a language model as events.
+// Recv returns io.EOF once the stream is complete.
 type CompletionStream interface {
-	Recv() (string, error)
+	Recv() (*StreamEvent, error)
 	Close() error
 }
 
-type completionStreamPayload struct {
-	content string
-	err     error
-}
-
 // StreamReadAll receives from a completion stream accumulating the results
-// and returning the streamed chunks as a whole. This function will return an error
-// if one is received from the CompletionStream. Calling this function will always
-// close the underlying stream.
+// and returning the streamed content as a whole. This function will return an error
+// if one is received from the CompletionStream, along with the content received
+// before the error. Calling this function will always close the underlying stream.
 func StreamReadAll(ctx context.Context, stream CompletionStream) (string, error) {
-	defer stream.Close()
-	dataChan := make(chan completionStreamPayload)
-
-	go func() {
-		defer close(dataChan)
-
-		for {
-			chunk, err := stream.Recv()
-
-			if errors.Is(err, io.EOF) {
-				return
-			}
-
-			if err != nil {
-				dataChan <- completionStreamPayload{err: err}
-				return
-			}
-
-			dataChan <- completionStreamPayload{content: chunk}
-		}
-	}()
-
-	var acc string
-
-	for {
-		select {
-		case <-ctx.Done():
-			return acc, nil
-		case payload, ok := <-dataChan:
-			if !ok {
-				// data stream closed
-				return acc, nil
-			}
-
-			if payload.err != nil {
-				return acc, nil
-			}
-
-			acc += payload.content
-		}
-	}
+	c, err := ReadCompletion(ctx, stream)
+	return c.Content, err
 }
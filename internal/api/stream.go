@@ -0,0 +1,245 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type StreamEventType int
+
+const (
+	// StreamEventContent carries a chunk of the generated text.
+	StreamEventContent StreamEventType = iota
+	// StreamEventToolCall carries a complete tool call requested by the model.
+	StreamEventToolCall
+	// StreamEventUsage carries the tokens used by the request. Providers which
+	// report usage more than once send the running total, the last event counts.
+	StreamEventUsage
+	// StreamEventFinish carries the reason the model stopped generating.
+	StreamEventFinish
+)
+
+var streamEventTypeName = map[StreamEventType]string{
+	StreamEventContent:  "content",
+	StreamEventToolCall: "tool_call",
+	StreamEventUsage:    "usage",
+	StreamEventFinish:   "finish",
+}
+
+func (t StreamEventType) String() string {
+	return streamEventTypeName[t]
+}
+
+// FinishReason is the reason a model stopped generating,
+// normalized across providers.
+type FinishReason string
+
+const (
+	FinishReasonStop          FinishReason = "stop"
+	FinishReasonLength        FinishReason = "length"
+	FinishReasonToolCalls     FinishReason = "tool_calls"
+	FinishReasonContentFilter FinishReason = "content_filter"
+	FinishReasonOther         FinishReason = "other"
+)
+
+// Usage counts the tokens used by a completion.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// StreamEvent is an event received from a CompletionStream. Only the field
+// matching the Type of the event is set, apart from Model which is set on any
+// event where the provider reports the model which served the request.
+//
+// Errors are not sent as events but returned by Recv, errors reported by
+// the provider in the stream are returned as *StreamError.
+type StreamEvent struct {
+	Type StreamEventType
+
+	Content      string
+	ToolCall     *ToolCall
+	Usage        *Usage
+	FinishReason FinishReason
+
+	Model string
+}
+
+func ContentEvent(content string) *StreamEvent {
+	return &StreamEvent{Type: StreamEventContent, Content: content}
+}
+
+func ToolCallEvent(tc *ToolCall) *StreamEvent {
+	return &StreamEvent{Type: StreamEventToolCall, ToolCall: tc}
+}
+
+func UsageEvent(u Usage, model string) *StreamEvent {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.InputTokens + u.OutputTokens
+	}
+	return &StreamEvent{Type: StreamEventUsage, Usage: &u, Model: model}
+}
+
+func FinishEvent(reason FinishReason, model string) *StreamEvent {
+	return &StreamEvent{Type: StreamEventFinish, FinishReason: reason, Model: model}
+}
+
+// StreamError is an error reported by the provider while streaming,
+// after the request itself succeeded.
+type StreamError struct {
+	Code    string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("completion stream failed: %s", e.Message)
+	}
+	return fmt.Sprintf("completion stream failed: %s: %s", e.Code, e.Message)
+}
+
+// StreamEventQueue buffers the events of streams which
+// receive several events from the provider at once.
+type StreamEventQueue struct {
+	events []*StreamEvent
+}
+
+func (q *StreamEventQueue) Push(events ...*StreamEvent) {
+	q.events = append(q.events, events...)
+}
+
+// Pop removes and returns the first event of the queue, if any.
+func (q *StreamEventQueue) Pop() (*StreamEvent, bool) {
+	if len(q.events) == 0 {
+		return nil, false
+	}
+	ev := q.events[0]
+	q.events = q.events[1:]
+	return ev, true
+}
+
+// Completion is the result of a completion stream received as a whole.
+type Completion struct {
+	Content   string
+	ToolCalls []*ToolCall
+	// Usage is nil if the provider did not report usage.
+	Usage        *Usage
+	FinishReason FinishReason
+	Model        string
+}
+
+// Add applies the event ev to the completion.
+func (c *Completion) Add(ev *StreamEvent) {
+	switch ev.Type {
+	case StreamEventContent:
+		c.Content += ev.Content
+	case StreamEventToolCall:
+		c.ToolCalls = append(c.ToolCalls, ev.ToolCall)
+	case StreamEventUsage:
+		c.Usage = ev.Usage
+	case StreamEventFinish:
+		c.FinishReason = ev.FinishReason
+	}
+	if ev.Model != "" {
+		c.Model = ev.Model
+	}
+}
+
+// Truncated reports whether the model stopped because it reached its token limit.
+func (c *Completion) Truncated() bool {
+	return c.FinishReason == FinishReasonLength
+}
+
+type streamEventPayload struct {
+	event *StreamEvent
+	err   error
+}
+
+// ReadCompletion receives all events of a completion stream and returns them as
+// a Completion. If the stream fails or ctx is done, the completion received so far
+// is returned along with the error. Calling this function will always close the
+// underlying stream.
+func ReadCompletion(ctx context.Context, stream CompletionStream) (*Completion, error) {
+	defer stream.Close()
+	dataChan := make(chan streamEventPayload)
+
+	go func() {
+		defer close(dataChan)
+
+		for {
+			ev, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			select {
+			case dataChan <- streamEventPayload{event: ev, err: err}:
+			case <-ctx.Done():
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	c := &Completion{}
+	for {
+		select {
+		case <-ctx.Done():
+			return c, ctx.Err()
+		case payload, ok := <-dataChan:
+			if !ok {
+				// data stream closed
+				return c, nil
+			}
+
+			if payload.err != nil {
+				return c, payload.err
+			}
+
+			c.Add(payload.event)
+		}
+	}
+}
+
+// ParseFinishReason normalizes a finish reason in the form used by
+// the OpenAI API, which is followed by many other providers.
+func ParseFinishReason(reason string) FinishReason {
+	switch reason {
+	case "stop":
+		return FinishReasonStop
+	case "length":
+		return FinishReasonLength
+	case "tool_calls", "function_call":
+		return FinishReasonToolCalls
+	case "content_filter":
+		return FinishReasonContentFilter
+	}
+	return FinishReasonOther
+}
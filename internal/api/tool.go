@@ -0,0 +1,110 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Tool describes a function which the model may call instead of answering.
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is an object schema of the arguments of the tool.
+	Parameters *Schema
+}
+
+// ToolCall is a call of a tool requested by the model,
+// it is received as a StreamEventToolCall.
+type ToolCall struct {
+	// ID identifies the call, so that its result can be matched to it.
+	// Providers which do not identify calls leave it empty.
+	ID        string
+	Name      string
+	Arguments map[string]any
+}
+
+// ParseToolArguments decodes the JSON encoded arguments of a tool call.
+func ParseToolArguments(arguments string) (map[string]any, error) {
+	args := make(map[string]any)
+	if arguments == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil, fmt.Errorf("invalid tool call arguments: %w", err)
+	}
+	return args, nil
+}
+
+// ToolCallAccumulator assembles tool calls which are streamed in fragments
+// identified by the index of the call, like in the OpenAI chat completions API.
+type ToolCallAccumulator struct {
+	indices   []int
+	calls     map[int]*ToolCall
+	arguments map[int]string
+}
+
+// Add appends a fragment of the tool call at index.
+func (a *ToolCallAccumulator) Add(index int, id, name, arguments string) {
+	if a.calls == nil {
+		a.calls = make(map[int]*ToolCall)
+		a.arguments = make(map[int]string)
+	}
+
+	call, ok := a.calls[index]
+	if !ok {
+		call = &ToolCall{}
+		a.calls[index] = call
+		a.indices = append(a.indices, index)
+	}
+	if id != "" {
+		call.ID = id
+	}
+	call.Name += name
+	a.arguments[index] += arguments
+}
+
+// Len returns the number of tool calls added so far.
+func (a *ToolCallAccumulator) Len() int {
+	return len(a.indices)
+}
+
+// Flush returns the assembled tool calls ordered by their index
+// and resets the accumulator.
+func (a *ToolCallAccumulator) Flush() ([]*ToolCall, error) {
+	defer func() { *a = ToolCallAccumulator{} }()
+
+	indices := slices.Sorted(slices.Values(a.indices))
+
+	calls := make([]*ToolCall, 0, len(indices))
+	for _, i := range indices {
+		args, err := ParseToolArguments(a.arguments[i])
+		if err != nil {
+			return nil, fmt.Errorf("tool call '%s': %w", a.calls[i].Name, err)
+		}
+		a.calls[i].Arguments = args
+		calls = append(calls, a.calls[i])
+	}
+	return calls, nil
+}
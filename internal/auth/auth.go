@@ -0,0 +1,215 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package auth authenticates the clients of the AWE service and
+// authorizes their access to workflows.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrNoCredentials   = errors.New("no credentials given")
+	ErrAccessDenied    = errors.New("access denied")
+)
+
+// Principal is an authenticated client.
+type Principal struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+	// Admin principals may read the traces and usage of all users.
+	Admin bool `json:"admin,omitempty"`
+}
+
+// InGroup reports whether the principal is a member of any of the groups.
+func (p *Principal) InGroup(groups ...string) bool {
+	if p == nil {
+		return false
+	}
+	for _, g := range groups {
+		if slices.Contains(p.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a static key authenticating a principal.
+type APIKey struct {
+	Key string
+	Principal
+}
+
+// Authenticator authenticates clients by their API key, bearer token or client
+// certificate. A nil Authenticator disables authentication.
+type Authenticator struct {
+	apiKeys     []APIKey
+	jwt         *jwtVerifier
+	clientCerts bool
+	adminGroups []string
+}
+
+type Option func(*Authenticator) error
+
+// WithAPIKeys authenticates clients sending one of the keys.
+func WithAPIKeys(keys ...APIKey) Option {
+	return func(a *Authenticator) error {
+		for _, k := range keys {
+			if k.Key == "" {
+				return errors.New("api key of user '" + k.User + "' is empty")
+			}
+			if k.User == "" {
+				return errors.New("api key without user")
+			}
+		}
+		a.apiKeys = append(a.apiKeys, keys...)
+		return nil
+	}
+}
+
+// WithJWT authenticates clients sending a bearer token verified with c.
+func WithJWT(c JWTConfig) Option {
+	return func(a *Authenticator) error {
+		v, err := newJWTVerifier(c)
+		if err != nil {
+			return err
+		}
+		a.jwt = v
+		return nil
+	}
+}
+
+// WithClientCertificates authenticates clients by their verified TLS client
+// certificate, using its common name as user and its organizational units as groups.
+func WithClientCertificates() Option {
+	return func(a *Authenticator) error {
+		a.clientCerts = true
+		return nil
+	}
+}
+
+// WithAdminGroups makes members of any of the groups admins.
+func WithAdminGroups(groups ...string) Option {
+	return func(a *Authenticator) error {
+		a.adminGroups = append(a.adminGroups, groups...)
+		return nil
+	}
+}
+
+// New returns an Authenticator configured by opts, or nil if opts configure no
+// way to authenticate, i.e. authentication is disabled.
+func New(opts ...Option) (*Authenticator, error) {
+	a := &Authenticator{}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.apiKeys) == 0 && a.jwt == nil && !a.clientCerts {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// Authenticate returns the principal of the given token, which is an API key
+// or a JWT, or of the verified client certificate chains if no token is given.
+func (a *Authenticator) Authenticate(token string, chains [][]*x509.Certificate) (*Principal, error) {
+	var p *Principal
+	switch {
+	case token != "":
+		p = a.authenticateToken(token)
+	case a.clientCerts && len(chains) > 0 && len(chains[0]) > 0:
+		p = principalFromCert(chains[0][0])
+	default:
+		return nil, ErrNoCredentials
+	}
+	if p == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	p.Admin = p.Admin || p.InGroup(a.adminGroups...)
+	return p, nil
+}
+
+func (a *Authenticator) authenticateToken(token string) *Principal {
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k.Key), []byte(token)) == 1 {
+			p := k.Principal
+			p.Groups = slices.Clone(k.Groups)
+			return &p
+		}
+	}
+
+	if a.jwt != nil && strings.Count(token, ".") == 2 {
+		p, err := a.jwt.verify(token)
+		if err != nil {
+			return nil
+		}
+		return p
+	}
+	return nil
+}
+
+func principalFromCert(cert *x509.Certificate) *Principal {
+	if cert.Subject.CommonName == "" {
+		return nil
+	}
+	return &Principal{
+		User:   cert.Subject.CommonName,
+		Groups: slices.Clone(cert.Subject.OrganizationalUnit),
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, or nil if it is not authenticated.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Access restricts a workflow to users and members of groups.
+type Access struct {
+	Users  []string
+	Groups []string
+}
+
+// Allows reports whether the principal p is one of the users or a member
+// of one of the groups. A nil principal is never allowed, servers running
+// without authentication do not restrict access, see executor.Workflow.Allows.
+func (a Access) Allows(p *Principal) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(a.Users, p.User) || p.InGroup(a.Groups...)
+}
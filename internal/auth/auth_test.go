@@ -0,0 +1,50 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package auth
+
+import "testing"
+
+func TestAccessAllows(t *testing.T) {
+	access := Access{Users: []string{"alice"}, Groups: []string{"staff"}}
+
+	tests := []struct {
+		name string
+		p    *Principal
+		want bool
+	}{
+		{"user", &Principal{User: "alice"}, true},
+		{"group", &Principal{User: "bob", Groups: []string{"guests", "staff"}}, true},
+		{"other user", &Principal{User: "bob", Groups: []string{"guests"}}, false},
+		{"no principal", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := access.Allows(tt.p); got != tt.want {
+				t.Errorf("Allows(%+v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+
+	if (Access{}).Allows(&Principal{User: "alice"}) {
+		t.Error("empty access allows principal")
+	}
+}
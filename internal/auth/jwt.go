@@ -0,0 +1,144 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig configures the verification of JSON Web Tokens.
+// Either Secret or PublicKey must be set.
+type JWTConfig struct {
+	// Secret verifies tokens signed with HMAC (HS256, HS384, HS512).
+	Secret []byte
+	// PublicKey verifies tokens signed with RSA, ECDSA or Ed25519,
+	// it is PEM encoded in PKIX or PKCS#1 form.
+	PublicKey []byte
+
+	// Issuer and Audience are required to match the claims of tokens, if set.
+	Issuer   string
+	Audience string
+
+	// UserClaim and GroupsClaim are the claims holding the user and the groups
+	// of the principal, defaults to 'sub' and 'groups'. Groups are either a
+	// list of strings or a string of groups separated by spaces.
+	UserClaim   string
+	GroupsClaim string
+}
+
+type jwtVerifier struct {
+	parser *jwt.Parser
+	key    any
+	config JWTConfig
+}
+
+func newJWTVerifier(c JWTConfig) (*jwtVerifier, error) {
+	var key any
+	var methods []string
+	switch {
+	case len(c.Secret) > 0 && len(c.PublicKey) > 0:
+		return nil, errors.New("jwt secret and public key are mutually exclusive")
+	case len(c.Secret) > 0:
+		key = c.Secret
+		methods = []string{"HS256", "HS384", "HS512"}
+	case len(c.PublicKey) > 0:
+		pub, err := parsePublicKey(c.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		key = pub
+		methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+	default:
+		return nil, errors.New("jwt requires a secret or public key")
+	}
+
+	if c.UserClaim == "" {
+		c.UserClaim = "sub"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if c.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(c.Issuer))
+	}
+	if c.Audience != "" {
+		opts = append(opts, jwt.WithAudience(c.Audience))
+	}
+
+	return &jwtVerifier{
+		parser: jwt.NewParser(opts...),
+		key:    key,
+		config: c,
+	}, nil
+}
+
+func (v *jwtVerifier) verify(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return v.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, _ := claims[v.config.UserClaim].(string)
+	if user == "" {
+		return nil, fmt.Errorf("token has no '%s' claim", v.config.UserClaim)
+	}
+
+	p := &Principal{User: user}
+	switch groups := claims[v.config.GroupsClaim].(type) {
+	case string:
+		p.Groups = strings.Fields(groups)
+	case []any:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				p.Groups = append(p.Groups, s)
+			}
+		}
+	}
+	return p, nil
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt public key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("jwt public key is neither a PKIX nor a PKCS#1 public key")
+}
@@ -0,0 +1,216 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/alan-mat/awe/internal/registry"
+)
+
+const workflowCallModule = "workflow.Call"
+
+// expandWorkflowCalls rewrites nodes using the 'workflow' shorthand
+// into nodes of the workflow.Call module.
+func expandWorkflowCalls(nodes []WorkflowNode) {
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Workflow != "" {
+			if n.Module == "" {
+				n.Module = workflowCallModule
+			}
+			args := make(map[string]any, len(n.Args)+1)
+			maps.Copy(args, n.Args)
+			args["workflow"] = n.Workflow
+			n.Args = args
+		}
+
+		expandWorkflowCalls(n.Nodes)
+		expandWorkflowCalls(n.Fallback)
+		for _, r := range n.Routes {
+			expandWorkflowCalls(r.Nodes)
+		}
+		for _, b := range n.Branches {
+			expandWorkflowCalls(b.Nodes)
+		}
+	}
+}
+
+// workflowCalls returns the references of all workflows called by the nodes.
+func workflowCalls(nodes []WorkflowNode) []string {
+	var calls []string
+	for _, n := range nodes {
+		if n.Module == workflowCallModule {
+			if ref, ok := n.Args["workflow"].(string); ok {
+				calls = append(calls, ref)
+			}
+		}
+
+		calls = append(calls, workflowCalls(n.Nodes)...)
+		calls = append(calls, workflowCalls(n.Fallback)...)
+		for _, r := range n.Routes {
+			calls = append(calls, workflowCalls(r.Nodes)...)
+		}
+		for _, b := range n.Branches {
+			calls = append(calls, workflowCalls(b.Nodes)...)
+		}
+	}
+	return calls
+}
+
+// workflowIndex resolves workflow references like the registry does,
+// a name without a version refers to the default version.
+type workflowIndex struct {
+	versions map[string][]string
+	defaults map[string]string
+}
+
+func newWorkflowIndex(wfs []Workflow) (workflowIndex, error) {
+	idx := workflowIndex{
+		versions: make(map[string][]string),
+		defaults: make(map[string]string),
+	}
+	for _, w := range wfs {
+		version, err := WorkflowVersion(w)
+		if err != nil {
+			return workflowIndex{}, fmt.Errorf("invalid workflow '%s': %w", w.Identifier, err)
+		}
+		idx.versions[w.Identifier] = append(idx.versions[w.Identifier], version)
+		if w.Default {
+			idx.defaults[w.Identifier] = version
+		}
+	}
+	return idx, nil
+}
+
+// resolve returns the 'name@version' reference of the workflow referred to by ref.
+func (idx workflowIndex) resolve(ref string) (string, bool) {
+	name, version := registry.ParseWorkflowRef(ref)
+	versions, ok := idx.versions[name]
+	if !ok {
+		return "", false
+	}
+
+	if version == "" {
+		if v, ok := idx.defaults[name]; ok {
+			version = v
+		} else if len(versions) == 1 {
+			version = versions[0]
+		}
+	}
+	if !slices.Contains(versions, version) {
+		return "", false
+	}
+	return name + "@" + version, true
+}
+
+// callGraph returns the resolved workflow calls of each workflow by 'name@version'.
+// Calls which can not be resolved are passed to unresolved, if not nil.
+func callGraph(wfs []Workflow, unresolved func(caller, ref string)) (map[string][]string, error) {
+	idx, err := newWorkflowIndex(wfs)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := make(map[string][]string)
+	for _, w := range wfs {
+		// the version was resolved by the index
+		caller, _ := workflowRef(w)
+		for _, ref := range workflowCalls(w.Nodes) {
+			callee, ok := idx.resolve(ref)
+			if !ok {
+				if unresolved != nil {
+					unresolved(caller, ref)
+				}
+				continue
+			}
+			graph[caller] = append(graph[caller], callee)
+		}
+	}
+	return graph, nil
+}
+
+// findCallCycle returns the workflows forming a cycle of calls,
+// starting and ending with the same workflow, or nil if there is none.
+func findCallCycle(graph map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(ref string) []string
+	visit = func(ref string) []string {
+		switch state[ref] {
+		case visiting:
+			start := slices.Index(path, ref)
+			return append(slices.Clone(path[start:]), ref)
+		case visited:
+			return nil
+		}
+
+		state[ref] = visiting
+		path = append(path, ref)
+		for _, callee := range graph[ref] {
+			if cycle := visit(callee); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[ref] = visited
+		return nil
+	}
+
+	for _, ref := range slices.Sorted(maps.Keys(graph)) {
+		if cycle := visit(ref); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// checkWorkflowCalls verifies that all called workflows exist
+// and that no workflow calls itself, directly or indirectly.
+func checkWorkflowCalls(wfs []Workflow) error {
+	var unresolvedErr error
+	graph, err := callGraph(wfs, func(caller, ref string) {
+		if unresolvedErr == nil {
+			unresolvedErr = fmt.Errorf("%w '%s' called by '%s'", ErrUnknownWorkflow, ref, caller)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if unresolvedErr != nil {
+		return unresolvedErr
+	}
+
+	if cycle := findCallCycle(graph); cycle != nil {
+		return fmt.Errorf("%w: %s", ErrWorkflowCallCycle, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
@@ -22,12 +22,23 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/alan-mat/awe/internal/auth"
 	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/expr"
 	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/session"
 	"github.com/goccy/go-yaml"
 )
 
@@ -36,23 +47,132 @@ var (
 	ErrIncompatibleNodeType = errors.New("incompatible node type")
 	ErrNodeMissingChildren  = errors.New("node must contain at least one child node")
 	ErrInvalidExecutor      = errors.New("invalid executor")
+	ErrInvalidNodeInput     = errors.New("invalid node input")
+	ErrNestedNodeGraph      = errors.New("only top level nodes may declare dependencies or inputs")
+	ErrInvalidRouteCond     = errors.New("invalid route condition")
+	ErrInvalidErrorPolicy   = errors.New("invalid error policy")
+	ErrDuplicateWorkflow    = errors.New("duplicate workflow")
+	ErrUnknownWorkflow      = errors.New("unknown workflow")
+	ErrWorkflowCallCycle    = errors.New("workflow calls form a cycle")
 )
 
-func ReadConfig(path string) WorkflowConfig {
+func ReadConfig(path string) (WorkflowConfig, error) {
 	file, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return WorkflowConfig{}, fmt.Errorf("failed to read workflows config: %w", err)
 	}
 
 	var wc WorkflowConfig
 	if err := yaml.Unmarshal(file, &wc); err != nil {
-		panic(err)
+		return WorkflowConfig{}, fmt.Errorf("failed to parse workflows config '%s': %w", path, err)
 	}
 
-	return wc
+	for key, w := range wc.Workflows {
+		expandWorkflowCalls(w.Nodes)
+		wc.Workflows[key] = w
+	}
+
+	wc.path = path
+	wc.source = file
+	return wc, nil
+}
+
+// WorkflowFiles returns the workflow files found at path. If path is a
+// directory, all of its .yaml and .yml files are returned in lexical order,
+// otherwise path itself is returned.
+func WorkflowFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflows config: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflows directory: %w", err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || !IsWorkflowFile(e.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(path, e.Name()))
+	}
+	return files, nil
+}
+
+// IsWorkflowFile reports whether name has a yaml file extension.
+func IsWorkflowFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// LoadWorkflows reads, validates and parses all workflows found at path,
+// which may be a single file or a directory of files. Workflow names must be
+// unique across all files, unless they differ in version.
+func LoadWorkflows(path string) (map[string]*executor.Workflow, error) {
+	files, err := WorkflowFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows := make(map[string]*executor.Workflow)
+	configs := make([]WorkflowConfig, 0, len(files))
+	for _, file := range files {
+		wc, err := ReadConfig(file)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, wc)
+
+		parsed, err := ParseWorkflows(wc)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(workflows, parsed)
+	}
+
+	if err := CheckWorkflows(configs); err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+// CheckWorkflows checks the workflows of several configs for problems
+// across configs, which Validate does not find when validating a single
+// config: workflows defined in more than one config, calls of unknown
+// workflows and workflows calling each other in a cycle.
+func CheckWorkflows(configs []WorkflowConfig) error {
+	origin := make(map[string]string)
+	var all []Workflow
+	for _, wc := range configs {
+		for _, key := range slices.Sorted(maps.Keys(wc.Workflows)) {
+			w := wc.Workflows[key]
+			all = append(all, w)
+
+			// duplicates within a config are reported by Validate
+			ref, err := workflowRef(w)
+			if err != nil {
+				return fmt.Errorf("invalid workflow '%s' in '%s': %w", w.Identifier, wc.path, err)
+			}
+			if prev, exists := origin[ref]; exists && prev != wc.path {
+				return fmt.Errorf("%w '%s' in '%s', already defined in '%s'", ErrDuplicateWorkflow, ref, wc.path, prev)
+			}
+			origin[ref] = wc.path
+		}
+	}
+
+	return checkWorkflowCalls(all)
 }
 
 func ParseWorkflows(conf WorkflowConfig) (map[string]*executor.Workflow, error) {
+	if problems := conf.Validate(); len(problems) > 0 {
+		return nil, ValidationError{Path: conf.path, Problems: problems}
+	}
+
 	workflows := make(map[string]*executor.Workflow)
 
 	for _, cw := range conf.Workflows {
@@ -61,6 +181,12 @@ func ParseWorkflows(conf WorkflowConfig) (map[string]*executor.Workflow, error)
 			return nil, fmt.Errorf("failed to parse node on '%s' workflow (%v)", cw.Identifier, err)
 		}
 
+		if executor.IsGraph(nodes) {
+			if err := executor.ValidateGraph(nodes); err != nil {
+				return nil, fmt.Errorf("invalid node graph on '%s' workflow (%v)", cw.Identifier, err)
+			}
+		}
+
 		var collectionName string
 		if cw.CollectionName == "" {
 			collectionName = "default"
@@ -68,20 +194,103 @@ func ParseWorkflows(conf WorkflowConfig) (map[string]*executor.Workflow, error)
 			collectionName = cw.CollectionName
 		}
 
+		version, err := WorkflowVersion(cw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of '%s' workflow (%v)", cw.Identifier, err)
+		}
+
+		opts := []executor.WorkflowOption{executor.WithWorkflowVersion(version)}
+		if cw.Default {
+			opts = append(opts, executor.AsDefaultVersion())
+		}
+		if h := cw.History; h != nil {
+			strategy, err := session.ParseStrategy(h.Strategy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid history on '%s' workflow (%v)", cw.Identifier, err)
+			}
+			opts = append(opts, executor.WithHistoryPolicy(session.Policy{
+				MaxMessages: h.MaxMessages,
+				MaxChars:    h.MaxChars,
+				Strategy:    strategy,
+				Provider:    h.Provider,
+				Model:       h.Model,
+			}))
+		}
+		if cw.Retention != "" {
+			retention, err := parseRetention(cw.Retention)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retention on '%s' workflow (%v)", cw.Identifier, err)
+			}
+			opts = append(opts, executor.WithRetention(retention))
+		}
+		if len(cw.ProviderArgs) > 0 {
+			opts = append(opts, executor.WithProviderArgs(cw.ProviderArgs))
+		}
+		if cw.Access != nil {
+			opts = append(opts, executor.WithAccess(auth.Access{
+				Users:  cw.Access.Users,
+				Groups: cw.Access.Groups,
+			}))
+		}
+
 		workflow := executor.NewWorkflow(
 			cw.Identifier,
 			cw.Description,
 			collectionName,
 			cw.Search,
 			nodes,
+			opts...,
 		)
 
-		workflows[cw.Identifier] = workflow
+		workflows[workflow.Ref()] = workflow
 	}
 
 	return workflows, nil
 }
 
+// WorkflowVersion returns the version of the workflow. Workflows without an
+// explicit version are versioned by a hash of their definition, so the
+// version only changes if the definition does. It fails if the definition
+// holds args which can not be hashed.
+func WorkflowVersion(w Workflow) (string, error) {
+	if w.Version != "" {
+		return w.Version, nil
+	}
+
+	// marking a version as default does not change its definition
+	w.Default = false
+	data, err := json.Marshal(w)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash workflow definition: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12], nil
+}
+
+// workflowRef returns the 'name@version' reference of the workflow.
+func workflowRef(w Workflow) (string, error) {
+	version, err := WorkflowVersion(w)
+	if err != nil {
+		return "", err
+	}
+	return w.Identifier + "@" + version, nil
+}
+
+// minRetention is the shortest retention of traces, so that clients can still
+// read the trace of a request shortly after it completed.
+const minRetention = time.Minute
+
+func parseRetention(s string) (time.Duration, error) {
+	retention, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if retention < minRetention {
+		return 0, fmt.Errorf("retention must be at least %v", minRetention)
+	}
+	return retention, nil
+}
+
 func parseWorkflowNodes(nodes []WorkflowNode) ([]*executor.WorkflowNode, error) {
 	if len(nodes) == 0 {
 		return nil, ErrNodeMissingChildren
@@ -99,17 +308,29 @@ func parseWorkflowNodes(nodes []WorkflowNode) ([]*executor.WorkflowNode, error)
 		}
 
 		wfNode := executor.NewWorkflowNode(exec, cnode.Operator, string(cnode.Type))
+		wfNode.ID = cnode.ID
+		wfNode.Module = cnode.Module
+		wfNode.DependsOn = cnode.DependsOn
+		wfNode.Outputs = cnode.Outputs
 		if len(cnode.Args) > 0 {
 			wfNode.Args = cnode.Args
 		}
 
+		if len(cnode.Inputs) > 0 {
+			inputs, err := parseNodeInputs(cnode.Inputs)
+			if err != nil {
+				return nil, err
+			}
+			wfNode.Inputs = inputs
+		}
+
 		switch cnode.Type {
 		case NodeTypeLoop:
 			if len(cnode.Nodes) == 0 {
 				return nil, ErrNodeMissingChildren
 			}
 
-			children, err := parseWorkflowNodes(cnode.Nodes)
+			children, err := parseNestedNodes(cnode.Nodes)
 			if err != nil {
 				return nil, err
 			}
@@ -122,16 +343,26 @@ func parseWorkflowNodes(nodes []WorkflowNode) ([]*executor.WorkflowNode, error)
 
 			routes := make([]*executor.WorkflowRoute, 0, len(cnode.Routes))
 			for _, r := range cnode.Routes {
-				children, err := parseWorkflowNodes(r.Nodes)
+				children, err := parseNestedNodes(r.Nodes)
 				if err != nil {
 					return nil, err
 				}
 
-				routes = append(routes, &executor.WorkflowRoute{
+				route := &executor.WorkflowRoute{
 					Key:         r.Key,
 					Description: r.Description,
 					Nodes:       children,
-				})
+				}
+
+				if r.When != "" {
+					cond, err := expr.Compile(r.When)
+					if err != nil {
+						return nil, fmt.Errorf("%w on route '%s': %w", ErrInvalidRouteCond, r.Key, err)
+					}
+					route.Condition = cond
+				}
+
+				routes = append(routes, route)
 			}
 			wfNode.Routes = routes
 
@@ -142,7 +373,7 @@ func parseWorkflowNodes(nodes []WorkflowNode) ([]*executor.WorkflowNode, error)
 
 			branches := make([]*executor.WorkflowBranch, 0, len(cnode.Branches))
 			for _, b := range cnode.Branches {
-				children, err := parseWorkflowNodes(b.Nodes)
+				children, err := parseNestedNodes(b.Nodes)
 				if err != nil {
 					return nil, err
 				}
@@ -163,8 +394,132 @@ func parseWorkflowNodes(nodes []WorkflowNode) ([]*executor.WorkflowNode, error)
 			return nil, ErrInvalidNodeType
 		}
 
+		policy, err := parseErrorPolicy(cnode, routeKeys(cnode.Routes))
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil && len(cnode.Fallback) > 0 {
+			fallback, err := parseNestedNodes(cnode.Fallback)
+			if err != nil {
+				return nil, err
+			}
+			policy.Fallback = fallback
+		}
+		wfNode.Policy = policy
+
 		execNodes = append(execNodes, wfNode)
 	}
 
 	return execNodes, nil
 }
+
+// parseErrorPolicy parses the error policy of a node, except for its fallback nodes.
+func parseErrorPolicy(cnode WorkflowNode, routeKeys []string) (*executor.ErrorPolicy, error) {
+	if cnode.Retry == nil && cnode.Timeout == "" && cnode.OnError == "" && len(cnode.Fallback) == 0 {
+		return nil, nil
+	}
+
+	policy := &executor.ErrorPolicy{}
+	conditional := cnode.Type == NodeTypeConditional
+
+	if cnode.Retry != nil {
+		if cnode.Retry.Max < 0 {
+			return nil, fmt.Errorf("%w: retry max must not be negative", ErrInvalidErrorPolicy)
+		}
+
+		switch cnode.Retry.Backoff {
+		case "", executor.BackoffConstant, executor.BackoffExponential:
+		default:
+			return nil, fmt.Errorf("%w: unknown backoff '%s'", ErrInvalidErrorPolicy, cnode.Retry.Backoff)
+		}
+
+		retry := &executor.RetryPolicy{
+			Max:     cnode.Retry.Max,
+			Backoff: cnode.Retry.Backoff,
+		}
+		if cnode.Retry.Delay != "" {
+			delay, err := time.ParseDuration(cnode.Retry.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid retry delay: %w", ErrInvalidErrorPolicy, err)
+			}
+			retry.Delay = delay
+		}
+		policy.Retry = retry
+	}
+
+	if cnode.Timeout != "" {
+		timeout, err := time.ParseDuration(cnode.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid timeout: %w", ErrInvalidErrorPolicy, err)
+		}
+		policy.Timeout = timeout
+	}
+
+	switch onError, routeKey, _ := strings.Cut(cnode.OnError, ":"); onError {
+	case "", "fail":
+		policy.OnError = executor.OnErrorFail
+
+	case "continue":
+		if conditional {
+			return nil, fmt.Errorf("%w: conditional nodes can not continue on error, use 'route:<key>'", ErrInvalidErrorPolicy)
+		}
+		policy.OnError = executor.OnErrorContinue
+
+	case "route":
+		if !conditional {
+			return nil, fmt.Errorf("%w: 'route:<key>' can only be used by conditional nodes", ErrInvalidErrorPolicy)
+		}
+		if !slices.Contains(routeKeys, routeKey) {
+			return nil, fmt.Errorf("%w: no route found for key '%s'", ErrInvalidErrorPolicy, routeKey)
+		}
+		policy.OnError = executor.OnErrorRoute
+		policy.RouteKey = routeKey
+
+	default:
+		return nil, fmt.Errorf("%w: unknown on_error value '%s'", ErrInvalidErrorPolicy, cnode.OnError)
+	}
+
+	if len(cnode.Fallback) > 0 && conditional {
+		return nil, fmt.Errorf("%w: conditional nodes can not use fallback nodes, use 'on_error: route:<key>'", ErrInvalidErrorPolicy)
+	}
+
+	return policy, nil
+}
+
+func routeKeys(routes []WorkflowRoute) []string {
+	keys := make([]string, 0, len(routes))
+	for _, r := range routes {
+		keys = append(keys, r.Key)
+	}
+	return keys
+}
+
+func parseNodeInputs(inputs map[string]string) (map[string]executor.NodeOutputRef, error) {
+	refs := make(map[string]executor.NodeOutputRef, len(inputs))
+	for name, ref := range inputs {
+		nodeID, output, ok := strings.Cut(ref, ".")
+		if !ok || nodeID == "" || output == "" {
+			return nil, fmt.Errorf("%w: '%s' must reference an output as 'node_id.output'", ErrInvalidNodeInput, name)
+		}
+
+		refs[name] = executor.NodeOutputRef{
+			Node:   nodeID,
+			Output: output,
+		}
+	}
+	return refs, nil
+}
+
+// parseNestedNodes parses the child nodes of loop, conditional or branching nodes,
+// which are always executed in sequence.
+func parseNestedNodes(nodes []WorkflowNode) ([]*executor.WorkflowNode, error) {
+	children, err := parseWorkflowNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if executor.IsGraph(children) {
+		return nil, ErrNestedNodeGraph
+	}
+	return children, nil
+}
@@ -0,0 +1,512 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/expr"
+	"github.com/alan-mat/awe/internal/provider"
+	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/session"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Problem is an issue found when validating a workflows config.
+type Problem struct {
+	Workflow string
+	// Path is the YAML path of the element the problem was found at,
+	// Line and Column are 0 if the position is unknown.
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%d:%d: workflow '%s': %s", p.Line, p.Column, p.Workflow, p.Message)
+	}
+	return fmt.Sprintf("%s: workflow '%s': %s", p.Path, p.Workflow, p.Message)
+}
+
+// ValidationError is returned when a workflows config contains problems.
+type ValidationError struct {
+	Path     string
+	Problems []Problem
+}
+
+func (e ValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "workflows config contains %d problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		sb.WriteString("\n\t")
+		if e.Path != "" {
+			sb.WriteString(e.Path + ":")
+		}
+		sb.WriteString(p.String())
+	}
+	return sb.String()
+}
+
+// argsAvailable are set for all nodes by the workflow engine or the request,
+// further request args are declared by the args of a workflow.
+var argsAvailable = []string{"collection_name", "history"}
+
+var plainPathKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type validator struct {
+	file     *ast.File
+	workflow string
+	problems []Problem
+}
+
+// Validate checks the workflows of the config for problems which would
+// otherwise only be found when executing them, like unknown modules and
+// operators, missing required args or invalid node graphs.
+func (wc WorkflowConfig) Validate() []Problem {
+	v := &validator{}
+	if len(wc.source) > 0 {
+		// the source was already decoded, positions are best effort
+		v.file, _ = parser.ParseBytes(wc.source, 0)
+	}
+
+	refs := make(map[string]string)
+	versions := make(map[string][]string)
+	defaults := make(map[string][]string)
+	for _, key := range slices.Sorted(maps.Keys(wc.Workflows)) {
+		w := wc.Workflows[key]
+		path := "$.workflows." + pathKey(key)
+
+		v.workflow = w.Identifier
+		if w.Identifier == "" {
+			v.workflow = key
+			v.report(path, "workflow must have a name")
+		} else if ref, err := workflowRef(w); err != nil {
+			v.report(path, "%v", err)
+		} else {
+			if strings.Contains(w.Identifier, "@") {
+				v.report(path+".name", "name must not contain '@'")
+			} else if other, ok := refs[ref]; ok {
+				v.report(path, "name '%s' and version are already used by the workflow defined as '%s'", w.Identifier, other)
+			}
+			refs[ref] = key
+			versions[w.Identifier] = append(versions[w.Identifier], key)
+			if w.Default {
+				defaults[w.Identifier] = append(defaults[w.Identifier], key)
+			}
+		}
+
+		v.validateWorkflow(path, w)
+	}
+
+	// calls of workflows defined in other files are checked when loading all files,
+	// workflows whose version can not be computed were reported above
+	graph, err := callGraph(slices.Collect(maps.Values(wc.Workflows)), nil)
+	if cycle := findCallCycle(graph); err == nil && cycle != nil {
+		name, _ := registry.ParseWorkflowRef(cycle[0])
+		v.workflow = name
+		v.report("$.workflows."+pathKey(refs[cycle[0]]), "%v: %s", ErrWorkflowCallCycle, strings.Join(cycle, " -> "))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(versions)) {
+		v.workflow = name
+		keys := versions[name]
+		switch {
+		case len(defaults[name]) > 1:
+			for _, key := range defaults[name][1:] {
+				v.report("$.workflows."+pathKey(key)+".default", "workflow '%s' is already marked as default by '%s'", name, defaults[name][0])
+			}
+		case len(keys) > 1 && len(defaults[name]) == 0:
+			v.report("$.workflows."+pathKey(keys[0]), "workflow '%s' has %d versions, one of them must be marked as default", name, len(keys))
+		}
+	}
+
+	return v.problems
+}
+
+func (v *validator) report(path string, format string, args ...any) {
+	p := Problem{
+		Workflow: v.workflow,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	}
+
+	if v.file != nil {
+		if yp, err := yaml.PathString(path); err == nil {
+			if n, err := yp.FilterFile(v.file); err == nil && n != nil {
+				p.Line = n.GetToken().Position.Line
+				p.Column = n.GetToken().Position.Column
+			}
+		}
+	}
+	v.problems = append(v.problems, p)
+}
+
+func (v *validator) validateWorkflow(path string, w Workflow) {
+	if len(w.Nodes) == 0 {
+		v.report(path, "workflow must contain at least one node")
+		return
+	}
+
+	available := make(map[string]bool)
+	for _, name := range argsAvailable {
+		available[name] = true
+	}
+	for i, name := range w.Args {
+		if name == "" {
+			v.report(fmt.Sprintf("%s.args[%d]", path, i), "arg name must not be empty")
+		}
+		available[name] = true
+	}
+
+	var produced map[string]bool
+	if isGraphConfig(w.Nodes) {
+		produced = v.validateGraph(path+".nodes", w.Nodes, available)
+	} else {
+		produced = v.validateNodes(path+".nodes", w.Nodes, available)
+	}
+
+	if w.Search && !produced["context_docs"] {
+		v.report(path, "search workflow never produces 'context_docs'")
+	}
+
+	if w.Access != nil && len(w.Access.Users) == 0 && len(w.Access.Groups) == 0 {
+		v.report(path+".access", "access must list users or groups")
+	}
+
+	selectionArgs := provider.SelectionArgs()
+	for i, name := range w.ProviderArgs {
+		if !slices.Contains(selectionArgs, name) {
+			v.report(fmt.Sprintf("%s.provider_args[%d]", path, i), "'%s' does not select a provider, expected one of %s", name, strings.Join(selectionArgs, ", "))
+		}
+	}
+
+	if w.Retention != "" {
+		if _, err := parseRetention(w.Retention); err != nil {
+			v.report(path+".retention", "%v", err)
+		}
+	}
+
+	if h := w.History; h != nil {
+		if h.MaxMessages < 0 || h.MaxChars < 0 {
+			v.report(path+".history", "history limits must not be negative")
+		}
+		if _, err := session.ParseStrategy(h.Strategy); err != nil {
+			v.report(path+".history.strategy", "%v", err)
+		}
+	}
+}
+
+// validateNodes validates nodes executed in sequence, given the args available
+// before the first node. It returns the args available after the last node.
+func (v *validator) validateNodes(path string, nodes []WorkflowNode, available map[string]bool) map[string]bool {
+	if len(nodes) == 0 {
+		v.report(path, "%v", ErrNodeMissingChildren)
+		return available
+	}
+
+	available = maps.Clone(available)
+	for i, node := range nodes {
+		nodePath := fmt.Sprintf("%s[%d]", path, i)
+
+		if len(node.DependsOn) > 0 || len(node.Inputs) > 0 {
+			v.report(nodePath, "node is not part of a graph, %s", ErrNestedNodeGraph)
+		}
+
+		outputs := v.validateNode(nodePath, node, available)
+
+		if node.Type == NodeTypeConditional {
+			if i < len(nodes)-1 {
+				v.report(fmt.Sprintf("%s[%d]", path, i+1), "node is never executed, nodes following a conditional node are replaced by the selected route")
+			}
+			return outputs
+		}
+
+		// only context_docs are passed on to the following nodes
+		if outputs["context_docs"] {
+			available["context_docs"] = true
+		}
+	}
+	return available
+}
+
+// validateGraph validates nodes executed as a graph and returns all args produced by the nodes.
+func (v *validator) validateGraph(path string, nodes []WorkflowNode, available map[string]bool) map[string]bool {
+	ids := make(map[string]int, len(nodes))
+	execNodes := make([]*executor.WorkflowNode, 0, len(nodes))
+	for i, node := range nodes {
+		execNode := &executor.WorkflowNode{ID: node.ID, DependsOn: node.DependsOn}
+		execNodes = append(execNodes, execNode)
+		ids[executor.NodeID(execNode, i)] = i
+
+		inputs, err := parseNodeInputs(node.Inputs)
+		if err != nil {
+			v.report(fmt.Sprintf("%s[%d].inputs", path, i), "%v", err)
+			continue
+		}
+		execNode.Inputs = inputs
+	}
+
+	if err := executor.ValidateGraph(execNodes); err != nil {
+		v.report(path, "invalid node graph: %v", err)
+		return v.validateNodesUnordered(path, nodes, available)
+	}
+
+	// a node receives the context_docs of all of its ancestors
+	producesContext := make([]bool, len(nodes))
+	declared := make([]map[string]bool, len(nodes))
+	for i, node := range nodes {
+		declared[i] = outputsOf(node)
+		producesContext[i] = declared[i]["context_docs"]
+	}
+
+	var hasContext func(i int, visited map[int]bool) bool
+	hasContext = func(i int, visited map[int]bool) bool {
+		for _, dep := range graphDeps(execNodes[i]) {
+			d := ids[dep]
+			if visited[d] {
+				continue
+			}
+			visited[d] = true
+			if producesContext[d] || hasContext(d, visited) {
+				return true
+			}
+		}
+		return false
+	}
+
+	produced := maps.Clone(available)
+	for i, node := range nodes {
+		nodePath := fmt.Sprintf("%s[%d]", path, i)
+
+		nodeAvailable := maps.Clone(available)
+		if hasContext(i, make(map[int]bool)) {
+			nodeAvailable["context_docs"] = true
+		}
+
+		for name, ref := range execNodes[i].Inputs {
+			nodeAvailable[name] = true
+
+			if outputs := declared[ids[ref.Node]]; outputs != nil && !outputs[ref.Output] {
+				v.report(nodePath+".inputs", "input '%s' references '%s', which is not an output of node '%s'", name, ref, ref.Node)
+			}
+		}
+
+		maps.Copy(produced, v.validateNode(nodePath, node, nodeAvailable))
+	}
+	return produced
+}
+
+// validateNodesUnordered validates nodes of a graph whose dependencies are invalid.
+func (v *validator) validateNodesUnordered(path string, nodes []WorkflowNode, available map[string]bool) map[string]bool {
+	produced := maps.Clone(available)
+	for i, node := range nodes {
+		nodeAvailable := maps.Clone(available)
+		for name := range node.Inputs {
+			nodeAvailable[name] = true
+		}
+		maps.Copy(produced, v.validateNode(fmt.Sprintf("%s[%d]", path, i), node, nodeAvailable))
+	}
+	return produced
+}
+
+// validateNode validates a single node, including its child nodes, given the args
+// available to it. It returns the args available after executing the node.
+func (v *validator) validateNode(path string, node WorkflowNode, available map[string]bool) map[string]bool {
+	after := maps.Clone(available)
+	nodeAvailable := maps.Clone(available)
+	for name := range node.Args {
+		nodeAvailable[name] = true
+	}
+
+	if node.Type == "" {
+		node.Type = NodeTypeLinear
+	}
+
+	switch node.Type {
+	case NodeTypeLinear:
+		if len(node.Nodes) > 0 || len(node.Routes) > 0 || len(node.Branches) > 0 {
+			v.report(path, "%v: nodes of type '%s' can not contain child nodes, routes or branches", ErrIncompatibleNodeType, node.Type)
+		}
+
+	case NodeTypeLoop:
+		v.validateNodes(path+".nodes", node.Nodes, nodeAvailable)
+
+	case NodeTypeConditional:
+		if len(node.Routes) == 0 {
+			v.report(path, "conditional node must contain at least one route")
+		}
+
+		keys := make(map[string]bool)
+		for i, r := range node.Routes {
+			routePath := fmt.Sprintf("%s.routes[%d]", path, i)
+			if r.Key == "" {
+				v.report(routePath, "route must have a key")
+			} else if keys[r.Key] {
+				v.report(routePath, "route key '%s' is used by more than one route", r.Key)
+			}
+			keys[r.Key] = true
+
+			if r.When != "" {
+				if _, err := expr.Compile(r.When); err != nil {
+					v.report(routePath+".when", "%v: %v", ErrInvalidRouteCond, err)
+				}
+			}
+
+			// route nodes continue with the state before the conditional node
+			maps.Copy(after, v.validateNodes(routePath+".nodes", r.Nodes, available))
+		}
+
+	case NodeTypeBranching:
+		if len(node.Branches) == 0 {
+			v.report(path, "branching node must contain at least one branch")
+		}
+		for i, b := range node.Branches {
+			v.validateNodes(fmt.Sprintf("%s.branches[%d].nodes", path, i), b.Nodes, nodeAvailable)
+		}
+
+	default:
+		v.report(path+".type", "%v '%s'", ErrInvalidNodeType, node.Type)
+	}
+
+	if _, err := parseErrorPolicy(node, routeKeys(node.Routes)); err != nil {
+		v.report(path, "%v", err)
+	}
+	if len(node.Fallback) > 0 {
+		v.validateNodes(path+".fallback", node.Fallback, nodeAvailable)
+	}
+
+	if node.Workflow != "" && node.Module != workflowCallModule {
+		v.report(path+".workflow", "'workflow' can not be used with module '%s'", node.Module)
+	}
+
+	exec, err := registry.GetExecutor(node.Module)
+	if errors.Is(err, registry.ErrExecutorUnavailable) {
+		v.report(path+".module", "%v", err)
+		return after
+	}
+	if err != nil {
+		v.report(path+".module", "unknown module '%s'", node.Module)
+		return after
+	}
+
+	d, ok := exec.(executor.Describer)
+	if !ok {
+		return after
+	}
+
+	spec := d.Spec()
+	op, ok := spec.Operator(node.Operator)
+	if !ok {
+		names := make([]string, 0, len(spec.Operators))
+		for _, o := range spec.Operators {
+			names = append(names, o.Name)
+		}
+		v.report(path+".operator", "unknown operator '%s' for module '%s', available operators: %s",
+			node.Operator, node.Module, strings.Join(names, ", "))
+		return after
+	}
+
+	for _, arg := range op.Args {
+		value, ok := node.Args[arg.Name]
+		if !ok {
+			if arg.Required && !nodeAvailable[arg.Name] {
+				v.report(path, "missing required arg '%s' of operator '%s'", arg.Name, op.Name)
+			}
+			continue
+		}
+
+		if _, err := executor.Coerce(value, arg.Type); err != nil {
+			v.report(path+".args."+pathKey(arg.Name), "arg '%s' must be of type '%s'", arg.Name, arg.Type)
+		}
+	}
+
+	for name := range node.Outputs {
+		if !slices.Contains(op.Outputs, name) {
+			v.report(path+".outputs", "operator '%s' does not output '%s'", op.Name, name)
+		}
+	}
+
+	maps.Copy(after, renamedOutputs(op.Outputs, node.Outputs))
+	return after
+}
+
+// outputsOf returns the declared outputs of a node, or nil if they are unknown.
+func outputsOf(node WorkflowNode) map[string]bool {
+	exec, err := registry.GetExecutor(node.Module)
+	if err != nil {
+		return nil
+	}
+	d, ok := exec.(executor.Describer)
+	if !ok {
+		return nil
+	}
+	op, ok := d.Spec().Operator(node.Operator)
+	if !ok {
+		return nil
+	}
+	return renamedOutputs(op.Outputs, node.Outputs)
+}
+
+func renamedOutputs(outputs []string, renames map[string]string) map[string]bool {
+	names := make(map[string]bool, len(outputs))
+	for _, name := range outputs {
+		if renamed, ok := renames[name]; ok {
+			name = renamed
+		}
+		names[name] = true
+	}
+	return names
+}
+
+func graphDeps(node *executor.WorkflowNode) []string {
+	deps := slices.Clone(node.DependsOn)
+	for _, ref := range node.Inputs {
+		deps = append(deps, ref.Node)
+	}
+	return deps
+}
+
+func isGraphConfig(nodes []WorkflowNode) bool {
+	for _, n := range nodes {
+		if len(n.DependsOn) > 0 || len(n.Inputs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func pathKey(key string) string {
+	if plainPathKey.MatchString(key) {
+		return key
+	}
+	return "'" + key + "'"
+}
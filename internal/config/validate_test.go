@@ -0,0 +1,317 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/registry"
+)
+
+type requiredArgExecutor struct{}
+
+func (requiredArgExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "run",
+		Operators: []executor.OperatorSpec{{
+			Name:    "run",
+			Outputs: []string{"context_docs"},
+			Args:    []executor.ArgSpec{executor.RequiredArg[int]("top_n", "number of results")},
+		}},
+	}
+}
+
+func (requiredArgExecutor) Execute(ctx context.Context, params *executor.ExecutorParams) *executor.ExecutorResult {
+	return &executor.ExecutorResult{}
+}
+
+func init() {
+	if err := registry.RegisterExecutor("test.RequiredArg", requiredArgExecutor{}); err != nil {
+		panic(err)
+	}
+}
+
+func writeWorkflows(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write workflows: %v", err)
+	}
+	return path
+}
+
+func readWorkflows(t *testing.T, content string) WorkflowConfig {
+	t.Helper()
+	wc, err := ReadConfig(writeWorkflows(t, t.TempDir(), "workflows.yaml", content))
+	if err != nil {
+		t.Fatalf("failed to read workflows: %v", err)
+	}
+	return wc
+}
+
+func TestValidateRequestArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		problem string
+	}{
+		{
+			name: "missing",
+			content: `
+workflows:
+  search:
+    name: search
+    nodes:
+      - module: test.RequiredArg
+`,
+			problem: "missing required arg 'top_n'",
+		},
+		{
+			name: "node arg",
+			content: `
+workflows:
+  search:
+    name: search
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+`,
+		},
+		{
+			name: "declared request arg",
+			content: `
+workflows:
+  search:
+    name: search
+    args: [top_n]
+    nodes:
+      - module: test.RequiredArg
+`,
+		},
+		{
+			name: "empty request arg",
+			content: `
+workflows:
+  search:
+    name: search
+    args: [""]
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+`,
+			problem: "arg name must not be empty",
+		},
+		{
+			name: "provider args",
+			content: `
+workflows:
+  search:
+    name: search
+    provider_args: [provider, model, embedder]
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+`,
+		},
+		{
+			name: "unknown provider arg",
+			content: `
+workflows:
+  search:
+    name: search
+    provider_args: [top_n]
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+`,
+			problem: "'top_n' does not select a provider",
+		},
+		{
+			name: "short retention",
+			content: `
+workflows:
+  search:
+    name: search
+    retention: 10s
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+`,
+			problem: "retention must be at least 1m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := readWorkflows(t, tt.content).Validate()
+			if tt.problem == "" {
+				if len(problems) > 0 {
+					t.Fatalf("unexpected problems: %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0].Message, tt.problem) {
+				t.Fatalf("problems = %v, want one containing %q", problems, tt.problem)
+			}
+		})
+	}
+}
+
+func TestDeclaredArgsDoNotChangeVersion(t *testing.T) {
+	w := Workflow{Identifier: "search", Nodes: []WorkflowNode{{Module: "test.RequiredArg"}}}
+	version, err := WorkflowVersion(w)
+	if err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+
+	w.Args = []string{"top_n"}
+	if got, _ := WorkflowVersion(w); got != version {
+		t.Errorf("version with args = %s, want %s", got, version)
+	}
+}
+
+func TestUnhashableWorkflow(t *testing.T) {
+	wc := readWorkflows(t, `
+workflows:
+  search:
+    name: search
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+          threshold: .nan
+`)
+
+	if _, err := WorkflowVersion(wc.Workflows["search"]); err == nil {
+		t.Fatal("WorkflowVersion() succeeded, want error")
+	}
+	if problems := wc.Validate(); len(problems) == 0 {
+		t.Error("Validate() found no problems")
+	}
+	if _, err := ParseWorkflows(wc); err == nil {
+		t.Error("ParseWorkflows() succeeded, want error")
+	}
+}
+
+func TestCheckWorkflows(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		err   error
+	}{
+		{
+			name: "call across files",
+			files: []string{`
+workflows:
+  outer:
+    name: outer
+    nodes:
+      - workflow: inner
+`, `
+workflows:
+  inner:
+    name: inner
+    nodes:
+      - module: test.RequiredArg
+        args:
+          top_n: 5
+`},
+		},
+		{
+			name: "unknown workflow",
+			files: []string{`
+workflows:
+  outer:
+    name: outer
+    nodes:
+      - workflow: missing
+`},
+			err: ErrUnknownWorkflow,
+		},
+		{
+			name: "cycle across files",
+			files: []string{`
+workflows:
+  a:
+    name: a
+    nodes:
+      - workflow: b
+`, `
+workflows:
+  b:
+    name: b
+    nodes:
+      - workflow: a
+`},
+			err: ErrWorkflowCallCycle,
+		},
+		{
+			name: "duplicate across files",
+			files: []string{`
+workflows:
+  search:
+    name: search
+    version: v1
+    nodes:
+      - module: test.RequiredArg
+`, `
+workflows:
+  search:
+    name: search
+    version: v1
+    nodes:
+      - module: test.RequiredArg
+`},
+			err: ErrDuplicateWorkflow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configs := make([]WorkflowConfig, 0, len(tt.files))
+			for i, content := range tt.files {
+				wc, err := ReadConfig(writeWorkflows(t, dir, string(rune('a'+i))+".yaml", content))
+				if err != nil {
+					t.Fatalf("failed to read workflows: %v", err)
+				}
+				configs = append(configs, wc)
+			}
+
+			err := CheckWorkflows(configs)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("CheckWorkflows() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -38,20 +38,48 @@ type WorkflowNodeType string
 } */
 
 type WorkflowNode struct {
+	ID       string           `yaml:"id"`
 	Module   string           `yaml:"module"`
 	Operator string           `yaml:"operator"`
 	Type     WorkflowNodeType `yaml:"type"`
 	Args     map[string]any   `yaml:"args"`
 
+	// Workflow calls the named workflow ('name' or 'name@version')
+	// and is short for the module workflow.Call with the workflow arg.
+	Workflow string `yaml:"workflow"`
+
+	// DependsOn and Inputs turn the workflow into a graph of nodes.
+	// Inputs map arg names to outputs of other nodes, in the form of 'node_id.output'.
+	// Outputs rename the values returned by the node.
+	DependsOn []string          `yaml:"depends_on"`
+	Inputs    map[string]string `yaml:"inputs"`
+	Outputs   map[string]string `yaml:"outputs"`
+
+	// Error handling of the node, Timeout and Retry.Delay are durations like '10s'.
+	// OnError is one of 'fail' (default), 'continue' or 'route:<key>'.
+	Retry    *WorkflowRetry `yaml:"retry"`
+	Timeout  string         `yaml:"timeout"`
+	OnError  string         `yaml:"on_error"`
+	Fallback []WorkflowNode `yaml:"fallback"`
+
 	Nodes    []WorkflowNode   `yaml:"nodes"`
 	Routes   []WorkflowRoute  `yaml:"routes"`
 	Branches []WorkflowBranch `yaml:"branches"`
 }
 
+type WorkflowRetry struct {
+	Max     int    `yaml:"max"`
+	Backoff string `yaml:"backoff"`
+	Delay   string `yaml:"delay"`
+}
+
 type WorkflowRoute struct {
-	Key         string         `yaml:"key"`
-	Description string         `yaml:"description"`
-	Nodes       []WorkflowNode `yaml:"nodes"`
+	Key         string `yaml:"key"`
+	Description string `yaml:"description"`
+	// When is an expression selecting this route when used
+	// with an expression based router, e.g. 'len(context_docs) == 0'.
+	When  string         `yaml:"when"`
+	Nodes []WorkflowNode `yaml:"nodes"`
 }
 
 type WorkflowBranch struct {
@@ -61,13 +89,56 @@ type WorkflowBranch struct {
 
 type Workflow struct {
 	Identifier     string `yaml:"name"`
+	Version        string `yaml:"version"`
+	Default        bool   `yaml:"default"`
 	Description    string `yaml:"description"`
 	CollectionName string `yaml:"collection"`
 	Search         bool   `yaml:"search"`
 
+	// Access restricts the workflow to the listed users and groups,
+	// it is open to all clients if not set.
+	Access *WorkflowAccess `yaml:"access"`
+
+	// Args lists the args of requests the nodes of the workflow rely on, like
+	// required args of operators not set by the nodes. It is only used to
+	// validate the workflow and does not change its definition.
+	Args []string `yaml:"args" json:"-"`
+
+	// ProviderArgs lists the args selecting providers and models, like 'provider'
+	// or 'embedder', which requests may set. Other selection args of requests
+	// are dropped, so only the nodes of the workflow choose their providers.
+	ProviderArgs []string `yaml:"provider_args" json:",omitempty"`
+
+	// History limits the history kept for chat sessions with the workflow.
+	History *WorkflowHistory `yaml:"history"`
+
+	// Retention is how long the traces of requests to the workflow are kept
+	// after they completed, a duration like '720h' of at least a minute. It does not change the definition of the
+	// workflow, see WorkflowVersion.
+	Retention string `yaml:"retention" json:"-"`
+
 	Nodes []WorkflowNode `yaml:"nodes"`
 }
 
+type WorkflowHistory struct {
+	MaxMessages int `yaml:"max_messages"`
+	MaxChars    int `yaml:"max_chars"`
+	// Strategy is one of 'truncate' (default) or 'summarize'.
+	Strategy string `yaml:"strategy"`
+	// Provider and Model select the LM summarizing the history.
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+}
+
+type WorkflowAccess struct {
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+}
+
 type WorkflowConfig struct {
 	Workflows map[string]Workflow `yaml:"workflows"`
+
+	// path and source of the config file, if read by ReadConfig
+	path   string
+	source []byte
 }
@@ -0,0 +1,208 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+var ErrCheckpointMismatch = errors.New("checkpoint does not match workflow")
+
+// Checkpoint holds the state of a workflow execution after the last
+// completed node, so that an interrupted execution can be resumed.
+//
+// For sequential workflows, Query and Args hold the state after the last
+// completed node, Routes the routes taken by conditional nodes and Next the
+// index of the next node to execute within the current route.
+// For graph workflows, Query and Args hold the initial state and Completed
+// the result values of all completed nodes by node ID.
+//
+// Loop and branching nodes, as well as nodes of routes in graph workflows,
+// are re-executed entirely when interrupted.
+type Checkpoint struct {
+	WorkflowID      string                             `json:"workflow_id"`
+	WorkflowVersion string                             `json:"workflow_version,omitempty"`
+	Query           string                             `json:"query"`
+	Args            map[string]EncodedValue            `json:"args"`
+	Routes          []RouteStep                        `json:"routes,omitempty"`
+	Next            int                                `json:"next"`
+	Completed       map[string]map[string]EncodedValue `json:"completed,omitempty"`
+
+	// StreamLen is the number of messages in the request's message stream
+	// at the time the checkpoint was taken.
+	StreamLen int `json:"stream_len"`
+}
+
+// RouteStep records the route selected by the conditional node at Index.
+type RouteStep struct {
+	Index int    `json:"index"`
+	Key   string `json:"key"`
+}
+
+// LoadCheckpoint retrieves the checkpoint of the request with the given id.
+// It returns transport.ErrCheckpointNotFound if no checkpoint exists.
+func LoadCheckpoint(ctx context.Context, t transport.Transport, id string) (*Checkpoint, error) {
+	data, err := t.GetCheckpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// checkpointer persists checkpoints of a workflow execution to the transport.
+// Failing to persist a checkpoint is logged but does not fail the workflow.
+type checkpointer struct {
+	mu        sync.Mutex
+	id        string
+	transport transport.Transport
+	cp        Checkpoint
+}
+
+func newCheckpointer(id string, t transport.Transport, workflowID, version string) *checkpointer {
+	return &checkpointer{
+		id:        id,
+		transport: t,
+		cp:        Checkpoint{WorkflowID: workflowID, WorkflowVersion: version},
+	}
+}
+
+// resume returns the nodes and index to continue a sequential execution at.
+func (c *checkpointer) resume(nodes []*WorkflowNode) ([]*WorkflowNode, int, error) {
+	for _, step := range c.cp.Routes {
+		if step.Index >= len(nodes) {
+			return nil, 0, ErrCheckpointMismatch
+		}
+
+		var route *WorkflowRoute
+		for _, r := range nodes[step.Index].Routes {
+			if r.Key == step.Key {
+				route = r
+				break
+			}
+		}
+		if route == nil {
+			return nil, 0, ErrCheckpointMismatch
+		}
+		nodes = route.Nodes
+	}
+
+	if c.cp.Next > len(nodes) {
+		return nil, 0, ErrCheckpointMismatch
+	}
+	return nodes, c.cp.Next, nil
+}
+
+// saveState records the state of a sequential execution before the node at next.
+func (c *checkpointer) saveState(ctx context.Context, next int, params *ExecutorParams) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	args, err := EncodeValues(params.Args)
+	if err != nil {
+		slog.Warn("failed to encode checkpoint state", "id", c.id, "err", err)
+		return
+	}
+
+	c.cp.Query = params.GetQuery()
+	c.cp.Args = args
+	c.cp.Next = next
+	c.save(ctx)
+}
+
+// saveRoute records the route selected by a conditional node of a sequential execution.
+func (c *checkpointer) saveRoute(ctx context.Context, step RouteStep, params *ExecutorParams) {
+	c.mu.Lock()
+	c.cp.Routes = append(c.cp.Routes, step)
+	c.mu.Unlock()
+
+	c.saveState(ctx, 0, params)
+}
+
+// saveNode records the result values of a completed node of a graph execution.
+func (c *checkpointer) saveNode(ctx context.Context, nodeID string, values map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	encoded, err := EncodeValues(values)
+	if err != nil {
+		slog.Warn("failed to encode checkpoint node results", "id", c.id, "nodeId", nodeID, "err", err)
+		return
+	}
+
+	if c.cp.Completed == nil {
+		c.cp.Completed = make(map[string]map[string]EncodedValue)
+	}
+	c.cp.Completed[nodeID] = encoded
+	c.save(ctx)
+}
+
+// completed returns the decoded result values of a node completed before resuming.
+func (c *checkpointer) completed(nodeID string) (map[string]any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	encoded, ok := c.cp.Completed[nodeID]
+	if !ok {
+		return nil, false
+	}
+
+	values, err := DecodeValues(encoded)
+	if err != nil {
+		slog.Warn("failed to decode checkpoint node results", "id", c.id, "nodeId", nodeID, "err", err)
+		return nil, false
+	}
+	return values, true
+}
+
+// save persists the checkpoint, the caller must hold c.mu.
+func (c *checkpointer) save(ctx context.Context) {
+	ms, err := c.transport.GetMessageStream(c.id)
+	if err == nil {
+		c.cp.StreamLen, err = ms.Len(ctx)
+	}
+	if err != nil {
+		slog.Warn("failed to read message stream length for checkpoint", "id", c.id, "err", err)
+		return
+	}
+
+	data, err := json.Marshal(c.cp)
+	if err != nil {
+		slog.Warn("failed to encode checkpoint", "id", c.id, "err", err)
+		return
+	}
+
+	if err := c.transport.SetCheckpoint(ctx, c.id, data); err != nil {
+		slog.Warn("failed to save checkpoint", "id", c.id, "err", err)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+var errNotCoercible = errors.New("value can not be coerced")
+
+// Coerce converts v to the type t. Besides values which are already assignable
+// to t, it converts between numeric types if the value fits the target type,
+// parses strings into numbers and booleans, formats numbers and booleans as
+// strings, and decodes JSON strings or generic values (e.g. map[string]any
+// from YAML) into slices, maps and structs.
+func Coerce(v any, t reflect.Type) (any, error) {
+	if v == nil {
+		return nil, errNotCoercible
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	out := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.String:
+		switch rv.Kind() {
+		case reflect.Bool:
+			out.SetString(strconv.FormatBool(rv.Bool()))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			out.SetString(strconv.FormatInt(rv.Int(), 10))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			out.SetString(strconv.FormatUint(rv.Uint(), 10))
+		case reflect.Float32, reflect.Float64:
+			out.SetString(strconv.FormatFloat(rv.Float(), 'f', -1, 64))
+		case reflect.String:
+			out.SetString(rv.String())
+		default:
+			return nil, errNotCoercible
+		}
+		return out.Interface(), nil
+
+	case reflect.Bool:
+		if rv.Kind() != reflect.String {
+			return nil, errNotCoercible
+		}
+		b, err := strconv.ParseBool(rv.String())
+		if err != nil {
+			return nil, err
+		}
+		out.SetBool(b)
+		return out.Interface(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch rv.Kind() {
+		case reflect.String:
+			parsed, err := strconv.ParseInt(rv.String(), 10, t.Bits())
+			if err != nil {
+				return nil, err
+			}
+			i = parsed
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > math.MaxInt64 {
+				return nil, errNotCoercible
+			}
+			i = int64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+				return nil, errNotCoercible
+			}
+			i = int64(f)
+		default:
+			return nil, errNotCoercible
+		}
+		if out.OverflowInt(i) {
+			return nil, errNotCoercible
+		}
+		out.SetInt(i)
+		return out.Interface(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch rv.Kind() {
+		case reflect.String:
+			parsed, err := strconv.ParseUint(rv.String(), 10, t.Bits())
+			if err != nil {
+				return nil, err
+			}
+			u = parsed
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if rv.Int() < 0 {
+				return nil, errNotCoercible
+			}
+			u = uint64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u = rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if f != math.Trunc(f) || f < 0 || f > math.MaxUint64 {
+				return nil, errNotCoercible
+			}
+			u = uint64(f)
+		default:
+			return nil, errNotCoercible
+		}
+		if out.OverflowUint(u) {
+			return nil, errNotCoercible
+		}
+		out.SetUint(u)
+		return out.Interface(), nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch rv.Kind() {
+		case reflect.String:
+			parsed, err := strconv.ParseFloat(rv.String(), t.Bits())
+			if err != nil {
+				return nil, err
+			}
+			f = parsed
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		default:
+			return nil, errNotCoercible
+		}
+		out.SetFloat(f)
+		return out.Interface(), nil
+
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Pointer:
+		var data []byte
+		if rv.Kind() == reflect.String {
+			data = []byte(rv.String())
+		} else {
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			data = encoded
+		}
+
+		ptr := reflect.New(t)
+		if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %w", errNotCoercible, err)
+		}
+		return ptr.Elem().Interface(), nil
+	}
+
+	return nil, errNotCoercible
+}
@@ -21,7 +21,10 @@
 
 package executor
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ErrOperatorNotFound struct {
 	ExecutorName string
@@ -60,3 +63,37 @@ func (e ErrInvalidParams) Error() string {
 	return fmt.Sprintf("executor '%s' is missing the following params: %v",
 		e.ExecutorName, e.MissingParams)
 }
+
+type ErrDuplicateNodeID struct {
+	NodeID string
+}
+
+func (e ErrDuplicateNodeID) Error() string {
+	return fmt.Sprintf("node id '%s' is used by more than one node", e.NodeID)
+}
+
+type ErrUnknownNodeDependency struct {
+	NodeID     string
+	Dependency string
+}
+
+func (e ErrUnknownNodeDependency) Error() string {
+	return fmt.Sprintf("node '%s' depends on unknown node '%s'", e.NodeID, e.Dependency)
+}
+
+type ErrNodeCycle struct {
+	Path []string
+}
+
+func (e ErrNodeCycle) Error() string {
+	return fmt.Sprintf("node dependencies contain a cycle: %s", strings.Join(e.Path, " -> "))
+}
+
+type ErrNodeOutputMissing struct {
+	NodeID string
+	Ref    NodeOutputRef
+}
+
+func (e ErrNodeOutputMissing) Error() string {
+	return fmt.Sprintf("input of node '%s' references missing output '%s'", e.NodeID, e.Ref)
+}
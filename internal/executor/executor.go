@@ -23,9 +23,11 @@ package executor
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"maps"
 	"reflect"
+	"slices"
 
 	"github.com/alan-mat/awe/internal/api"
 	"github.com/alan-mat/awe/internal/transport"
@@ -197,7 +199,9 @@ func ProcessResult(params *ExecutorParams, result *ExecutorResult) *ExecutorPara
 				if !ok {
 					slog.Error("workflow error", "msg", "invalid type of context docs in params")
 				}
-				newParams.Args["context_docs"] = append(context_typed, new_context...)
+				// params may be shared by nodes executed concurrently,
+				// never append to their backing array
+				newParams.Args["context_docs"] = append(slices.Clip(context_typed), new_context...)
 			}
 		}
 	}
@@ -213,13 +217,17 @@ func GetTypedArg[T any](p *ExecutorParams, argName string) (T, error) {
 
 	typedArg, ok := arg.(T)
 	if !ok {
+		// args passed as strings or decoded from YAML or JSON
+		// may be of a different but convertible type
 		expectedType := reflect.TypeOf((*T)(nil)).Elem()
-		receivedType := reflect.TypeOf(arg)
+		if coerced, err := Coerce(arg, expectedType); err == nil {
+			return coerced.(T), nil
+		}
 
 		return *new(T), ErrInvalidArgumentType{
 			Name:     argName,
 			Expected: expectedType.String(),
-			Received: receivedType.String(),
+			Received: fmt.Sprintf("%T", arg),
 		}
 	}
 
@@ -0,0 +1,343 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/alan-mat/awe/internal/api"
+	"golang.org/x/sync/errgroup"
+)
+
+// IsGraph reports whether the given nodes should be executed as a graph,
+// which is the case when at least one node declares dependencies
+// or inputs referencing other nodes.
+func IsGraph(nodes []*WorkflowNode) bool {
+	for _, n := range nodes {
+		if len(n.DependsOn) > 0 || len(n.Inputs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeID returns the ID of a node at the given index. Nodes without
+// an explicit ID are identified by their index, e.g. 'node_0'.
+func NodeID(node *WorkflowNode, idx int) string {
+	if node.ID != "" {
+		return node.ID
+	}
+	return fmt.Sprintf("node_%d", idx)
+}
+
+// ValidateGraph checks that the given nodes form a valid graph:
+// node IDs must be unique, dependencies and inputs must reference
+// existing nodes and dependencies must not contain cycles.
+func ValidateGraph(nodes []*WorkflowNode) error {
+	_, err := newWorkflowGraph(nodes)
+	return err
+}
+
+type graphNode struct {
+	id   string
+	node *WorkflowNode
+
+	// deps holds the indices of direct dependencies,
+	// ancestors holds the indices of all transitive dependencies
+	// in topological order.
+	deps      []int
+	ancestors []int
+}
+
+type workflowGraph struct {
+	nodes []*graphNode
+	index map[string]int
+	order []int
+}
+
+func newWorkflowGraph(nodes []*WorkflowNode) (*workflowGraph, error) {
+	g := &workflowGraph{
+		nodes: make([]*graphNode, 0, len(nodes)),
+		index: make(map[string]int, len(nodes)),
+	}
+
+	for i, n := range nodes {
+		id := NodeID(n, i)
+		if _, ok := g.index[id]; ok {
+			return nil, ErrDuplicateNodeID{NodeID: id}
+		}
+		g.index[id] = i
+		g.nodes = append(g.nodes, &graphNode{id: id, node: n})
+	}
+
+	for _, gn := range g.nodes {
+		// inputs implicitly depend on the node they reference
+		depIDs := slices.Clone(gn.node.DependsOn)
+		for _, name := range slices.Sorted(maps.Keys(gn.node.Inputs)) {
+			depIDs = append(depIDs, gn.node.Inputs[name].Node)
+		}
+
+		for _, depID := range depIDs {
+			dep, ok := g.index[depID]
+			if !ok {
+				return nil, ErrUnknownNodeDependency{NodeID: gn.id, Dependency: depID}
+			}
+			if !slices.Contains(gn.deps, dep) {
+				gn.deps = append(gn.deps, dep)
+			}
+		}
+	}
+
+	if err := g.sort(); err != nil {
+		return nil, err
+	}
+
+	position := make([]int, len(g.nodes))
+	for pos, i := range g.order {
+		position[i] = pos
+	}
+
+	for _, i := range g.order {
+		gn := g.nodes[i]
+		for _, dep := range gn.deps {
+			for _, a := range append(g.nodes[dep].ancestors, dep) {
+				if !slices.Contains(gn.ancestors, a) {
+					gn.ancestors = append(gn.ancestors, a)
+				}
+			}
+		}
+		slices.SortFunc(gn.ancestors, func(a, b int) int {
+			return position[a] - position[b]
+		})
+	}
+
+	return g, nil
+}
+
+// sort orders the graph nodes topologically, keeping the declared order
+// of nodes where possible. It returns an error if a cycle is found.
+func (g *workflowGraph) sort() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(g.nodes))
+	path := make([]string, 0, len(g.nodes))
+	g.order = make([]int, 0, len(g.nodes))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, g.nodes[i].id)
+			return ErrNodeCycle{Path: append(slices.Clone(path[start:]), g.nodes[i].id)}
+		}
+
+		state[i] = visiting
+		path = append(path, g.nodes[i].id)
+		for _, dep := range g.nodes[i].deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+
+		g.order = append(g.order, i)
+		return nil
+	}
+
+	for i := range g.nodes {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute runs all nodes of the graph, executing every node as soon as
+// all of its dependencies have completed. Each node receives the params
+// resulting from merging the results of its ancestors, with its inputs
+// set as args. It returns the params resulting from merging the results
+// of all nodes in topological order, see mergeResult.
+//
+// If c is not nil, a checkpoint is saved after every completed node and
+// nodes completed before resuming are not executed again.
+func (g *workflowGraph) execute(ctx context.Context, params *ExecutorParams, c *checkpointer) (*ExecutorParams, error) {
+	results := make([]*ExecutorResult, len(g.nodes))
+	done := make([]chan struct{}, len(g.nodes))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, gn := range g.nodes {
+		if c != nil {
+			if values, ok := c.completed(gn.id); ok {
+				results[i] = &ExecutorResult{Name: gn.id, Values: values}
+				close(done[i])
+				continue
+			}
+		}
+
+		eg.Go(func() error {
+			for _, dep := range gn.deps {
+				select {
+				case <-done[dep]:
+				case <-egCtx.Done():
+					return egCtx.Err()
+				}
+			}
+
+			result, err := g.executeNode(egCtx, gn, params, results)
+			if err != nil {
+				return err
+			}
+
+			if c != nil {
+				c.saveNode(egCtx, gn.id, result.Values)
+			}
+
+			results[i] = result
+			close(done[i])
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return g.merge(params, results, g.order), nil
+}
+
+// merge returns the params resulting from merging the results of the given
+// nodes, in topological order, into params. Every result is merged against
+// the params its node received, merged from the results of its ancestors.
+func (g *workflowGraph) merge(params *ExecutorParams, results []*ExecutorResult, nodes []int) *ExecutorParams {
+	received := make([]*ExecutorParams, len(g.nodes))
+
+	var merge func(nodes []int) *ExecutorParams
+	merge = func(nodes []int) *ExecutorParams {
+		state := params
+		for _, i := range nodes {
+			if received[i] == nil {
+				received[i] = merge(g.nodes[i].ancestors)
+			}
+			state = mergeResult(state, received[i], results[i])
+		}
+		return state
+	}
+	return merge(nodes)
+}
+
+// mergeResult processes the result of a node, which received the params
+// in, into state. Context docs appended by the node are appended to those
+// of state. A node replacing its context docs only replaces the docs it
+// received, so that docs of independent nodes are kept.
+func mergeResult(state, in *ExecutorParams, result *ExecutorResult) *ExecutorParams {
+	merged := state.Copy()
+	if q, ok := result.Values["query_transformed"].(string); ok {
+		merged.SetQuery(q)
+	}
+
+	docs, ok := ProcessResult(in, result).Args["context_docs"].([]*api.ScoredDocument)
+	if !ok {
+		return merged
+	}
+	receivedDocs, _ := in.Args["context_docs"].([]*api.ScoredDocument)
+	current, _ := merged.Args["context_docs"].([]*api.ScoredDocument)
+
+	if len(docs) >= len(receivedDocs) && slices.Equal(docs[:len(receivedDocs)], receivedDocs) {
+		if _, ok := merged.Args["context_docs"]; !ok || len(docs) > len(receivedDocs) {
+			merged.Args["context_docs"] = append(slices.Clip(current), docs[len(receivedDocs):]...)
+		}
+		return merged
+	}
+
+	kept := slices.DeleteFunc(slices.Clone(current), func(doc *api.ScoredDocument) bool {
+		return slices.Contains(receivedDocs, doc)
+	})
+	merged.Args["context_docs"] = append(kept, docs...)
+	return merged
+}
+
+func (g *workflowGraph) executeNode(
+	ctx context.Context,
+	gn *graphNode,
+	params *ExecutorParams,
+	results []*ExecutorResult,
+) (*ExecutorResult, error) {
+	state := g.merge(params, results, gn.ancestors)
+
+	nodeParams := MakeNodeParams(gn.node, state)
+	for name, ref := range gn.node.Inputs {
+		val, ok := results[g.index[ref.Node]].Get(ref.Output)
+		if !ok {
+			return nil, ErrNodeOutputMissing{NodeID: gn.id, Ref: ref}
+		}
+
+		if q, ok := val.(string); ok && name == "query" {
+			nodeParams.SetQuery(q)
+			continue
+		}
+		nodeParams.Args[name] = val
+	}
+
+	slog.Debug("executing workflow node", "nodeId", gn.id, "dependsOn", len(gn.deps))
+
+	result := gn.node.Execute(ctx, nodeParams)
+	if result.Err != nil {
+		slog.Error("failed to execute node", "nodeId", gn.id, "error", fmt.Sprintf("(%T): %v", result.Err, result.Err))
+		return nil, result.Err
+	}
+
+	if gn.node.NodeType != "conditional" {
+		return result, nil
+	}
+
+	// conditional nodes execute the selected route in place,
+	// the node result is the state after executing the route
+	route, err := selectRoute(gn.node, result)
+	if err != nil {
+		slog.Error("failed to execute workflow", "nodeId", gn.id, "err", err)
+		return nil, err
+	}
+
+	routeState, err := RunNodes(ctx, route.Nodes, nodeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	routeResult := StateResult(result.Name, result.Operator, nodeParams, routeState)
+	routeResult.Values["route_key"] = route.Key
+	routeResult.Values = gn.node.renameOutputs(routeResult.Values)
+	return routeResult, nil
+}
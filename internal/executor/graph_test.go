@@ -0,0 +1,250 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/span"
+)
+
+// docsExecutor returns a single context doc with the given content.
+type docsExecutor string
+
+func (e docsExecutor) Execute(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	return &ExecutorResult{Values: map[string]any{
+		"context_docs": []*api.ScoredDocument{{Content: string(e)}},
+	}}
+}
+
+// routeExecutor selects the route with the given key.
+type routeExecutor string
+
+func (e routeExecutor) Execute(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	return &ExecutorResult{Values: map[string]any{"route_key": string(e)}}
+}
+
+// iterationExecutor executes its node once, in a span of its own.
+type iterationExecutor struct {
+	node *WorkflowNode
+}
+
+func (e iterationExecutor) Execute(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	ctx, s := span.Start(ctx, "iteration 1")
+	result := e.node.Execute(ctx, params)
+	s.End(result.Values, result.Err)
+	return result
+}
+
+func docContents(t *testing.T, params *ExecutorParams) []string {
+	t.Helper()
+	docs, err := GetTypedArg[[]*api.ScoredDocument](params, "context_docs")
+	if err != nil {
+		t.Fatalf("failed to get context docs: %v", err)
+	}
+	contents := make([]string, len(docs))
+	for i, doc := range docs {
+		contents[i] = doc.Content
+	}
+	return contents
+}
+
+func TestProcessResultDoesNotShareContextDocs(t *testing.T) {
+	docs := make([]*api.ScoredDocument, 1, 8)
+	docs[0] = &api.ScoredDocument{Content: "initial"}
+	params := NewExecutorParams("test", "query", WithArgs(map[string]any{"context_docs": docs}))
+
+	a := ProcessResult(params, docsExecutor("a").Execute(context.Background(), params))
+	b := ProcessResult(params, docsExecutor("b").Execute(context.Background(), params))
+
+	if got := docContents(t, a); len(got) != 2 || got[1] != "a" {
+		t.Errorf("context docs of first result = %v, want [initial a]", got)
+	}
+	if got := docContents(t, b); len(got) != 2 || got[1] != "b" {
+		t.Errorf("context docs of second result = %v, want [initial b]", got)
+	}
+}
+
+func TestGraphExecutesSiblingsConcurrently(t *testing.T) {
+	nodes := []*WorkflowNode{
+		{ID: "root", Executor: docsExecutor("root")},
+		{ID: "left", Executor: docsExecutor("left"), DependsOn: []string{"root"}},
+		{ID: "right", Executor: docsExecutor("right"), DependsOn: []string{"root"}},
+		{ID: "middle", Executor: docsExecutor("middle"), DependsOn: []string{"root"}},
+		{ID: "join", Executor: docsExecutor("join"), DependsOn: []string{"left", "right", "middle"}},
+	}
+
+	g, err := newWorkflowGraph(nodes)
+	if err != nil {
+		t.Fatalf("failed to create graph: %v", err)
+	}
+
+	docs := make([]*api.ScoredDocument, 0, 16)
+	params := NewExecutorParams("test", "query", WithArgs(map[string]any{"context_docs": docs}))
+	res, err := g.execute(context.Background(), params, nil)
+	if err != nil {
+		t.Fatalf("failed to execute graph: %v", err)
+	}
+
+	got := docContents(t, res)
+	want := []string{"root", "left", "right", "middle", "join"}
+	if len(got) != len(want) {
+		t.Fatalf("context docs = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("context docs = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestGraphKeepsDocsOfSiblings(t *testing.T) {
+	conditional := func() *WorkflowNode {
+		return &WorkflowNode{
+			ID:        "c",
+			NodeType:  "conditional",
+			Executor:  routeExecutor("r"),
+			DependsOn: []string{"a"},
+			Routes: []*WorkflowRoute{{
+				Key:   "r",
+				Nodes: []*WorkflowNode{{Executor: docsExecutor("r")}},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		nodes []*WorkflowNode
+		want  []string
+	}{
+		{
+			name: "sibling before conditional",
+			nodes: []*WorkflowNode{
+				{ID: "a", Executor: docsExecutor("a")},
+				{ID: "b", Executor: docsExecutor("b")},
+				conditional(),
+			},
+			want: []string{"a", "b", "r"},
+		},
+		{
+			name: "sibling after conditional",
+			nodes: []*WorkflowNode{
+				{ID: "a", Executor: docsExecutor("a")},
+				conditional(),
+				{ID: "b", Executor: docsExecutor("b")},
+			},
+			want: []string{"a", "r", "b"},
+		},
+		{
+			name: "join after conditional",
+			nodes: []*WorkflowNode{
+				{ID: "a", Executor: docsExecutor("a")},
+				{ID: "b", Executor: docsExecutor("b")},
+				conditional(),
+				{ID: "join", Executor: docsExecutor("join"), DependsOn: []string{"b", "c"}},
+			},
+			want: []string{"a", "b", "r", "join"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newWorkflowGraph(tt.nodes)
+			if err != nil {
+				t.Fatalf("failed to create graph: %v", err)
+			}
+
+			res, err := g.execute(context.Background(), NewExecutorParams("test", "query"), nil)
+			if err != nil {
+				t.Fatalf("failed to execute graph: %v", err)
+			}
+			if got := docContents(t, res); !slices.Equal(got, tt.want) {
+				t.Errorf("context docs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphRecordsSpans(t *testing.T) {
+	nodes := []*WorkflowNode{
+		{ID: "search", Module: "test.Docs", Executor: docsExecutor("a")},
+		{
+			ID:        "loop",
+			Module:    "test.Iterate",
+			Executor:  iterationExecutor{node: &WorkflowNode{Module: "test.Docs", Executor: docsExecutor("b")}},
+			DependsOn: []string{"search"},
+		},
+	}
+
+	g, err := newWorkflowGraph(nodes)
+	if err != nil {
+		t.Fatalf("failed to create graph: %v", err)
+	}
+
+	rec := span.NewRecorder()
+	ctx := span.WithRecorder(context.Background(), rec)
+	if _, err := g.execute(ctx, NewExecutorParams("test", "query"), nil); err != nil {
+		t.Fatalf("failed to execute graph: %v", err)
+	}
+
+	want := []struct {
+		id, parentID int
+		name, module string
+		query        string
+		// inputDocs and outputDocs summarize the context docs of the span
+		inputDocs, outputDocs string
+	}{
+		{id: 1, name: "search", module: "test.Docs", query: "query", outputDocs: "1 documents\n1. a (0.0000)"},
+		{id: 2, name: "loop", module: "test.Iterate", query: "query", inputDocs: "1 documents\n1. a (0.0000)", outputDocs: "1 documents\n1. b (0.0000)"},
+		{id: 3, parentID: 2, name: "iteration 1", outputDocs: "1 documents\n1. b (0.0000)"},
+		{id: 4, parentID: 3, name: "test.Docs", module: "test.Docs", query: "query", inputDocs: "1 documents\n1. a (0.0000)", outputDocs: "1 documents\n1. b (0.0000)"},
+	}
+
+	spans := rec.Spans()
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %+v, want %d spans", spans, len(want))
+	}
+	for i, w := range want {
+		s := spans[i]
+		if s.ID != w.id || s.ParentID != w.parentID || s.Name != w.name || s.Module != w.module {
+			t.Errorf("span %d = {id: %d, parent: %d, name: %s, module: %s}, want %+v", i, s.ID, s.ParentID, s.Name, s.Module, w)
+		}
+		if s.Query != w.query {
+			t.Errorf("span %s query = %q, want %q", s.Name, s.Query, w.query)
+		}
+		if s.Args["context_docs"] != w.inputDocs {
+			t.Errorf("span %s input docs = %q, want %q", s.Name, s.Args["context_docs"], w.inputDocs)
+		}
+		if s.Outputs["context_docs"] != w.outputDocs {
+			t.Errorf("span %s output docs = %q, want %q", s.Name, s.Outputs["context_docs"], w.outputDocs)
+		}
+		if s.CompletedAt == 0 || s.Error != "" {
+			t.Errorf("span %s did not complete: %+v", s.Name, s)
+		}
+	}
+	if rec.Dropped() != 0 {
+		t.Errorf("dropped = %d, want 0", rec.Dropped())
+	}
+}
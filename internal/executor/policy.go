@@ -0,0 +1,246 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alan-mat/awe/internal/span"
+)
+
+const (
+	BackoffConstant    = "constant"
+	BackoffExponential = "exponential"
+
+	defaultRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 30 * time.Second
+)
+
+type ErrorAction int
+
+const (
+	// OnErrorFail aborts the workflow when a node fails.
+	OnErrorFail ErrorAction = iota
+	// OnErrorContinue ignores the error and continues with the next node.
+	OnErrorContinue
+	// OnErrorRoute selects the route given by ErrorPolicy.RouteKey
+	// when a conditional node fails.
+	OnErrorRoute
+)
+
+// RetryPolicy configures how often a failed node is retried
+// and the delay between attempts.
+type RetryPolicy struct {
+	Max     int
+	Backoff string
+	Delay   time.Duration
+}
+
+// ErrorPolicy configures how a workflow node handles errors.
+// Retries are attempted first, then the fallback nodes are executed,
+// and if the node still failed, OnError decides how to proceed.
+type ErrorPolicy struct {
+	Retry    *RetryPolicy
+	Timeout  time.Duration
+	OnError  ErrorAction
+	RouteKey string
+	Fallback []*WorkflowNode
+}
+
+type ErrNodeTimeout struct {
+	Node    string
+	Timeout time.Duration
+}
+
+func (e ErrNodeTimeout) Error() string {
+	return fmt.Sprintf("node '%s' timed out after %s", e.Node, e.Timeout)
+}
+
+func (r *RetryPolicy) delay(attempt int) time.Duration {
+	delay := r.Delay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+
+	if r.Backoff == BackoffExponential {
+		delay = delay << attempt
+		if delay <= 0 || delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// isPermanent reports whether an error is caused by the workflow
+// configuration, in which case retrying the node is pointless.
+func isPermanent(err error) bool {
+	var (
+		errOperator ErrOperatorNotFound
+		errMissing  ErrArgMissing
+		errType     ErrInvalidArgumentType
+		errParams   ErrInvalidParams
+	)
+	return errors.As(err, &errOperator) || errors.As(err, &errMissing) ||
+		errors.As(err, &errType) || errors.As(err, &errParams) ||
+		errors.Is(err, context.Canceled)
+}
+
+func (n WorkflowNode) name() string {
+	if n.ID != "" {
+		return n.ID
+	}
+	if n.Operator != "" {
+		return fmt.Sprintf("%s:%s", n.Module, n.Operator)
+	}
+	return n.Module
+}
+
+// executeWithPolicy executes the node, applying its error policy.
+func (n WorkflowNode) executeWithPolicy(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	policy := n.Policy
+	if policy == nil {
+		return n.executeOnce(ctx, params)
+	}
+
+	result := n.executeWithRetry(ctx, params)
+	if result.Err == nil {
+		return result
+	}
+
+	// the error is recorded, even if the policy handles it
+	span.Current(ctx).SetError(result.Err)
+
+	if len(policy.Fallback) > 0 && ctx.Err() == nil {
+		slog.Warn("node failed, executing fallback", "node", n.name(), "err", result.Err)
+
+		fctx, s := span.Start(ctx, "fallback")
+		state, err := RunNodes(fctx, policy.Fallback, params)
+		s.End(nil, err)
+		if err == nil {
+			return StateResult(result.Name, result.Operator, params, state)
+		}
+		result.Err = fmt.Errorf("fallback of node '%s' failed: %w", n.name(), err)
+	}
+
+	switch policy.OnError {
+	case OnErrorContinue:
+		slog.Warn("node failed, continuing", "node", n.name(), "err", result.Err)
+		return &ExecutorResult{
+			Name:     result.Name,
+			Operator: result.Operator,
+		}
+	case OnErrorRoute:
+		slog.Warn("node failed, selecting error route", "node", n.name(), "route", policy.RouteKey, "err", result.Err)
+		return &ExecutorResult{
+			Name:     result.Name,
+			Operator: result.Operator,
+			Values:   map[string]any{"route_key": policy.RouteKey},
+		}
+	}
+	return result
+}
+
+func (n WorkflowNode) executeWithRetry(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	maxRetries := 0
+	if n.Policy.Retry != nil {
+		maxRetries = n.Policy.Retry.Max
+	}
+
+	for attempt := 0; ; attempt++ {
+		result := n.executeWithTimeout(ctx, copyNodeParams(params))
+		if result.Err == nil || attempt >= maxRetries || isPermanent(result.Err) {
+			if attempt > 0 {
+				span.Current(ctx).SetAttempts(attempt + 1)
+			}
+			return result
+		}
+
+		delay := n.Policy.Retry.delay(attempt)
+		slog.Warn("node failed, retrying", "node", n.name(), "attempt", attempt+1, "delay", delay, "err", result.Err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return result
+		}
+	}
+}
+
+// executeOnce coerces the args to the types declared by the node's operator
+// and executes the node once. Invalid args fail the attempt like any other
+// error of the executor, so that they are handled by the node's policy.
+func (n WorkflowNode) executeOnce(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	if d, ok := n.Executor.(Describer); ok {
+		if op, ok := d.Spec().Operator(params.Operator); ok {
+			if err := op.CoerceArgs(params.Args); err != nil {
+				return &ExecutorResult{Operator: params.Operator, Err: err}
+			}
+		}
+	}
+	return n.Executor.Execute(ctx, params)
+}
+
+// executeWithTimeout executes the node, returning once the timeout has passed
+// even if the executor does not return on context cancellation.
+func (n WorkflowNode) executeWithTimeout(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	if n.Policy.Timeout <= 0 {
+		return n.executeOnce(ctx, params)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, n.Policy.Timeout)
+	defer cancel()
+
+	done := make(chan *ExecutorResult, 1)
+	go func() {
+		done <- n.executeOnce(tctx, params)
+	}()
+
+	select {
+	case result := <-done:
+		if result.Err != nil && errors.Is(tctx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			result.Err = ErrNodeTimeout{Node: n.name(), Timeout: n.Policy.Timeout}
+		}
+		return result
+	case <-tctx.Done():
+		if ctx.Err() != nil {
+			return &ExecutorResult{Operator: params.Operator, Err: ctx.Err()}
+		}
+		return &ExecutorResult{
+			Operator: params.Operator,
+			Err:      ErrNodeTimeout{Node: n.name(), Timeout: n.Policy.Timeout},
+		}
+	}
+}
+
+// copyNodeParams copies params including the child nodes, so that
+// every attempt of a node starts from the same params.
+func copyNodeParams(params *ExecutorParams) *ExecutorParams {
+	p := params.Copy()
+	p.SetChildren(params.Children)
+	p.SetRoutes(params.Routes)
+	p.SetBranches(params.Branches)
+	return p
+}
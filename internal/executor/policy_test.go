@@ -0,0 +1,98 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// countExecutor declares a required int arg and counts its executions.
+type countExecutor struct {
+	calls int
+}
+
+func (e *countExecutor) Spec() ExecutorSpec {
+	return ExecutorSpec{
+		DefaultOperator: "count",
+		Operators: []OperatorSpec{{
+			Name: "count",
+			Args: []ArgSpec{RequiredArg[int]("n", "a number")},
+		}},
+	}
+}
+
+func (e *countExecutor) Execute(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	e.calls++
+	return &ExecutorResult{Values: map[string]any{"n": params.Args["n"]}}
+}
+
+func TestInvalidArgsAreHandledByPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *ErrorPolicy
+		wantErr bool
+	}{
+		{"no policy", nil, true},
+		{"fail", &ErrorPolicy{OnError: OnErrorFail}, true},
+		{"continue", &ErrorPolicy{OnError: OnErrorContinue}, false},
+		{"fallback", &ErrorPolicy{Fallback: []*WorkflowNode{{Executor: docsExecutor("fallback")}}}, false},
+		{"retry", &ErrorPolicy{Retry: &RetryPolicy{Max: 3}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &countExecutor{}
+			node := &WorkflowNode{ID: "node", Executor: exec, Policy: tt.policy}
+			params := NewExecutorParams("test", "query", WithArgs(map[string]any{"n": "not a number"}))
+
+			result := node.Execute(context.Background(), params)
+			if gotErr := result.Err != nil; gotErr != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", result.Err, tt.wantErr)
+			}
+			if result.Err != nil {
+				var errType ErrInvalidArgumentType
+				if !errors.As(result.Err, &errType) {
+					t.Errorf("error = %v, want ErrInvalidArgumentType", result.Err)
+				}
+			}
+			if exec.calls != 0 {
+				t.Errorf("executor called %d times with invalid args", exec.calls)
+			}
+		})
+	}
+}
+
+func TestArgsAreCoerced(t *testing.T) {
+	exec := &countExecutor{}
+	node := &WorkflowNode{ID: "node", Executor: exec}
+	params := NewExecutorParams("test", "query", WithArgs(map[string]any{"n": "10"}))
+
+	result := node.Execute(context.Background(), params)
+	if result.Err != nil {
+		t.Fatalf("failed to execute node: %v", result.Err)
+	}
+	if n, ok := result.Values["n"].(int); !ok || n != 10 {
+		t.Errorf("arg n = %#v, want 10", result.Values["n"])
+	}
+}
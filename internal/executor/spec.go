@@ -0,0 +1,114 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import "reflect"
+
+// ArgSpec describes an argument of an executor operator.
+type ArgSpec struct {
+	Name        string
+	Type        reflect.Type
+	Required    bool
+	Description string
+}
+
+// RequiredArg describes a required argument of type T.
+func RequiredArg[T any](name string, description string) ArgSpec {
+	return ArgSpec{
+		Name:        name,
+		Type:        reflect.TypeFor[T](),
+		Required:    true,
+		Description: description,
+	}
+}
+
+// OptionalArg describes an optional argument of type T.
+func OptionalArg[T any](name string, description string) ArgSpec {
+	return ArgSpec{
+		Name:        name,
+		Type:        reflect.TypeFor[T](),
+		Description: description,
+	}
+}
+
+// OperatorSpec describes an operator of an executor and the args it accepts.
+type OperatorSpec struct {
+	Name        string
+	Description string
+	// Outputs lists the names of the values returned by the operator.
+	Outputs []string
+	Args    []ArgSpec
+}
+
+// ExecutorSpec describes the operators of an executor.
+type ExecutorSpec struct {
+	DefaultOperator string
+	Operators       []OperatorSpec
+}
+
+// Operator returns the spec of the operator with the given name,
+// an empty name returns the default operator.
+func (s ExecutorSpec) Operator(name string) (OperatorSpec, bool) {
+	if name == "" {
+		name = s.DefaultOperator
+	}
+
+	for _, op := range s.Operators {
+		if op.Name == name {
+			return op, true
+		}
+	}
+	return OperatorSpec{}, false
+}
+
+// Describer is implemented by executors which describe their operators.
+// Args of described operators are checked and coerced to their declared
+// types before the executor is called.
+type Describer interface {
+	Spec() ExecutorSpec
+}
+
+// CoerceArgs checks that all required args of the operator are present and
+// converts all present args to their declared types. Args which are not
+// declared by the operator are left as they are.
+func (s OperatorSpec) CoerceArgs(args map[string]any) error {
+	for _, spec := range s.Args {
+		arg, ok := args[spec.Name]
+		if !ok || arg == nil {
+			if spec.Required {
+				return ErrArgMissing{ArgName: spec.Name}
+			}
+			continue
+		}
+
+		coerced, err := Coerce(arg, spec.Type)
+		if err != nil {
+			return ErrInvalidArgumentType{
+				Name:     spec.Name,
+				Expected: spec.Type.String(),
+				Received: reflect.TypeOf(arg).String(),
+			}
+		}
+		args[spec.Name] = coerced
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+var (
+	stateTypesLock sync.RWMutex
+	stateTypes     = make(map[string]reflect.Type)
+)
+
+func init() {
+	RegisterStateType[string]()
+	RegisterStateType[bool]()
+	RegisterStateType[int]()
+	RegisterStateType[int64]()
+	RegisterStateType[uint64]()
+	RegisterStateType[float64]()
+	RegisterStateType[[]any]()
+	RegisterStateType[[]string]()
+	RegisterStateType[map[string]any]()
+	RegisterStateType[map[string]string]()
+
+	RegisterStateType[[]*api.ScoredDocument]()
+	RegisterStateType[[]*api.ChatMessage]()
+	RegisterStateType[[]*api.FileContent]()
+}
+
+// RegisterStateType registers a type which may be stored in ExecutorParams.Args
+// or returned by executors, so that it can be restored from a checkpoint.
+// The type must be serializable to JSON.
+func RegisterStateType[T any]() {
+	RegisterStateTypeOf(reflect.TypeFor[T]())
+}
+
+// RegisterStateTypeOf registers the given type like RegisterStateType.
+func RegisterStateTypeOf(t reflect.Type) {
+	stateTypesLock.Lock()
+	defer stateTypesLock.Unlock()
+	stateTypes[t.String()] = t
+}
+
+// EncodedValue is the JSON representation of a value of a registered state type.
+type EncodedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type ErrUnregisteredStateType struct {
+	Name string
+	Type string
+}
+
+func (e ErrUnregisteredStateType) Error() string {
+	return fmt.Sprintf("value '%s' is of unregistered state type '%s'", e.Name, e.Type)
+}
+
+// EncodeValues encodes the given values, all values must be of registered state types.
+func EncodeValues(values map[string]any) (map[string]EncodedValue, error) {
+	stateTypesLock.RLock()
+	defer stateTypesLock.RUnlock()
+
+	encoded := make(map[string]EncodedValue, len(values))
+	for name, v := range values {
+		if v == nil {
+			encoded[name] = EncodedValue{Value: json.RawMessage("null")}
+			continue
+		}
+
+		typeName := reflect.TypeOf(v).String()
+		if _, ok := stateTypes[typeName]; !ok {
+			return nil, ErrUnregisteredStateType{Name: name, Type: typeName}
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value '%s': %w", name, err)
+		}
+		encoded[name] = EncodedValue{Type: typeName, Value: data}
+	}
+	return encoded, nil
+}
+
+// DecodeValues decodes values encoded by EncodeValues into their original types.
+func DecodeValues(encoded map[string]EncodedValue) (map[string]any, error) {
+	stateTypesLock.RLock()
+	defer stateTypesLock.RUnlock()
+
+	values := make(map[string]any, len(encoded))
+	for name, ev := range encoded {
+		if ev.Type == "" {
+			values[name] = nil
+			continue
+		}
+
+		t, ok := stateTypes[ev.Type]
+		if !ok {
+			return nil, ErrUnregisteredStateType{Name: name, Type: ev.Type}
+		}
+
+		v := reflect.New(t)
+		if err := json.Unmarshal(ev.Value, v.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to decode value '%s': %w", name, err)
+		}
+		values[name] = v.Elem().Interface()
+	}
+	return values, nil
+}
@@ -22,31 +22,67 @@
 package executor
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
+	"time"
 
 	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/auth"
+	"github.com/alan-mat/awe/internal/expr"
+	"github.com/alan-mat/awe/internal/session"
+	"github.com/alan-mat/awe/internal/span"
+	"github.com/alan-mat/awe/internal/telemetry"
 	"github.com/alan-mat/awe/internal/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WorkflowNode struct {
+	ID       string
+	Module   string
 	Executor Executor
 	Operator string
 	NodeType string
 	Args     map[string]any
 
+	// DependsOn lists the IDs of nodes which must complete before this node
+	// is executed. Only used by workflows executed as a graph.
+	DependsOn []string
+	// Inputs maps argument names of this node to outputs of other nodes.
+	Inputs map[string]NodeOutputRef
+	// Outputs renames values returned by this node's executor,
+	// mapping the returned name to the name it is exposed as.
+	Outputs map[string]string
+	// Policy configures retries, timeouts and fallbacks of this node,
+	// a nil policy fails the workflow on the first error.
+	Policy *ErrorPolicy
+
 	Children []*WorkflowNode
 	Routes   []*WorkflowRoute
 	Branches []*WorkflowBranch
 }
 
+// NodeOutputRef references a named output value of a workflow node.
+type NodeOutputRef struct {
+	Node   string
+	Output string
+}
+
+func (r NodeOutputRef) String() string {
+	return fmt.Sprintf("%s.%s", r.Node, r.Output)
+}
+
 type WorkflowRoute struct {
 	Key         string
 	Description string
-	Nodes       []*WorkflowNode
+	// Condition is evaluated by expression based routers,
+	// routes without a condition are used as default route.
+	Condition *expr.Expr
+	Nodes     []*WorkflowNode
 }
 
 type WorkflowBranch struct {
@@ -63,17 +99,108 @@ func NewWorkflowNode(executor Executor, operator string, nodeType string) *Workf
 	return node
 }
 
+// Execute executes the node with its error policy, recording it as a span
+// if the spans of ctx are recorded. The node is exported as a span and metrics.
 func (n WorkflowNode) Execute(ctx context.Context, params *ExecutorParams) *ExecutorResult {
-	return n.Executor.Execute(ctx, params)
+	ctx, node := telemetry.StartNode(ctx, n.name(), n.Module)
+	ctx, s := span.Start(ctx, n.name())
+	s.SetNode(n.Module, params.Operator)
+	s.SetInput(params.GetQuery(), params.Args)
+
+	result := n.executeWithPolicy(ctx, params)
+	result.Values = n.renameOutputs(result.Values)
+
+	if result.Operator != "" {
+		// executors select their default operator if none is given
+		s.SetNode(n.Module, result.Operator)
+	}
+	if key, ok := result.Values["route_key"].(string); ok && n.NodeType == "conditional" {
+		s.SetRoute(key)
+	}
+	s.End(result.Values, result.Err)
+	node.End(cmp.Or(result.Operator, params.Operator), result.Err)
+	return result
+}
+
+// renameOutputs returns the given result values with the names
+// configured in the node's Outputs applied.
+func (n WorkflowNode) renameOutputs(values map[string]any) map[string]any {
+	if len(n.Outputs) == 0 || values == nil {
+		return values
+	}
+
+	renamed := make(map[string]any, len(values))
+	for k, v := range values {
+		if name, ok := n.Outputs[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	return renamed
 }
 
 type Workflow struct {
 	identifier     string
+	version        string
+	isDefault      bool
 	description    string
 	collectionName string
 	search         bool
+	access         *auth.Access
+	history        session.Policy
+	retention      time.Duration
+	providerArgs   []string
+
+	nodes    []*WorkflowNode
+	graph    *workflowGraph
+	graphErr error
+}
+
+type WorkflowOption func(*Workflow)
+
+// WithWorkflowVersion sets the version of the workflow, multiple versions
+// of a workflow may be registered at the same time.
+func WithWorkflowVersion(version string) WorkflowOption {
+	return func(w *Workflow) {
+		w.version = version
+	}
+}
+
+// AsDefaultVersion marks the workflow as the version used
+// when a workflow is requested without a version.
+func AsDefaultVersion() WorkflowOption {
+	return func(w *Workflow) {
+		w.isDefault = true
+	}
+}
+
+// WithAccess restricts the workflow to the users and groups of access.
+func WithAccess(access auth.Access) WorkflowOption {
+	return func(w *Workflow) {
+		w.access = &access
+	}
+}
+
+// WithHistoryPolicy sets the policy limiting the history of chat sessions with the workflow.
+func WithHistoryPolicy(policy session.Policy) WorkflowOption {
+	return func(w *Workflow) {
+		w.history = policy
+	}
+}
+
+// WithRetention sets how long the traces of the workflow's requests are kept.
+func WithRetention(retention time.Duration) WorkflowOption {
+	return func(w *Workflow) {
+		w.retention = retention
+	}
+}
 
-	nodes []*WorkflowNode
+// WithProviderArgs sets the args selecting providers which requests may set,
+// see Workflow.ProviderArgs.
+func WithProviderArgs(names []string) WorkflowOption {
+	return func(w *Workflow) {
+		w.providerArgs = names
+	}
 }
 
 func NewWorkflow(
@@ -82,6 +209,7 @@ func NewWorkflow(
 	collectionName string,
 	search bool,
 	nodes []*WorkflowNode,
+	opts ...WorkflowOption,
 ) *Workflow {
 	workflow := &Workflow{
 		identifier:     identifier,
@@ -90,80 +218,242 @@ func NewWorkflow(
 		search:         search,
 		nodes:          nodes,
 	}
+	for _, opt := range opts {
+		opt(workflow)
+	}
+
+	if IsGraph(nodes) {
+		// invalid graphs are reported when executing the workflow,
+		// use ValidateGraph to check the nodes beforehand
+		workflow.graph, workflow.graphErr = newWorkflowGraph(nodes)
+	}
 	return workflow
 }
 
+func (w Workflow) Identifier() string {
+	return w.identifier
+}
+
+func (w Workflow) Version() string {
+	return w.version
+}
+
+func (w Workflow) Description() string {
+	return w.description
+}
+
+// Allows reports whether the principal p may request the workflow. Workflows
+// without access restrictions allow all requests. Unlike auth.Access.Allows,
+// a nil principal is allowed, as it means that authentication is disabled.
+func (w Workflow) Allows(p *auth.Principal) bool {
+	return w.access == nil || p == nil || w.access.Allows(p)
+}
+
+// HistoryPolicy returns the policy limiting the history of chat sessions with the workflow.
+func (w Workflow) HistoryPolicy() session.Policy {
+	return w.history
+}
+
+// Retention returns how long the traces of the workflow's requests are kept,
+// which is 0 if the default retention applies.
+func (w Workflow) Retention() time.Duration {
+	return w.retention
+}
+
+// ProviderArgs returns the names of the args selecting providers and models
+// which requests to the workflow may set. Requests must not set any other.
+func (w Workflow) ProviderArgs() []string {
+	return w.providerArgs
+}
+
+// IsDefault reports whether the workflow is marked as the default version.
+func (w Workflow) IsDefault() bool {
+	return w.isDefault
+}
+
+// Ref returns the reference of the workflow in the form 'name@version',
+// or only its name if it has no version.
+func (w Workflow) Ref() string {
+	if w.version == "" {
+		return w.identifier
+	}
+	return w.identifier + "@" + w.version
+}
+
 func (w Workflow) Execute(ctx context.Context, params *ExecutorParams) *ExecutorResult {
+	return w.execute(ctx, params, nil)
+}
+
+// ExecuteWithCheckpoints executes the workflow like Execute, saving a checkpoint
+// to params.Transport after every completed node. If cp is not nil,
+// the execution resumes from the given checkpoint.
+func (w Workflow) ExecuteWithCheckpoints(ctx context.Context, params *ExecutorParams, cp *Checkpoint) *ExecutorResult {
+	c := newCheckpointer(params.GetTaskID(), params.Transport, w.identifier, w.version)
+
+	if cp != nil {
+		if cp.WorkflowID != w.identifier || cp.WorkflowVersion != w.version {
+			return &ExecutorResult{Name: w.identifier, Err: ErrCheckpointMismatch}
+		}
+
+		args, err := DecodeValues(cp.Args)
+		if err != nil {
+			return &ExecutorResult{Name: w.identifier, Err: fmt.Errorf("failed to restore checkpoint: %w", err)}
+		}
+
+		params.SetQuery(cp.Query)
+		params.Args = args
+		c.cp = *cp
+		slog.Info("resuming workflow from checkpoint", "workflowId", w.identifier, "version", w.version, "id", params.GetTaskID())
+	} else if w.graph != nil {
+		// graph checkpoints hold the initial state
+		c.saveState(ctx, 0, params)
+	}
+
+	return w.execute(ctx, params, c)
+}
+
+// Call executes the workflow as a step of another workflow and returns the
+// resulting params. Unlike Execute, the context docs of search workflows
+// are not streamed but returned to the caller. No checkpoints are saved,
+// an interrupted call is executed again as a whole.
+func (w Workflow) Call(ctx context.Context, params *ExecutorParams) (*ExecutorParams, error) {
+	return w.run(ctx, params, nil)
+}
+
+func (w Workflow) run(ctx context.Context, params *ExecutorParams, c *checkpointer) (res *ExecutorParams, err error) {
 	params.Args["collection_name"] = w.collectionName
-	nodes := w.nodes
+
+	slog.Info("executing workflow", "workflowId", w.identifier, "version", w.version, "params", params)
+
+	ctx, ts := telemetry.Start(ctx, "workflow "+w.identifier, trace.WithAttributes(
+		attribute.String("awe.workflow", w.identifier),
+		attribute.String("awe.workflow.version", w.version),
+	))
+	defer func() { telemetry.End(ts, err) }()
+
+	if w.graphErr != nil {
+		return nil, w.graphErr
+	} else if w.graph != nil {
+		return w.graph.execute(ctx, params, c)
+	}
+	return runNodes(ctx, w.nodes, params, c)
+}
+
+func (w Workflow) execute(ctx context.Context, params *ExecutorParams, c *checkpointer) *ExecutorResult {
+	params, err := w.run(ctx, params, c)
+	if err != nil {
+		return &ExecutorResult{
+			Name: w.identifier,
+			Err:  err,
+		}
+	}
+
+	// if search-only workflow, stream context_docs
+	if w.search {
+		err = w.sendContextDocs(ctx, params)
+	}
+
+	return &ExecutorResult{
+		Name:   w.identifier,
+		Err:    err,
+		Values: params.Args,
+	}
+}
+
+// RunNodes executes the given nodes in sequence, processing the result of each node
+// into the params passed to the next one. When a conditional node is executed,
+// the remaining nodes are replaced with the nodes of the selected route.
+// It returns the params resulting from the last executed node.
+func RunNodes(ctx context.Context, nodes []*WorkflowNode, params *ExecutorParams) (*ExecutorParams, error) {
+	return runNodes(ctx, nodes, params, nil)
+}
+
+func runNodes(ctx context.Context, nodes []*WorkflowNode, params *ExecutorParams, c *checkpointer) (*ExecutorParams, error) {
 	nodeIdx := 0
 
-	slog.Info("executing workflow", "workflowId", w.identifier, "params", params)
+	if c != nil {
+		var err error
+		nodes, nodeIdx, err = c.resume(nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	for {
+	for nodeIdx < len(nodes) {
 		node := nodes[nodeIdx]
 		nodeParams := MakeNodeParams(node, params)
 
-		result := node.Executor.Execute(ctx, nodeParams)
-		// slog.Info(fmt.Sprintf("%v\n", result))
+		result := node.Execute(ctx, nodeParams)
 
 		if result.Err != nil {
 			slog.Error("failed to execute node", "error", fmt.Sprintf("(%T): %v", result.Err, result.Err))
-			return result
+			return nil, result.Err
 		}
 
 		if node.NodeType == "conditional" {
-			// conditional nodes MUST return a route_key
-			if routeKey, ok := result.Values["route_key"].(string); ok {
-				// set nodes to route nodes
-				// and reset nodeIdx
-				var nextRoute *WorkflowRoute
-				for _, r := range node.Routes {
-					if r.Key == routeKey {
-						nextRoute = r
-						break
-					}
-				}
-
-				if nextRoute == nil {
-					// invalid route key
-					slog.Error("failed to execute workflow: no route found for given key")
-					return &ExecutorResult{
-						Name: w.identifier,
-						Err:  errors.New("failed to execute workflow: no route found for given key"),
-					}
-				}
-
-				nodes = nextRoute.Nodes
-				nodeIdx = 0
-				continue
-			} else {
-				slog.Error("failed to execute workflow: conditional type node did not return a route key")
-				return &ExecutorResult{
-					Name: w.identifier,
-					Err:  errors.New("failed to execute workflow: conditional type node did not return a route key"),
-				}
+			route, err := selectRoute(node, result)
+			if err != nil {
+				slog.Error("failed to execute workflow", "err", err)
+				return nil, err
+			}
+
+			if c != nil {
+				c.saveRoute(ctx, RouteStep{Index: nodeIdx, Key: route.Key}, params)
 			}
+
+			// set nodes to route nodes
+			// and reset nodeIdx
+			nodes = route.Nodes
+			nodeIdx = 0
+			continue
 		}
 
 		params = ProcessResult(params, result)
-
 		nodeIdx++
-		if nodeIdx >= len(nodes) {
-			break
+
+		if c != nil {
+			c.saveState(ctx, nodeIdx, params)
 		}
 	}
 
-	var err error = nil
-	// if search-only workflow, stream context_docs
-	if w.search {
-		err = w.sendContextDocs(ctx, params)
+	return params, nil
+}
+
+// selectRoute returns the route of a conditional node, matching the
+// route key returned by the node's executor.
+func selectRoute(node *WorkflowNode, result *ExecutorResult) (*WorkflowRoute, error) {
+	// conditional nodes MUST return a route_key
+	routeKey, ok := result.Values["route_key"].(string)
+	if !ok {
+		return nil, errors.New("failed to execute workflow: conditional type node did not return a route key")
+	}
+
+	for _, r := range node.Routes {
+		if r.Key == routeKey {
+			return r, nil
+		}
+	}
+
+	// invalid route key
+	return nil, errors.New("failed to execute workflow: no route found for given key")
+}
+
+// StateResult creates a result from the params before and after executing
+// a sequence of nodes, which replaces the state of the params it is processed into
+// with the state after execution.
+func StateResult(name string, operator string, before *ExecutorParams, after *ExecutorParams) *ExecutorResult {
+	values := make(map[string]any, len(after.Args)+2)
+	maps.Copy(values, after.Args)
+	values["replace_context"] = true
+
+	if after.GetQuery() != before.GetQuery() {
+		values["query_transformed"] = after.GetQuery()
 	}
 
 	return &ExecutorResult{
-		Name:   w.identifier,
-		Err:    err,
-		Values: params.Args,
+		Name:     name,
+		Operator: operator,
+		Values:   values,
 	}
 }
 
@@ -0,0 +1,476 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package expr implements a small expression language, used to evaluate
+// conditions over workflow args and node outputs.
+//
+// Expressions support literals (numbers, strings, booleans, nil and lists),
+// identifiers with member and index access (args.lang, docs[0].title),
+// arithmetic, comparison, logical operators and the 'in' operator, as well as
+// the functions len, matches, contains, lower, upper, startsWith and endsWith.
+// Identifiers which do not exist evaluate to nil.
+package expr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var ErrNotBoolean = errors.New("expression did not evaluate to a boolean")
+
+type ErrSyntax struct {
+	Pos int
+	Msg string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+type ErrType struct {
+	Op  string
+	Msg string
+}
+
+func (e ErrType) Error() string {
+	return fmt.Sprintf("invalid operands for '%s': %s", e.Op, e.Msg)
+}
+
+// Expr is a compiled expression which can be evaluated
+// concurrently against different environments.
+type Expr struct {
+	source string
+	root   node
+}
+
+// Compile parses the given source into an expression.
+func Compile(source string) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expr{source: source, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression, resolving identifiers from env.
+func (e *Expr) Eval(env map[string]any) (any, error) {
+	return e.root.eval(env)
+}
+
+// EvalBool evaluates the expression and returns its result,
+// which must be a boolean. A nil result is treated as false.
+func (e *Expr) EvalBool(env map[string]any) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := truthy(v)
+	if !ok {
+		return false, ErrNotBoolean
+	}
+	return b, nil
+}
+
+func (n *literalNode) eval(env map[string]any) (any, error) {
+	return n.value, nil
+}
+
+func (n *identNode) eval(env map[string]any) (any, error) {
+	return env[n.name], nil
+}
+
+func (n *memberNode) eval(env map[string]any) (any, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return member(target, n.name), nil
+}
+
+func (n *indexNode) eval(env map[string]any) (any, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := index.(string); ok {
+		return member(target, key), nil
+	}
+
+	i, ok := toFloat(index)
+	if !ok {
+		return nil, ErrType{Op: "[]", Msg: "index must be a number or string"}
+	}
+
+	v := indirect(target)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		if i < 0 || int(i) >= v.Len() {
+			return nil, nil
+		}
+		return v.Index(int(i)).Interface(), nil
+	}
+	return nil, nil
+}
+
+func (n *listNode) eval(env map[string]any) (any, error) {
+	items := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+func (n *unaryNode) eval(env map[string]any) (any, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		b, ok := truthy(v)
+		if !ok {
+			return nil, ErrType{Op: n.op, Msg: "operand must be a boolean"}
+		}
+		return !b, nil
+	default:
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, ErrType{Op: n.op, Msg: "operand must be a number"}
+		}
+		return -f, nil
+	}
+}
+
+func (n *binaryNode) eval(env map[string]any) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// logical operators short-circuit
+	if n.op == "&&" || n.op == "||" {
+		l, ok := truthy(left)
+		if !ok {
+			return nil, ErrType{Op: n.op, Msg: "operands must be booleans"}
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := truthy(right)
+		if !ok {
+			return nil, ErrType{Op: n.op, Msg: "operands must be booleans"}
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return in(left, right)
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	default:
+		return arithmetic(n.op, left, right)
+	}
+}
+
+func (n *callNode) eval(env map[string]any) (any, error) {
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	if n.re != nil {
+		s, ok := args[0].(string)
+		if !ok {
+			return false, nil
+		}
+		return n.re.MatchString(s), nil
+	}
+	return n.fn.call(args)
+}
+
+type function struct {
+	arity []int
+	call  func(args []any) (any, error)
+}
+
+var functions = map[string]function{
+	"len": {arity: []int{1}, call: func(args []any) (any, error) {
+		if args[0] == nil {
+			return float64(0), nil
+		}
+		v := indirect(args[0])
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			return float64(v.Len()), nil
+		}
+		return nil, ErrType{Op: "len", Msg: fmt.Sprintf("unsupported type %T", args[0])}
+	}},
+	"matches": {arity: []int{2}, call: func(args []any) (any, error) {
+		s, ok1 := args[0].(string)
+		pattern, ok2 := args[1].(string)
+		if !ok2 {
+			return nil, ErrType{Op: "matches", Msg: "pattern must be a string"}
+		}
+		if !ok1 {
+			return false, nil
+		}
+		return regexp.MatchString(pattern, s)
+	}},
+	"contains": {arity: []int{2}, call: func(args []any) (any, error) {
+		return in(args[1], args[0])
+	}},
+	"lower": {arity: []int{1}, call: stringFunc("lower", strings.ToLower)},
+	"upper": {arity: []int{1}, call: stringFunc("upper", strings.ToUpper)},
+	"startsWith": {arity: []int{2}, call: func(args []any) (any, error) {
+		s, ok1 := args[0].(string)
+		prefix, ok2 := args[1].(string)
+		return ok1 && ok2 && strings.HasPrefix(s, prefix), nil
+	}},
+	"endsWith": {arity: []int{2}, call: func(args []any) (any, error) {
+		s, ok1 := args[0].(string)
+		suffix, ok2 := args[1].(string)
+		return ok1 && ok2 && strings.HasSuffix(s, suffix), nil
+	}},
+}
+
+func stringFunc(name string, fn func(string) string) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		if args[0] == nil {
+			return "", nil
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, ErrType{Op: name, Msg: "argument must be a string"}
+		}
+		return fn(s), nil
+	}
+}
+
+func truthy(v any) (bool, bool) {
+	switch b := v.(type) {
+	case nil:
+		return false, true
+	case bool:
+		return b, true
+	}
+	return false, false
+}
+
+func indirect(v any) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// member resolves a map key or struct field by name. Struct fields
+// are matched by name, ignoring case, or by their json tag.
+func member(target any, name string) any {
+	v := indirect(target)
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		val := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !val.IsValid() {
+			return nil
+		}
+		return val.Interface()
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if strings.EqualFold(f.Name, name) || tag == name {
+				return v.Field(i).Interface()
+			}
+		}
+	}
+	return nil
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func equal(left, right any) bool {
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		return ok && l == r
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func in(item, collection any) (any, error) {
+	if collection == nil {
+		return false, nil
+	}
+
+	v := indirect(collection)
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := item.(string)
+		return ok && strings.Contains(v.String(), s), nil
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if equal(item, v.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, ok := item.(string)
+		return ok && member(collection, key) != nil, nil
+	}
+	return nil, ErrType{Op: "in", Msg: fmt.Sprintf("unsupported collection type %T", collection)}
+}
+
+func compare(op string, left, right any) (any, error) {
+	var c int
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		if !ok {
+			return nil, ErrType{Op: op, Msg: "cannot compare number with non-number"}
+		}
+		c = cmp.Compare(l, r)
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return nil, ErrType{Op: op, Msg: "cannot compare string with non-string"}
+		}
+		c = strings.Compare(l, r)
+	} else {
+		return nil, ErrType{Op: op, Msg: fmt.Sprintf("cannot compare %T and %T", left, right)}
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func arithmetic(op string, left, right any) (any, error) {
+	if op == "+" {
+		if l, ok := left.(string); ok {
+			r, ok := right.(string)
+			if !ok {
+				return nil, ErrType{Op: op, Msg: "cannot add string and non-string"}
+			}
+			return l + r, nil
+		}
+	}
+
+	l, ok1 := toFloat(left)
+	r, ok2 := toFloat(right)
+	if !ok1 || !ok2 {
+		return nil, ErrType{Op: op, Msg: "operands must be numbers"}
+	}
+
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, ErrType{Op: op, Msg: "division by zero"}
+		}
+		return l / r, nil
+	default:
+		if r == 0 {
+			return nil, ErrType{Op: op, Msg: "division by zero"}
+		}
+		return math.Mod(l, r), nil
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package expr
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	env := map[string]any{
+		"query": "Wie groß ist München?",
+		"args": map[string]any{
+			"lang":  "de",
+			"top_n": 10,
+			"tags":  []string{"news", "sport"},
+		},
+		"docs": []map[string]any{
+			{"title": "a", "score": 0.9},
+			{"title": "b", "score": 0.4},
+		},
+		"größe":   3,
+		"ünicode": "ok",
+		"nothing": nil,
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want any
+	}{
+		// precedence
+		{"multiplication before addition", "1 + 2 * 3", float64(7)},
+		{"parentheses", "(1 + 2) * 3", float64(9)},
+		{"left associative subtraction", "10 - 4 - 3", float64(3)},
+		{"left associative division", "12 / 3 / 2", float64(2)},
+		{"modulo", "7 % 4 + 1", float64(4)},
+		{"unary minus", "-2 * 3", float64(-6)},
+		{"comparison before equality", "1 < 2 == true", true},
+		{"and before or", "true || false && false", true},
+		{"or with parentheses", "(true || false) && false", false},
+		{"not", "!false && !(1 > 2)", true},
+		{"in before and", "'de' in ['de', 'fr'] && args.top_n >= 10", true},
+		{"arithmetic before comparison", "args.top_n - 5 > 4", true},
+
+		// values
+		{"string concatenation", "'a' + \"b\"", "ab"},
+		{"escapes", `'a\'b\n'`, "a'b\n"},
+		{"member", "args.lang", "de"},
+		{"index", "docs[1].title", "b"},
+		{"string index", "args['lang']", "de"},
+		{"list", "[1, 'a']", []any{float64(1), "a"}},
+		{"number equality across types", "args.top_n == 10", true},
+		{"string comparison", "'a' < 'b'", true},
+
+		// functions
+		{"len", "len(docs)", float64(2)},
+		{"matches", "matches(query, '(?i)^wie')", true},
+		{"contains", "contains(args.tags, 'sport')", true},
+		{"lower", "lower('ÄB')", "äb"},
+		{"upper", "upper(args.lang)", "DE"},
+		{"startsWith", "startsWith(query, 'Wie')", true},
+		{"endsWith", "endsWith(query, '?')", true},
+
+		// nil handling
+		{"missing identifier", "missing", nil},
+		{"missing member", "args.missing", nil},
+		{"member of nil", "nothing.field", nil},
+		{"index out of range", "docs[5]", nil},
+		{"negative index", "docs[-1]", nil},
+		{"nil equals nil", "missing == nothing", true},
+		{"nil is falsy", "!missing", true},
+		{"nil and", "missing && true", false},
+		{"len of nil", "len(missing)", float64(0)},
+		{"lower of nil", "lower(missing)", ""},
+		{"in nil", "'a' in missing", false},
+		{"matches nil", "matches(missing, 'a')", false},
+		{"startsWith nil", "startsWith(missing, 'a')", false},
+
+		// unicode
+		{"unicode identifier", "größe * 2", float64(6)},
+		{"leading unicode identifier", "ünicode", "ok"},
+		{"unicode string", "query == 'Wie groß ist München?'", true},
+		{"unicode in string", "'ü' in query", true},
+		{"len counts bytes", "len('ü')", float64(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile(%q) failed: %v", tt.src, err)
+			}
+			got, err := e.Eval(env)
+			if err != nil {
+				t.Fatalf("Eval(%q) failed: %v", tt.src, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval(%q) = %#v, want %#v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{"", 0},
+		{"1 +", 3},
+		{"(1 + 2", 6},
+		{"args.", 5},
+		{"[1, 2", 5},
+		{"'unterminated", 0},
+		{"1 2", 2},
+		{"a # b", 2},
+		{"größe § 1", 8},
+		{"unknown(1)", 0},
+		{"len(1, 2)", 0},
+		{"matches(query, '(')", 0},
+		{"1..2", 0},
+		{"a\xffb", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			var syntaxErr ErrSyntax
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Compile(%q) error = %v, want ErrSyntax", tt.src, err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Compile(%q) error at position %d, want %d: %v", tt.src, syntaxErr.Pos, tt.pos, err)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	env := map[string]any{"n": 1, "s": "a", "list": []int{1}}
+
+	tests := []string{
+		"n + s",
+		"s - 1",
+		"1 / 0",
+		"1 % 0",
+		"-s",
+		"!n",
+		"n && true",
+		"false || n",
+		"n < s",
+		"s < n",
+		"list < 1",
+		"len(n)",
+		"upper(n)",
+		"1 in n",
+		"list[true]",
+		"matches(s, n)",
+	}
+
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			e, err := Compile(src)
+			if err != nil {
+				t.Fatalf("Compile(%q) failed: %v", src, err)
+			}
+			_, err = e.Eval(env)
+			var typeErr ErrType
+			if !errors.As(err, &typeErr) {
+				t.Errorf("Eval(%q) error = %v, want ErrType", src, err)
+			}
+		})
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    bool
+		wantErr error
+	}{
+		{"true", true, nil},
+		{"missing", false, nil},
+		{"1 == 1", true, nil},
+		{"'yes'", false, ErrNotBoolean},
+		{"1", false, ErrNotBoolean},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile(%q) failed: %v", tt.src, err)
+			}
+			got, err := e.EvalBool(nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EvalBool(%q) error = %v, want %v", tt.src, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvalBool(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
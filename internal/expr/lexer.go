@@ -0,0 +1,143 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any
+	pos   int
+}
+
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"<", ">", "!", "+", "-", "*", "/", "%",
+	"(", ")", "[", "]", ",", ".",
+}
+
+func tokenize(src string) ([]token, error) {
+	tokens := make([]token, 0)
+	pos := 0
+
+	for pos < len(src) {
+		c, size := utf8.DecodeRuneInString(src[pos:])
+
+		switch {
+		case c == utf8.RuneError && size == 1:
+			return nil, ErrSyntax{Pos: pos, Msg: "invalid UTF-8 encoding"}
+
+		case unicode.IsSpace(c):
+			pos += size
+
+		case isIdentStart(c):
+			start := pos
+			for pos < len(src) {
+				r, size := utf8.DecodeRuneInString(src[pos:])
+				if !isIdentStart(r) && !unicode.IsDigit(r) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:pos], pos: start})
+
+		case '0' <= c && c <= '9':
+			start := pos
+			for pos < len(src) && ('0' <= src[pos] && src[pos] <= '9' || src[pos] == '.') {
+				pos++
+			}
+			num, err := strconv.ParseFloat(src[start:pos], 64)
+			if err != nil {
+				return nil, ErrSyntax{Pos: start, Msg: fmt.Sprintf("invalid number '%s'", src[start:pos])}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:pos], value: num, pos: start})
+
+		case c == '"' || c == '\'':
+			start := pos
+			var sb strings.Builder
+			pos++
+			for {
+				if pos >= len(src) {
+					return nil, ErrSyntax{Pos: start, Msg: "unterminated string"}
+				}
+				if src[pos] == byte(c) {
+					pos++
+					break
+				}
+				if src[pos] == '\\' && pos+1 < len(src) {
+					pos++
+					switch src[pos] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[pos])
+					}
+					pos++
+					continue
+				}
+				sb.WriteByte(src[pos])
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[start:pos], value: sb.String(), pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[pos:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, ErrSyntax{Pos: pos, Msg: fmt.Sprintf("unexpected character '%c'", c)}
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(src)})
+	return tokens, nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
@@ -0,0 +1,305 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// node is a single node of a compiled expression tree.
+type node interface {
+	eval(env map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+type identNode struct {
+	name string
+}
+
+type memberNode struct {
+	target node
+	name   string
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+type listNode struct {
+	items []node
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+	// re holds the compiled pattern of 'matches' calls with a literal pattern
+	re *regexp.Regexp
+}
+
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4, "in": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.text != op {
+		return ErrSyntax{Pos: t.pos, Msg: fmt.Sprintf("expected '%s'", op)}
+	}
+	return nil
+}
+
+func (p *parser) isOperator(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.text == op
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, ErrSyntax{Pos: t.pos, Msg: fmt.Sprintf("unexpected '%s'", t.text)}
+	}
+	return n, nil
+}
+
+func (p *parser) binaryOperator() (string, int) {
+	t := p.peek()
+	if t.kind == tokenOperator || (t.kind == tokenIdent && t.text == "in") {
+		if prec, ok := binaryPrecedence[t.text]; ok {
+			return t.text, prec
+		}
+	}
+	return "", 0
+}
+
+func (p *parser) parseBinary(minPrec int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, prec := p.binaryOperator()
+		if prec == 0 || prec < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") || p.isOperator("-") {
+		op := p.next().text
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.isOperator("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, ErrSyntax{Pos: t.pos, Msg: "expected field name after '.'"}
+			}
+			n = &memberNode{target: n, name: t.text}
+
+		case p.isOperator("["):
+			p.next()
+			index, err := p.parseBinary(1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: index}
+
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "nil", "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if p.isOperator("(") {
+			return p.parseCall(t)
+		}
+		return &identNode{name: t.text}, nil
+
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			n, err := p.parseBinary(1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, ErrSyntax{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return nil, ErrSyntax{Pos: t.pos, Msg: fmt.Sprintf("unexpected '%s'", t.text)}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, ErrSyntax{Pos: name.pos, Msg: fmt.Sprintf("unknown function '%s'", name.text)}
+	}
+
+	p.next()
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(fn.arity, len(args)) {
+		return nil, ErrSyntax{Pos: name.pos, Msg: fmt.Sprintf("function '%s' does not accept %d arguments", name.text, len(args))}
+	}
+
+	call := &callNode{name: name.text, fn: fn, args: args}
+	if name.text == "matches" {
+		if lit, ok := args[1].(*literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, ErrSyntax{Pos: name.pos, Msg: "pattern of 'matches' must be a string"}
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, ErrSyntax{Pos: name.pos, Msg: fmt.Sprintf("invalid pattern: %v", err)}
+			}
+			call.re = re
+		}
+	}
+	return call, nil
+}
+
+func (p *parser) parseList(end string) ([]node, error) {
+	items := make([]node, 0)
+	if p.isOperator(end) {
+		p.next()
+		return items, nil
+	}
+
+	for {
+		item, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		if p.isOperator(",") {
+			p.next()
+			continue
+		}
+		if err := p.expect(end); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+}
@@ -23,12 +23,15 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	gohttp "net/http"
 	"net/url"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Common HTTP method, as defined in net/http package
@@ -54,6 +57,7 @@ var retryStatusCodes = map[int]bool{
 
 type Client struct {
 	httpClient *gohttp.Client
+	timeout    time.Duration
 	maxRetries int
 
 	endpoint string
@@ -65,27 +69,41 @@ type ClientOption func(*Client)
 func NewClient(endpoint string, opts ...ClientOption) Client {
 	c := Client{
 		endpoint: endpoint,
-		httpClient: &gohttp.Client{
-			Timeout: 60 * time.Second,
-		},
+		timeout:  60 * time.Second,
 	}
 
 	for _, opt := range opts {
 		opt(&c)
 	}
 
+	c.httpClient = &gohttp.Client{
+		Transport: NewTransport(c.timeout),
+	}
 	return c
 }
 
+// NewTransport returns a RoundTripper waiting at most timeout for the response
+// headers of a request, a timeout of 0 means no timeout. Reading the body
+// is not limited, so that streamed responses can take as long as they need,
+// use the context of a request to cancel it.
+// Every attempt of a request is traced as a span of its own.
+func NewTransport(timeout time.Duration) gohttp.RoundTripper {
+	t := gohttp.DefaultTransport.(*gohttp.Transport).Clone()
+	t.ResponseHeaderTimeout = timeout
+	return otelhttp.NewTransport(t)
+}
+
 func WithApiKey(key string) ClientOption {
 	return func(c *Client) {
 		c.apiKey = key
 	}
 }
 
+// WithTimeout sets how long requests wait for the response headers,
+// see NewTransport. It defaults to 60 seconds.
 func WithTimeout(timeout time.Duration) ClientOption {
 	return func(c *Client) {
-		c.httpClient.Timeout = timeout
+		c.timeout = timeout
 	}
 }
 
@@ -96,7 +114,12 @@ func WithMaxRetries(maxRetries int) ClientOption {
 }
 
 func (c *Client) Request(method string, path string, paylaod map[string]any) (map[string]any, error) {
-	resp, err := c.do(method, path, paylaod)
+	return c.RequestWithContext(context.Background(), method, path, paylaod)
+}
+
+// RequestWithContext is like Request, cancelling the request when ctx is done.
+func (c *Client) RequestWithContext(ctx context.Context, method string, path string, paylaod map[string]any) (map[string]any, error) {
+	resp, err := c.do(ctx, method, path, paylaod)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +140,13 @@ func (c *Client) Request(method string, path string, paylaod map[string]any) (ma
 }
 
 func (c *Client) RequestStream(method string, path string, paylaod map[string]any) (io.ReadCloser, error) {
-	resp, err := c.do(method, path, paylaod)
+	return c.RequestStreamWithContext(context.Background(), method, path, paylaod)
+}
+
+// RequestStreamWithContext is like RequestStream, cancelling the request
+// and closing the stream when ctx is done.
+func (c *Client) RequestStreamWithContext(ctx context.Context, method string, path string, paylaod map[string]any) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, method, path, paylaod)
 	if err != nil {
 		return nil, err
 	}
@@ -125,15 +154,15 @@ func (c *Client) RequestStream(method string, path string, paylaod map[string]an
 	return resp.Body, nil
 }
 
-func (c *Client) do(method string, path string, paylaod map[string]any) (*gohttp.Response, error) {
+func (c *Client) do(ctx context.Context, method string, path string, paylaod map[string]any) (*gohttp.Response, error) {
 	uri, err := url.Parse(c.endpoint)
 	if err != nil {
 		return nil, err
 	}
-	uri.Path = path
+	uri = uri.JoinPath(path)
 
 	jsonData, _ := json.Marshal(paylaod)
-	req, err := gohttp.NewRequest(method, uri.String(), bytes.NewBuffer(jsonData))
+	req, err := gohttp.NewRequestWithContext(ctx, method, uri.String(), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -144,16 +173,24 @@ func (c *Client) do(method string, path string, paylaod map[string]any) (*gohttp
 	req.Header.Set("Content-Type", "application/json")
 
 	var resp *gohttp.Response
-	for i := range c.maxRetries {
+	attempts := max(c.maxRetries, 1)
+	for i := range attempts {
+		if i > 0 {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
 		resp, err = c.httpClient.Do(req)
 		if err != nil {
-			if i == c.maxRetries-1 {
+			if i == attempts-1 {
 				return nil, err
 			}
 			continue
 		}
 
-		if _, ok := retryStatusCodes[resp.StatusCode]; ok {
+		if _, ok := retryStatusCodes[resp.StatusCode]; ok && i < attempts-1 {
+			resp.Body.Close()
 			time.Sleep(time.Duration(i+1) * 500 * time.Millisecond)
 			continue
 		}
@@ -173,3 +210,68 @@ func (c *Client) do(method string, path string, paylaod map[string]any) (*gohttp
 
 	return resp, nil
 }
+
+type retryTransport struct {
+	base       gohttp.RoundTripper
+	maxRetries int
+}
+
+// NewRetryTransport returns a RoundTripper making up to maxRetries attempts
+// of a request failing with a retryable status code, using the same backoff as Client.
+// It is meant for third party SDKs accepting a custom *http.Client.
+func NewRetryTransport(base gohttp.RoundTripper, maxRetries int) gohttp.RoundTripper {
+	if base == nil {
+		base = gohttp.DefaultTransport
+	}
+	return &retryTransport{
+		base:       base,
+		maxRetries: max(maxRetries, 1),
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *gohttp.Request) (*gohttp.Response, error) {
+	// requests with a body can only be retried if it can be rewound
+	hasBody := req.Body != nil && req.Body != gohttp.NoBody
+	if hasBody && req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	var (
+		resp *gohttp.Response
+		err  error
+	)
+	for i := range t.maxRetries {
+		if i > 0 {
+			if hasBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(time.Duration(i) * 500 * time.Millisecond):
+			}
+		}
+
+		resp, err = t.base.RoundTrip(req)
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, ok := retryStatusCodes[resp.StatusCode]; ok && i < t.maxRetries-1 {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+	return resp, err
+}
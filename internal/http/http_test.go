@@ -0,0 +1,76 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package http
+
+import (
+	"context"
+	"io"
+	gohttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutDoesNotLimitStreams(t *testing.T) {
+	srv := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.WriteHeader(gohttp.StatusOK)
+		w.(gohttp.Flusher).Flush()
+		for range 3 {
+			time.Sleep(50 * time.Millisecond)
+			io.WriteString(w, "data\n")
+			w.(gohttp.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithTimeout(75*time.Millisecond))
+	body, err := c.RequestStreamWithContext(context.Background(), MethodPost, "/", nil)
+	if err != nil {
+		t.Fatalf("failed to start stream: %v", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if string(data) != "data\ndata\ndata\n" {
+		t.Errorf("stream = %q", data)
+	}
+}
+
+func TestTimeoutLimitsResponseHeaders(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	c := NewClient(srv.URL, WithTimeout(50*time.Millisecond))
+	if _, err := c.RequestWithContext(context.Background(), MethodPost, "/", nil); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+}
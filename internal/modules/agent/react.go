@@ -0,0 +1,520 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/auth"
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/provider"
+	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/transport"
+	"github.com/hibiken/asynq"
+)
+
+var reactExecutorDescriptor = "agent.ReAct"
+
+var ErrUnknownTool = errors.New("unknown tool")
+
+const defaultSystemPrompt = "You are a helpful assistant. Use the provided tools to find the information " +
+	"needed to answer the question of the user. Answer once you have enough information."
+
+// args of the agent which are not passed on to its tools
+var agentArgs = []string{"tools", "system_prompt", "max_steps", "history", "provider", "model"}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+func registerReActExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Answers the query with a language model which calls executors and workflows as tools.",
+		Capabilities: []string{string(provider.CapabilityLM)},
+	}
+	return registry.RegisterExecutorFactory(reactExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e := NewReActExecutor()
+		if err := provider.Check(provider.CapabilityLM, e.DefaultLM); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
+}
+
+// ReActExecutor runs an agent loop, in which the language model either calls
+// tools or answers the query. The results of the tool calls are passed back
+// to the model until it answers or the maximum number of steps is reached.
+type ReActExecutor struct {
+	// DefaultLM is used unless the node args or the
+	// configuration select another provider.
+	DefaultLM string
+	operators map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
+}
+
+func NewReActExecutor() *ReActExecutor {
+	e := &ReActExecutor{
+		DefaultLM: "openai",
+	}
+	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
+		"react": e.react,
+	}
+	return e
+}
+
+func (e *ReActExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "react",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "react",
+				Description: "Answers the query, calling the given tools as requested by the language model.",
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[[]string]("tools", "executors or workflows the model may call, an executor may select its operator as 'name:operator'"),
+					executor.OptionalArg[string]("system_prompt", "instructions for the model"),
+					executor.OptionalArg[int]("max_steps", "maximum number of model calls, defaults to 5"),
+					executor.OptionalArg[[]*api.ChatMessage]("history", "previous messages of the chat"),
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+				Outputs: []string{"generation_results", "context_docs"},
+			},
+		},
+	}
+}
+
+func (e *ReActExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
+	if p.Operator == "" {
+		p.Operator = "react"
+	}
+	slog.Info("executing", "name", reactExecutorDescriptor, "op", p.Operator, "query", p.GetQuery(), "id", p.GetTaskID())
+
+	opFunc, exists := e.operators[p.Operator]
+	if !exists {
+		return e.buildResult(p.Operator, executor.ErrOperatorNotFound{
+			ExecutorName: reactExecutorDescriptor, OperatorName: p.Operator}, nil)
+	}
+
+	vals, err := opFunc(ctx, p)
+	return e.buildResult(p.Operator, err, vals)
+}
+
+func (e *ReActExecutor) react(ctx context.Context, p *executor.ExecutorParams) (map[string]any, error) {
+	if len(p.GetQuery()) == 0 {
+		return nil, fmt.Errorf("<empty query>: %w", asynq.SkipRetry)
+	}
+
+	ms, err := p.Transport.GetMessageStream(p.GetTaskID())
+	if err != nil {
+		slog.Warn("failed to create message stream", "id", p.GetTaskID())
+		return nil, err
+	}
+
+	toolNames, err := executor.GetTypedArg[[]string](p, "tools")
+	if err != nil {
+		return nil, err
+	}
+	tools, err := resolveTools(auth.FromContext(ctx), toolNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tools {
+		t.hideArgs(p.Args)
+	}
+
+	systemPrompt, err := executor.GetTypedArg[string](p, "system_prompt")
+	if err != nil || systemPrompt == "" {
+		systemPrompt = defaultSystemPrompt
+	}
+
+	maxSteps, err := executor.GetTypedArg[int](p, "max_steps")
+	if err != nil || maxSteps <= 0 {
+		maxSteps = 5
+	}
+
+	history, err := executor.GetTypedArg[[]*api.ChatMessage](p, "history")
+	if err != nil {
+		if _, ok := err.(executor.ErrArgMissing); !ok {
+			return nil, err
+		}
+	}
+
+	lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+	if err != nil {
+		return nil, err
+	}
+
+	toolDefs := make([]*api.Tool, 0, len(tools))
+	byName := make(map[string]*tool, len(tools))
+	for _, t := range tools {
+		toolDefs = append(toolDefs, t.def)
+		byName[t.def.Name] = t
+	}
+
+	messages := append([]*api.ChatMessage{}, history...)
+	messages = append(messages, &api.ChatMessage{Role: api.RoleUser, Content: p.GetQuery()})
+
+	var contextDocs []*api.ScoredDocument
+	for step := range maxSteps {
+		req := api.ChatRequest{
+			SystemPrompt: systemPrompt,
+			History:      messages,
+		}
+		// the last step has to answer the query
+		if step < maxSteps-1 {
+			req.Tools = toolDefs
+		}
+
+		c, err := e.step(ctx, lm, ms, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(c.ToolCalls) == 0 {
+			return map[string]any{
+				"generation_results": c.Content,
+				"context_docs":       contextDocs,
+			}, nil
+		}
+
+		messages = append(messages, &api.ChatMessage{
+			Role:      api.RoleAssistant,
+			Content:   c.Content,
+			ToolCalls: c.ToolCalls,
+		})
+
+		for _, call := range c.ToolCalls {
+			slog.Info("calling tool", "name", reactExecutorDescriptor, "tool", call.Name, "step", step, "id", p.GetTaskID())
+
+			output, docs, err := e.callTool(ctx, p, byName[call.Name], call)
+			if err != nil {
+				// the model is told about failed calls, so that it may recover
+				slog.Warn("tool call failed", "tool", call.Name, "id", p.GetTaskID(), "err", err)
+				output = fmt.Sprintf("error: %s", err)
+			}
+			contextDocs = append(contextDocs, docs...)
+
+			messages = append(messages, &api.ChatMessage{
+				Role:       api.RoleTool,
+				Content:    output,
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+			})
+		}
+	}
+
+	return nil, fmt.Errorf("agent did not answer within %d steps", maxSteps)
+}
+
+// step calls the model once, streaming its response to ms, and returns
+// the completion including the requested tool calls.
+func (e *ReActExecutor) step(ctx context.Context, lm provider.LM, ms transport.MessageStream, req api.ChatRequest) (*api.Completion, error) {
+	cs, err := lm.Chat(ctx, req)
+	if err != nil {
+		slog.Warn("error creating chat completion stream, cancelling task")
+		ms.Send(ctx, transport.MessageStreamPayload{
+			Content: "something went wrong",
+			Status:  "ERR",
+		})
+		return nil, err
+	}
+	defer cs.Close()
+
+	c, err := transport.ProcessCompletionStream(ctx, ms, cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process completion stream: %w", err)
+	}
+	return c, nil
+}
+
+// callTool executes the tool requested by call and returns its output as text
+// along with the documents it retrieved. Messages sent by the tool are
+// captured instead of being streamed to the client.
+func (e *ReActExecutor) callTool(ctx context.Context, p *executor.ExecutorParams, t *tool, call *api.ToolCall) (string, []*api.ScoredDocument, error) {
+	if t == nil {
+		return "", nil, fmt.Errorf("%w: '%s'", ErrUnknownTool, call.Name)
+	}
+
+	capture := transport.NewMemoryTransport()
+	tp := p.Copy()
+	tp.Transport = captureTransport{Transport: p.Transport, capture: capture}
+	tp.Operator = t.operator
+	for _, name := range agentArgs {
+		delete(tp.Args, name)
+	}
+	for name, val := range call.Arguments {
+		if _, ok := t.def.Parameters.Properties[name]; !ok {
+			// the model may only set the args described to it
+			slog.Warn("dropping undeclared tool argument", "tool", t.def.Name, "arg", name)
+			continue
+		}
+		if name == "query" {
+			if q, ok := val.(string); ok && q != "" {
+				tp.SetQuery(q)
+			}
+			continue
+		}
+		if _, ok := tp.Args[name]; ok {
+			// args set by the workflow are not replaced by the model
+			slog.Warn("dropping tool argument set by the workflow", "tool", t.def.Name, "arg", name)
+			continue
+		}
+		tp.Args[name] = val
+	}
+	// fixed args are set last, the model can not replace them
+	for name, val := range t.args {
+		tp.Args[name] = val
+	}
+
+	res := executor.NewWorkflowNode(t.exec, t.operator, "").Execute(ctx, tp)
+	if res.Err != nil {
+		return "", nil, res.Err
+	}
+
+	docs, _ := executor.GetTypedResult[[]*api.ScoredDocument](res, "context_docs")
+
+	ms, err := capture.GetMessageStream(tp.GetTaskID())
+	if err != nil {
+		return "", nil, err
+	}
+	streamed, err := ms.Text(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return formatToolOutput(res.Values, docs, streamed), docs, nil
+}
+
+func (e *ReActExecutor) buildResult(operator string, err error, values map[string]any) *executor.ExecutorResult {
+	return &executor.ExecutorResult{
+		Name:     reactExecutorDescriptor,
+		Operator: operator,
+		Err:      err,
+		Values:   values,
+	}
+}
+
+// tool is an executor, or a workflow called through workflow.Call,
+// which may be called by the model.
+type tool struct {
+	def      *api.Tool
+	exec     executor.Executor
+	operator string
+	// args are fixed args of the tool, which the model does not set
+	args map[string]any
+}
+
+// hideArgs removes the args set by the workflow, and the fixed args of the
+// tool, from the parameters of the tool, so that they are not offered to
+// the model.
+func (t *tool) hideArgs(args map[string]any) {
+	params := t.def.Parameters
+	for _, name := range slices.Concat(slices.Collect(maps.Keys(args)), slices.Collect(maps.Keys(t.args))) {
+		if name == "query" {
+			continue
+		}
+		delete(params.Properties, name)
+		params.Required = slices.DeleteFunc(params.Required, func(r string) bool { return r == name })
+	}
+}
+
+// resolveTools looks up the executors and workflows with the given names.
+// Executors take precedence over workflows of the same name. Workflows
+// must allow the principal p, which is nil if authentication is disabled.
+func resolveTools(p *auth.Principal, names []string) ([]*tool, error) {
+	tools := make([]*tool, 0, len(names))
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		t, err := resolveTool(p, name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[t.def.Name] {
+			return nil, fmt.Errorf("duplicate tool '%s'", t.def.Name)
+		}
+		seen[t.def.Name] = true
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+func resolveTool(p *auth.Principal, name string) (*tool, error) {
+	execName, operator, _ := strings.Cut(name, ":")
+
+	exec, err := registry.GetExecutor(execName)
+	if err == nil {
+		return executorTool(name, exec, operator)
+	}
+	if !errors.Is(err, registry.ErrExecutorNotFound) {
+		return nil, err
+	}
+
+	wf, wfErr := registry.GetWorkflow(name)
+	if wfErr != nil {
+		return nil, fmt.Errorf("%w: '%s' is neither an executor nor a workflow", ErrUnknownTool, name)
+	}
+	if !wf.Allows(p) {
+		return nil, fmt.Errorf("%w: workflow '%s'", auth.ErrAccessDenied, wf.Ref())
+	}
+
+	call, err := registry.GetExecutor("workflow.Call")
+	if err != nil {
+		return nil, err
+	}
+
+	description := wf.Description()
+	if description == "" {
+		description = fmt.Sprintf("Runs the workflow '%s'.", wf.Ref())
+	}
+	return &tool{
+		def: &api.Tool{
+			Name:        toolName(wf.Ref()),
+			Description: description,
+			Parameters:  parametersSchema(nil),
+		},
+		exec:     call,
+		operator: "call",
+		args:     map[string]any{"workflow": wf.Ref()},
+	}, nil
+}
+
+func executorTool(name string, exec executor.Executor, operator string) (*tool, error) {
+	t := &tool{
+		exec:     exec,
+		operator: operator,
+		def: &api.Tool{
+			Name:       toolName(name),
+			Parameters: parametersSchema(nil),
+		},
+	}
+
+	d, ok := exec.(executor.Describer)
+	if !ok {
+		t.def.Description = fmt.Sprintf("Runs '%s' with the given query.", name)
+		return t, nil
+	}
+
+	op, ok := d.Spec().Operator(operator)
+	if !ok {
+		return nil, executor.ErrOperatorNotFound{ExecutorName: name, OperatorName: operator}
+	}
+	t.operator = op.Name
+	t.def.Description = op.Description
+	t.def.Parameters = parametersSchema(op.Args)
+	return t, nil
+}
+
+// toolName returns name in the form accepted by providers as a function name.
+func toolName(name string) string {
+	return invalidToolNameChars.ReplaceAllString(name, "_")
+}
+
+// parametersSchema describes the query and the scalar args of an operator,
+// which are the args the model may set when calling a tool. Args selecting
+// providers are left out, they are only set by the workflow.
+func parametersSchema(args []executor.ArgSpec) *api.Schema {
+	schema := &api.Schema{
+		Type: api.TypeObject,
+		Properties: map[string]*api.Schema{
+			"query": {Type: api.TypeString, Description: "query passed to the tool"},
+		},
+		Required: []string{"query"},
+	}
+
+	for _, arg := range args {
+		if slices.Contains(provider.SelectionArgs(), arg.Name) {
+			continue
+		}
+
+		var typ api.DataType
+		switch arg.Type.Kind() {
+		case reflect.String:
+			typ = api.TypeString
+		case reflect.Bool:
+			typ = api.TypeBoolean
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			typ = api.TypeInteger
+		case reflect.Float32, reflect.Float64:
+			typ = api.TypeNumber
+		default:
+			// args of other types are set by the workflow, not the model
+			continue
+		}
+
+		schema.Properties[arg.Name] = &api.Schema{Type: typ, Description: arg.Description}
+		if arg.Required {
+			schema.Required = append(schema.Required, arg.Name)
+		}
+	}
+	return schema
+}
+
+// formatToolOutput returns the result of a tool call as text for the model.
+func formatToolOutput(values map[string]any, docs []*api.ScoredDocument, streamed string) string {
+	if out, ok := values["generation_results"].(string); ok && out != "" {
+		return out
+	}
+
+	if len(docs) > 0 {
+		var sb strings.Builder
+		for i, doc := range docs {
+			if i > 0 {
+				sb.WriteString("\n---\n")
+			}
+			if doc.Title != "" {
+				sb.WriteString(doc.Title + "\n")
+			}
+			sb.WriteString(strings.TrimSpace(doc.Content))
+		}
+		return sb.String()
+	}
+
+	if streamed != "" {
+		return streamed
+	}
+
+	if len(values) > 0 {
+		if data, err := json.Marshal(values); err == nil {
+			return string(data)
+		}
+	}
+	return "done"
+}
+
+// captureTransport keeps the messages sent by tools from the client
+// by sending them to a separate transport.
+type captureTransport struct {
+	transport.Transport
+	capture *transport.MemoryTransport
+}
+
+func (t captureTransport) GetMessageStream(id string) (transport.MessageStream, error) {
+	return t.capture.GetMessageStream(id)
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package agent
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+// argsExecutor records the query and args it was executed with.
+type argsExecutor struct {
+	query string
+	args  map[string]any
+}
+
+func (e *argsExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
+	e.query = p.GetQuery()
+	e.args = maps.Clone(p.Args)
+	return &executor.ExecutorResult{}
+}
+
+// specArgsExecutor is an argsExecutor describing its args.
+type specArgsExecutor struct {
+	argsExecutor
+}
+
+func (e *specArgsExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "read",
+		Operators: []executor.OperatorSpec{{
+			Name: "read",
+			Args: []executor.ArgSpec{
+				executor.OptionalArg[string]("path", "path to read"),
+				executor.OptionalArg[string]("collection_name", "collection to search"),
+				executor.OptionalArg[string]("embedder", "embedding provider"),
+				executor.OptionalArg[int]("top_n", "number of results"),
+			},
+		}},
+	}
+}
+
+func TestCallToolArgs(t *testing.T) {
+	exec := &argsExecutor{}
+	tl := &tool{
+		def: &api.Tool{
+			Name:       "workflow",
+			Parameters: parametersSchema([]executor.ArgSpec{executor.OptionalArg[int]("top_n", "number of results")}),
+		},
+		exec:     exec,
+		operator: "call",
+		args:     map[string]any{"workflow": "search"},
+	}
+	p := executor.NewExecutorParams("id", "question", executor.WithTransport(transport.NewMemoryTransport()))
+
+	call := &api.ToolCall{Name: "workflow", Arguments: map[string]any{
+		"query":    "tool query",
+		"top_n":    3,
+		"workflow": "admin",
+		"provider": "openai",
+	}}
+	if _, _, err := NewReActExecutor().callTool(context.Background(), p, tl, call); err != nil {
+		t.Fatalf("failed to call tool: %v", err)
+	}
+
+	if exec.query != "tool query" {
+		t.Errorf("query = %q, want %q", exec.query, "tool query")
+	}
+	want := map[string]any{"workflow": "search", "top_n": 3}
+	if !maps.Equal(exec.args, want) {
+		t.Errorf("args = %v, want %v", exec.args, want)
+	}
+}
+
+func TestToolArgsSetByWorkflow(t *testing.T) {
+	exec := &specArgsExecutor{}
+	tl, err := executorTool("test.Reader", exec, "")
+	if err != nil {
+		t.Fatalf("failed to create tool: %v", err)
+	}
+	p := executor.NewExecutorParams("id", "question",
+		executor.WithTransport(transport.NewMemoryTransport()),
+		executor.WithArgs(map[string]any{"path": "/data/docs", "collection_name": "docs"}))
+	tl.hideArgs(p.Args)
+
+	for _, name := range []string{"path", "collection_name", "embedder"} {
+		if _, ok := tl.def.Parameters.Properties[name]; ok {
+			t.Errorf("arg %q is offered to the model", name)
+		}
+	}
+	if _, ok := tl.def.Parameters.Properties["top_n"]; !ok {
+		t.Errorf("arg %q is not offered to the model", "top_n")
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want map[string]any
+	}{
+		{
+			name: "offered args",
+			args: map[string]any{"query": "q", "top_n": 2},
+			want: map[string]any{"path": "/data/docs", "collection_name": "docs", "top_n": 2},
+		},
+		{
+			name: "args set by the workflow",
+			args: map[string]any{"query": "q", "path": "/etc/passwd", "collection_name": "secrets"},
+			want: map[string]any{"path": "/data/docs", "collection_name": "docs"},
+		},
+		{
+			name: "selection args",
+			args: map[string]any{"query": "q", "embedder": "other", "model": "other"},
+			want: map[string]any{"path": "/data/docs", "collection_name": "docs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := &api.ToolCall{Name: tl.def.Name, Arguments: tt.args}
+			if _, _, err := NewReActExecutor().callTool(context.Background(), p, tl, call); err != nil {
+				t.Fatalf("failed to call tool: %v", err)
+			}
+			if !maps.Equal(exec.args, tt.want) {
+				t.Errorf("args = %v, want %v", exec.args, tt.want)
+			}
+		})
+	}
+}
@@ -24,7 +24,6 @@ package generation
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -74,22 +73,27 @@ const (
 `
 )
 
-func init() {
-	exec, err := NewAugmentedExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", augmentedExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(augmentedExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", augmentedExecutorDescriptor)
+func registerAugmentedExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Generates responses to the query grounded in the context documents.",
+		Capabilities: []string{string(provider.CapabilityLM)},
 	}
+	return registry.RegisterExecutorFactory(augmentedExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewAugmentedExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityLM, e.DefaultLM); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type AugmentedExecutor struct {
-	DefaultEmbedProvider provider.Embedder
-	DefaultLMProvider    provider.LM
+	// DefaultLM is used unless the node args or the
+	// configuration select another provider.
+	DefaultLM string
 
 	operators map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 
@@ -97,18 +101,10 @@ type AugmentedExecutor struct {
 }
 
 func NewAugmentedExecutor() (*AugmentedExecutor, error) {
-	ep, err := provider.NewEmbedder(provider.EmbedderTypeJina)
-	lp, err2 := provider.NewLM(provider.LMTypeOpenai)
-	joinedErr := errors.Join(err, err2)
-	if joinedErr != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %w", err)
-	}
-
 	templ := template.Must(template.New("promptGenerateWithContext").Parse(promptGenerateWithContext))
 
 	e := &AugmentedExecutor{
-		DefaultEmbedProvider:        ep,
-		DefaultLMProvider:           lp,
+		DefaultLM:                   "openai",
 		templateGenerateWithContext: *templ,
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
@@ -117,6 +113,24 @@ func NewAugmentedExecutor() (*AugmentedExecutor, error) {
 	return e, nil
 }
 
+func (e AugmentedExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "gen_context",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "gen_context",
+				Description: "Generates a response to the query using the context documents.",
+				Outputs:     []string{"generation_results"},
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[[]*api.ScoredDocument]("context_docs", "documents used as context"),
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+			},
+		},
+	}
+}
+
 func (e AugmentedExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "gen_context"
@@ -183,7 +197,12 @@ func (e AugmentedExecutor) generateWithContext(ctx context.Context, p *executor.
 		return nil, err
 	}
 
-	stream, err := e.DefaultLMProvider.Chat(ctx, api.ChatRequest{
+	lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := lm.Chat(ctx, api.ChatRequest{
 		Query:        p.GetQuery(),
 		SystemPrompt: parsedPrompt,
 	})
@@ -203,6 +222,6 @@ func (e AugmentedExecutor) generateWithContext(ctx context.Context, p *executor.
 	}
 
 	return map[string]any{
-		"generation_results": output,
+		"generation_results": output.Content,
 	}, nil
 }
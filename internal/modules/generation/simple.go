@@ -36,32 +36,33 @@ import (
 
 var simpleExecutorDescriptor = "generation.Simple"
 
-func init() {
-	e, err := NewSimpleExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", simpleExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(simpleExecutorDescriptor, e)
-	if err != nil {
-		slog.Error("failed to register executor", "name", simpleExecutorDescriptor)
-	}
+func registerSimpleExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Generates responses to the query with a language model.",
+		Capabilities: []string{string(provider.CapabilityLM)},
+	}
+	return registry.RegisterExecutorFactory(simpleExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewSimpleExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityLM, e.DefaultLM); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type SimpleExecutor struct {
-	DefaultLMProvider provider.LM
-	operators         map[string]func(context.Context, *executor.ExecutorParams) error
+	// DefaultLM is used unless the node args or the
+	// configuration select another provider.
+	DefaultLM string
+	operators map[string]func(context.Context, *executor.ExecutorParams) error
 }
 
 func NewSimpleExecutor() (*SimpleExecutor, error) {
-	lp, err := provider.NewLM(provider.LMTypeOpenai)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %w", err)
-	}
-
 	e := &SimpleExecutor{
-		DefaultLMProvider: lp,
+		DefaultLM: "openai",
 	}
 
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) error{
@@ -71,6 +72,32 @@ func NewSimpleExecutor() (*SimpleExecutor, error) {
 	return e, nil
 }
 
+func (e *SimpleExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "generate",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "generate",
+				Description: "Generates a response to the query.",
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[float64]("temperature", "sampling temperature"),
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+			},
+			{
+				Name:        "chat",
+				Description: "Generates a chat response to the query.",
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[[]*api.ChatMessage]("history", "previous messages of the chat"),
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+			},
+		},
+	}
+}
+
 func (e *SimpleExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "generate"
@@ -108,7 +135,12 @@ func (e SimpleExecutor) generate(ctx context.Context, p *executor.ExecutorParams
 		greq.Temperature = float32(temperature)
 	}
 
-	cs, err := e.DefaultLMProvider.Generate(ctx, *greq)
+	lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+	if err != nil {
+		return err
+	}
+
+	cs, err := lm.Generate(ctx, *greq)
 	if err != nil {
 		slog.Warn("error creating generation completion stream, cancelling task")
 		ms.Send(ctx, transport.MessageStreamPayload{
@@ -138,12 +170,11 @@ func (e *SimpleExecutor) chat(ctx context.Context, p *executor.ExecutorParams) e
 		return fmt.Errorf("<empty query>: %w", asynq.SkipRetry)
 	}
 
-	var history []*api.ChatMessage
-	h, ok := p.Args["history"]
-	if !ok {
-		history = nil
-	} else {
-		history = h.([]*api.ChatMessage)
+	history, err := executor.GetTypedArg[[]*api.ChatMessage](p, "history")
+	if err != nil {
+		if _, ok := err.(executor.ErrArgMissing); !ok {
+			return err
+		}
 	}
 
 	creq := api.ChatRequest{
@@ -151,7 +182,12 @@ func (e *SimpleExecutor) chat(ctx context.Context, p *executor.ExecutorParams) e
 		History: history,
 	}
 
-	cs, err := e.DefaultLMProvider.Chat(ctx, creq)
+	lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+	if err != nil {
+		return err
+	}
+
+	cs, err := lm.Chat(ctx, creq)
 	if err != nil {
 		slog.Warn("error creating chat completion stream, cancelling task")
 		ms.Send(ctx, transport.MessageStreamPayload{
@@ -23,7 +23,6 @@ package indexing
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -38,39 +37,43 @@ import (
 
 var simpleExecutorDescriptor = "indexing.Simple"
 
-func init() {
-	exec, err := NewSimpleExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", simpleExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(simpleExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", simpleExecutorDescriptor, "err", err)
+func registerSimpleExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Parses, segments and embeds files into a vector store collection.",
+		Capabilities: []string{string(provider.CapabilityDocParser), string(provider.CapabilitySegmenter), string(provider.CapabilityEmbedder)},
 	}
+	return registry.RegisterExecutorFactory(simpleExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewSimpleExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityDocParser, e.DefaultParser); err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilitySegmenter, e.DefaultSegmenter); err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityEmbedder, e.DefaultEmbedder); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
+// The default providers are used unless the node args
+// or the configuration select other providers.
 type SimpleExecutor struct {
-	DefaultParseProvider   provider.DocParser
-	DefaultSegmentProvider provider.Segmenter
-	DefaultEmbedProvider   provider.Embedder
-	operators              map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
+	DefaultParser    string
+	DefaultSegmenter string
+	DefaultEmbedder  string
+	operators        map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 }
 
 func NewSimpleExecutor() (*SimpleExecutor, error) {
-	pp, err1 := provider.NewDocParser(provider.DocParserTypeMistral)
-	sp, err2 := provider.NewSegmenter(provider.SegmenterTypeJina)
-	ep, err3 := provider.NewEmbedder(provider.EmbedderTypeJina)
-	joinedErr := errors.Join(err1, err2, err3)
-	if joinedErr != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %e", joinedErr)
-	}
-
 	e := &SimpleExecutor{
-		DefaultParseProvider:   pp,
-		DefaultSegmentProvider: sp,
-		DefaultEmbedProvider:   ep,
+		DefaultParser:    "mistral",
+		DefaultSegmenter: "jina",
+		DefaultEmbedder:  "jina",
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
 		"index_files_base64": e.indexFilesBase64,
@@ -78,6 +81,26 @@ func NewSimpleExecutor() (*SimpleExecutor, error) {
 	return e, nil
 }
 
+func (e SimpleExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "index_files_base64",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "index_files_base64",
+				Description: "Segments, embeds and stores base64 encoded files in the vector store.",
+				Outputs:     []string{"points_indexed"},
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[[]*api.FileContent]("file_contents", "base64 encoded files to index"),
+					executor.RequiredArg[string]("collection_name", "vector store collection to index into"),
+					executor.OptionalArg[string]("parser", "document parser provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("segmenter", "segmenter provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("embedder", "embedding provider, as 'name' or 'name/model'"),
+				},
+			},
+		},
+	}
+}
+
 func (e SimpleExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "index_files_base64"
@@ -122,13 +145,26 @@ func (e SimpleExecutor) indexFilesBase64(ctx context.Context, p *executor.Execut
 		return nil, fmt.Errorf("argument 'collection_name' must be of type 'string'")
 	}
 
+	parser, err := provider.SelectDocParser(p.Args, e.DefaultParser)
+	if err != nil {
+		return nil, err
+	}
+	segmenter, err := provider.SelectSegmenter(p.Args, e.DefaultSegmenter)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := provider.SelectEmbedder(p.Args, e.DefaultEmbedder)
+	if err != nil {
+		return nil, err
+	}
+
 	if exists, err := p.VectorStore.CollectionExists(ctx, collectionName); err == nil {
 		if !exists {
 			slog.Info("requested collection not found", "name", collectionName)
 
 			err := p.VectorStore.CreateCollection(ctx, vector.Collection{
 				Name:       collectionName,
-				Dimensions: e.DefaultEmbedProvider.GetDimensions(),
+				Dimensions: embedder.GetDimensions(),
 			})
 
 			slog.Info("successfully created collection", "name", collectionName)
@@ -153,7 +189,7 @@ func (e SimpleExecutor) indexFilesBase64(ctx context.Context, p *executor.Execut
 		go func(ctx context.Context, file *api.FileContent) {
 			defer wg.Done()
 
-			chunks := e.parseAndSegmentFile(ctx, file)
+			chunks := e.parseAndSegmentFile(ctx, parser, segmenter, file)
 			if len(chunks) > 0 {
 				docReqMu.Lock()
 				docRequests = append(docRequests, &api.EmbedDocumentRequest{
@@ -172,7 +208,7 @@ func (e SimpleExecutor) indexFilesBase64(ctx context.Context, p *executor.Execut
 		return nil, fmt.Errorf("failed to index files: no files parsed")
 	}
 
-	embeddings, err := e.DefaultEmbedProvider.EmbedDocuments(ctx, docRequests)
+	embeddings, err := embedder.EmbedDocuments(ctx, docRequests)
 	if err != nil {
 		return nil, fmt.Errorf("failed to embed %d documents: %e", len(docRequests), err)
 	}
@@ -188,14 +224,14 @@ func (e SimpleExecutor) indexFilesBase64(ctx context.Context, p *executor.Execut
 	}, nil
 }
 
-func (e SimpleExecutor) parseAndSegmentFile(ctx context.Context, file *api.FileContent) []string {
-	parsed, err := e.DefaultParseProvider.Parse(ctx, file.Content)
+func (e SimpleExecutor) parseAndSegmentFile(ctx context.Context, parser provider.DocParser, segmenter provider.Segmenter, file *api.FileContent) []string {
+	parsed, err := parser.Parse(ctx, file.Content)
 	if err != nil {
 		slog.Error("failed to parse file, skipping...", "name", file.Name, "err", err)
 		return nil
 	}
 
-	chunks, err := e.DefaultSegmentProvider.ChunkDocument(ctx, parsed)
+	chunks, err := segmenter.ChunkDocument(ctx, parsed)
 	if err != nil {
 		slog.Error("failed to segment file, skipping...", "name", file.Name, "err", err)
 		return nil
@@ -0,0 +1,63 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package modules registers the executors of all builtin modules.
+package modules
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/alan-mat/awe/internal/modules/agent"
+	"github.com/alan-mat/awe/internal/modules/generation"
+	"github.com/alan-mat/awe/internal/modules/indexing"
+	"github.com/alan-mat/awe/internal/modules/orchestration"
+	"github.com/alan-mat/awe/internal/modules/postretrieval"
+	"github.com/alan-mat/awe/internal/modules/preretrieval"
+	"github.com/alan-mat/awe/internal/modules/retrieval"
+	"github.com/alan-mat/awe/internal/modules/system"
+	"github.com/alan-mat/awe/internal/modules/workflow"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// RegisterAll registers the executors of all builtin modules. Executors are
+// only created on first use, so registering them does not create any provider.
+// Calling RegisterAll more than once has no effect.
+func RegisterAll() error {
+	registerOnce.Do(func() {
+		registerErr = errors.Join(
+			agent.Register(),
+			generation.Register(),
+			indexing.Register(),
+			orchestration.Register(),
+			postretrieval.Register(),
+			preretrieval.Register(),
+			retrieval.Register(),
+			system.Register(),
+			workflow.Register(),
+		)
+	})
+	return registerErr
+}
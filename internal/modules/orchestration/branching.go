@@ -31,21 +31,18 @@ import (
 	"github.com/alan-mat/awe/internal/api"
 	"github.com/alan-mat/awe/internal/executor"
 	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/span"
 )
 
 var branchingExecutorDescriptor = "orchestration.Branching"
 
-func init() {
-	exec, err := NewBranchingExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", branchingExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(branchingExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", branchingExecutorDescriptor)
+func registerBranchingExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description: "Executes branches concurrently and fuses their context documents.",
 	}
+	return registry.RegisterExecutorFactory(branchingExecutorDescriptor, meta, func() (executor.Executor, error) {
+		return NewBranchingExecutor()
+	})
 }
 
 type BranchingExecutor struct {
@@ -60,6 +57,23 @@ func NewBranchingExecutor() (*BranchingExecutor, error) {
 	return e, nil
 }
 
+func (e BranchingExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "rrf",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "rrf",
+				Description: "Executes all branches concurrently and fuses their context documents using reciprocal rank fusion.",
+				Outputs:     []string{"context_docs"},
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[uint64]("rrf_k", "ranking constant (default: 60)"),
+					executor.OptionalArg[uint64]("limit", "maximum amount of fused documents"),
+				},
+			},
+		},
+	}
+}
+
 func (e BranchingExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "rrf"
@@ -120,24 +134,27 @@ func (e BranchingExecutor) rrf(ctx context.Context, p *executor.ExecutorParams)
 	var wg sync.WaitGroup
 	for _, branch := range p.Branches {
 		wg.Add(1)
-		go func(nodes []*executor.WorkflowNode, params *executor.ExecutorParams, resultsChan chan map[string]any) {
+		go func(name string, nodes []*executor.WorkflowNode, params *executor.ExecutorParams, resultsChan chan map[string]any) {
 			defer wg.Done()
 			var result *executor.ExecutorResult
 
+			bctx, s := span.Start(ctx, "branch "+name)
 			for _, node := range nodes {
 				nodeParams := executor.MakeNodeParams(node, params)
-				result = node.Executor.Execute(ctx, nodeParams)
+				result = node.Execute(bctx, nodeParams)
 
 				if result.Err != nil {
 					slog.Error("failed to execute node", "error", fmt.Sprintf("(%T): %v", result.Err, result.Err))
+					s.SetError(result.Err)
 					break
 				}
 
 				params = executor.ProcessResult(params, result)
 			}
+			s.End(nil, nil)
 
 			resultsChan <- params.Args
-		}(branch.Nodes, p.Copy(), resultsChan)
+		}(branch.Name, branch.Nodes, p.Copy(), resultsChan)
 	}
 
 	wg.Wait()
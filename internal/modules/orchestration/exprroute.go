@@ -0,0 +1,145 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/registry"
+)
+
+var exprRouteExecutorDescriptor = "orchestration.ExprRoute"
+
+var ErrNoRouteMatched = errors.New("no route condition matched and no default route is defined")
+
+func registerExprRouteExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description: "Selects routes by evaluating their conditions.",
+	}
+	return registry.RegisterExecutorFactory(exprRouteExecutorDescriptor, meta, func() (executor.Executor, error) {
+		return NewExprRouteExecutor(), nil
+	})
+}
+
+// ExprRouteExecutor selects routes by evaluating the 'when' conditions
+// of the routes, without calling any model.
+type ExprRouteExecutor struct {
+	operators map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
+}
+
+func NewExprRouteExecutor() *ExprRouteExecutor {
+	e := &ExprRouteExecutor{}
+	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
+		"first_match": e.firstMatch,
+	}
+	return e
+}
+
+func (e ExprRouteExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "first_match",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "first_match",
+				Description: "Selects the first route whose condition is true, or the default route.",
+				Outputs:     []string{"route_key"},
+			},
+		},
+	}
+}
+
+func (e ExprRouteExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
+	if p.Operator == "" {
+		p.Operator = "first_match"
+	}
+	slog.Info("executing", "name", exprRouteExecutorDescriptor, "op", p.Operator, "query", p.GetQuery(), "id", p.GetTaskID())
+
+	opFunc, exists := e.operators[p.Operator]
+	if !exists {
+		return e.buildResult(p.Operator, executor.ErrOperatorNotFound{
+			ExecutorName: exprRouteExecutorDescriptor, OperatorName: p.Operator}, nil)
+	}
+
+	if len(p.Routes) == 0 {
+		return e.buildResult(p.Operator, executor.ErrInvalidParams{
+			ExecutorName:  exprRouteExecutorDescriptor,
+			MissingParams: []string{"routes"},
+		}, nil)
+	}
+
+	vals, err := opFunc(ctx, p)
+	return e.buildResult(p.Operator, err, vals)
+}
+
+// firstMatch selects the first route whose condition evaluates to true.
+// If no condition matches, the first route without a condition is selected.
+//
+// Conditions can reference all args by name, the args map itself as 'args'
+// and the current query as 'query', e.g.:
+//
+//	len(context_docs) == 0
+//	args.lang == "de"
+//	matches(query, "(?i)^translate")
+func (e ExprRouteExecutor) firstMatch(ctx context.Context, p *executor.ExecutorParams) (map[string]any, error) {
+	env := make(map[string]any, len(p.Args)+2)
+	maps.Copy(env, p.Args)
+	env["args"] = p.Args
+	env["query"] = p.GetQuery()
+
+	var defaultRoute *executor.WorkflowRoute
+	for _, route := range p.Routes {
+		if route.Condition == nil {
+			if defaultRoute == nil {
+				defaultRoute = route
+			}
+			continue
+		}
+
+		matched, err := route.Condition.EvalBool(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate condition '%s' of route '%s': %w", route.Condition, route.Key, err)
+		}
+		if matched {
+			slog.Debug("route condition matched", "key", route.Key, "condition", route.Condition.String())
+			return map[string]any{"route_key": route.Key}, nil
+		}
+	}
+
+	if defaultRoute == nil {
+		return nil, ErrNoRouteMatched
+	}
+	return map[string]any{"route_key": defaultRoute.Key}, nil
+}
+
+func (e ExprRouteExecutor) buildResult(operator string, err error, values map[string]any) *executor.ExecutorResult {
+	return &executor.ExecutorResult{
+		Name:     exprRouteExecutorDescriptor,
+		Operator: operator,
+		Err:      err,
+		Values:   values,
+	}
+}
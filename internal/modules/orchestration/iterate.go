@@ -35,6 +35,7 @@ import (
 	"github.com/alan-mat/awe/internal/executor"
 	"github.com/alan-mat/awe/internal/provider"
 	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/span"
 )
 
 var iterateExecutorDescriptor = "orchestration.Iterate"
@@ -68,32 +69,37 @@ Determine if the 'Context Documents' are sufficient to fully, accurately, and tr
 `
 )
 
-func init() {
-	exec, err := NewIterateExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", iterateExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(iterateExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", iterateExecutorDescriptor)
+func registerIterateExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Executes the child nodes repeatedly, optionally until a language model judges the results sufficient.",
+		Capabilities: []string{string(provider.CapabilityLM)},
 	}
+	return registry.RegisterExecutorFactory(iterateExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewIterateExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityLM, e.DefaultLM); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type IterateExecutor struct {
-	DefaultLM provider.LM
+	// DefaultLM is used unless the node args or the
+	// configuration select another provider.
+	DefaultLM string
 
 	operators        map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 	templateLLMJudge template.Template
 }
 
 func NewIterateExecutor() (*IterateExecutor, error) {
-	lp, _ := provider.NewLM(provider.LMTypeOpenai)
 	templ := template.Must(template.New("promptLLMJudge").Parse(promptLLMJudge))
 
 	e := &IterateExecutor{
-		DefaultLM:        lp,
+		DefaultLM:        "openai",
 		templateLLMJudge: *templ,
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
@@ -103,6 +109,32 @@ func NewIterateExecutor() (*IterateExecutor, error) {
 	return e, nil
 }
 
+func (e IterateExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "fixed_iters",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "fixed_iters",
+				Description: "Executes the child nodes a fixed amount of times.",
+				Outputs:     []string{"context_docs"},
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[uint64]("num_iters", "amount of iterations (default: 3)"),
+				},
+			},
+			{
+				Name:        "llm_judge_context_rewrite",
+				Description: "Executes the child nodes until a model judges the context documents as sufficient, rewriting the query between iterations.",
+				Outputs:     []string{"context_docs"},
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[uint64]("max_iters", "maximum amount of iterations (default: 3)"),
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+			},
+		},
+	}
+}
+
 func (e IterateExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "fixed_iters"
@@ -159,20 +191,23 @@ func (e IterateExecutor) fixedIterations(ctx context.Context, p *executor.Execut
 	runtimeParams := p.Copy()
 	for i := range numIters {
 
+		ictx, s := span.Start(ctx, fmt.Sprintf("iteration %d", i+1))
 		for j, node := range p.Children {
 			slog.Info("running iteration", "i", i, "nodeIdx", j)
 
 			nodeParams := executor.MakeNodeParams(node, runtimeParams)
 
-			result := node.Executor.Execute(ctx, nodeParams)
+			result := node.Execute(ictx, nodeParams)
 
 			if result.Err != nil {
 				slog.Error("failed to execute node", "error", fmt.Sprintf("(%T): %v", result.Err, result.Err))
+				s.End(nil, result.Err)
 				return nil, fmt.Errorf("iteration failed on node '%s': %w", node.Operator, result.Err)
 			}
 
 			runtimeParams = executor.ProcessResult(runtimeParams, result)
 		}
+		s.End(nil, nil)
 
 	}
 
@@ -212,20 +247,23 @@ func (e IterateExecutor) llmJudgeContextRewrite(ctx context.Context, p *executor
 	runtimeParams := p.Copy()
 	for i := range maxIters {
 
+		ictx, s := span.Start(ctx, fmt.Sprintf("iteration %d", i+1))
 		for j, node := range p.Children {
 			slog.Info("running iteration", "i", i, "nodeIdx", j)
 
 			nodeParams := executor.MakeNodeParams(node, runtimeParams)
 
-			result := node.Executor.Execute(ctx, nodeParams)
+			result := node.Execute(ictx, nodeParams)
 
 			if result.Err != nil {
 				slog.Error("failed to execute node", "error", fmt.Sprintf("(%T): %v", result.Err, result.Err))
+				s.End(nil, result.Err)
 				return nil, fmt.Errorf("iteration failed on node '%s': %w", node.Operator, result.Err)
 			}
 
 			runtimeParams = executor.ProcessResult(runtimeParams, result)
 		}
+		s.End(nil, nil)
 
 		// gather context_docs
 		// get response from judge
@@ -278,7 +316,12 @@ func (e IterateExecutor) llmJudgeContextRewrite(ctx context.Context, p *executor
 			//Temperature:    0.2,
 		}
 
-		cs, err := e.DefaultLM.Generate(ctx, req)
+		lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+		if err != nil {
+			return nil, err
+		}
+
+		cs, err := lm.Generate(ctx, req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate llm judge results: %w", err)
 		}
@@ -58,21 +58,27 @@ Available Routes (JSON):
 `
 )
 
-func init() {
-	exec, err := NewRouteExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", routeExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(routeExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", routeExecutorDescriptor)
-	}
+func registerRouteExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Selects routes for the query with a language model.",
+		Capabilities: []string{string(provider.CapabilityLM)},
+	}
+	return registry.RegisterExecutorFactory(routeExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewRouteExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityLM, e.DefaultLM); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type RouteExecutor struct {
-	DefaultLM provider.LM
+	// DefaultLM is used unless the node args or the
+	// configuration select another provider.
+	DefaultLM string
 
 	operators map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 
@@ -80,14 +86,9 @@ type RouteExecutor struct {
 }
 
 func NewRouteExecutor() (*RouteExecutor, error) {
-	lp, err := provider.NewLM(provider.LMTypeGemini)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %w", err)
-	}
-
 	templ := template.Must(template.New("promptLLMSelector").Parse(promptLLMSelector))
 	e := &RouteExecutor{
-		DefaultLM:           lp,
+		DefaultLM:           "gemini",
 		templateLLMSelector: *templ,
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
@@ -96,6 +97,23 @@ func NewRouteExecutor() (*RouteExecutor, error) {
 	return e, nil
 }
 
+func (e RouteExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "llm_selector",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "llm_selector",
+				Description: "Selects the route whose description matches the query best using a model.",
+				Outputs:     []string{"route_key"},
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+			},
+		},
+	}
+}
+
 func (e RouteExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "llm_selector"
@@ -189,7 +207,12 @@ func (e RouteExecutor) llmSelector(ctx context.Context, p *executor.ExecutorPara
 		Temperature:    0.2,
 	}
 
-	cs, err := e.DefaultLM.Generate(ctx, req)
+	lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := lm.Generate(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query router results: %w", err)
 	}
@@ -35,32 +35,33 @@ import (
 
 var rerankExecutorDescriptor = "post.Rerank"
 
-func init() {
-	exec, err := NewRerankExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", rerankExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(rerankExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", rerankExecutorDescriptor)
+func registerRerankExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Reranks the context documents by relevance to the query.",
+		Capabilities: []string{string(provider.CapabilityReranker)},
 	}
+	return registry.RegisterExecutorFactory(rerankExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewRerankExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityReranker, e.DefaultReranker); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type RerankExecutor struct {
-	DefaultReranker provider.Reranker
+	// DefaultReranker is used unless the node args or the
+	// configuration select another provider.
+	DefaultReranker string
 	operators       map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 }
 
 func NewRerankExecutor() (*RerankExecutor, error) {
-	rp, err := provider.NewReranker(provider.RerankerTypeCohere)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %e", err)
-	}
-
 	e := &RerankExecutor{
-		DefaultReranker: rp,
+		DefaultReranker: "cohere",
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
 		"cohere_rerank": e.cohereRerank,
@@ -68,6 +69,25 @@ func NewRerankExecutor() (*RerankExecutor, error) {
 	return e, nil
 }
 
+func (e RerankExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "cohere_rerank",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "cohere_rerank",
+				Description: "Reranks the context documents by relevance to the query.",
+				Outputs:     []string{"context_docs"},
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[[]*api.ScoredDocument]("context_docs", "documents to rerank"),
+					executor.OptionalArg[uint64]("top_n", "maximum amount of documents returned"),
+					executor.OptionalArg[float64]("threshold", "minimum relevance score of returned documents"),
+					executor.OptionalArg[string]("reranker", "reranker provider, as 'name' or 'name/model'"),
+				},
+			},
+		},
+	}
+}
+
 func (e RerankExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "cohere_rerank"
@@ -144,7 +164,12 @@ func (e RerankExecutor) cohereRerank(ctx context.Context, p *executor.ExecutorPa
 		rerankRequest.Threshold = &thresholdArg
 	}
 
-	resp, err := e.DefaultReranker.Rerank(ctx, *rerankRequest)
+	reranker, err := provider.SelectReranker(p.Args, e.DefaultReranker)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := reranker.Rerank(ctx, *rerankRequest)
 	if err != nil {
 		return nil, fmt.Errorf("rerank request failed: %w", err)
 	}
@@ -46,36 +46,37 @@ Rewritten Query:
 `
 )
 
-func init() {
-	exec, err := NewTransformExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", transformExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(transformExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", transformExecutorDescriptor)
+func registerTransformExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Rewrites the query with a language model.",
+		Capabilities: []string{string(provider.CapabilityLM)},
 	}
+	return registry.RegisterExecutorFactory(transformExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewTransformExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityLM, e.DefaultLM); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type TransformExecutor struct {
-	DefaultLMProvider provider.LM
-	promptRewrite     *template.Template
-	operators         map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
+	// DefaultLM is used unless the node args or the
+	// configuration select another provider.
+	DefaultLM     string
+	promptRewrite *template.Template
+	operators     map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 }
 
 func NewTransformExecutor() (*TransformExecutor, error) {
-	lp, err := provider.NewLM(provider.LMTypeGemini)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %e", err)
-	}
-
 	templ := template.Must(template.New("promptRewrite").Parse(promptRewrite))
 
 	e := &TransformExecutor{
-		DefaultLMProvider: lp,
-		promptRewrite:     templ,
+		DefaultLM:     "gemini",
+		promptRewrite: templ,
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
 		"rewrite": e.rewriteSimple,
@@ -83,6 +84,23 @@ func NewTransformExecutor() (*TransformExecutor, error) {
 	return e, nil
 }
 
+func (e TransformExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "rewrite",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "rewrite",
+				Description: "Rewrites the query for retrieval using a model.",
+				Outputs:     []string{"query_original", "query_transformed"},
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[string]("provider", "language model provider, as 'name' or 'name/model'"),
+					executor.OptionalArg[string]("model", "model of the language model provider"),
+				},
+			},
+		},
+	}
+}
+
 func (e TransformExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "rewrite"
@@ -129,7 +147,12 @@ func (e TransformExecutor) rewriteSimple(ctx context.Context, p *executor.Execut
 		Prompt:      parsedPrompt,
 		Temperature: 0.2,
 	}
-	cs, err := e.DefaultLMProvider.Generate(ctx, req)
+	lm, err := provider.SelectLM(p.Args, e.DefaultLM)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := lm.Generate(ctx, req)
 	if err != nil {
 		slog.Warn("error creating generation completion stream, cancelling task")
 		return nil, err
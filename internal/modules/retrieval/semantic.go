@@ -35,32 +35,33 @@ import (
 
 var semanticExecutorDescriptor = "retrieval.Semantic"
 
-func init() {
-	exec, err := NewSemanticExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", semanticExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(semanticExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", semanticExecutorDescriptor)
+func registerSemanticExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Retrieves context documents from a vector store collection.",
+		Capabilities: []string{string(provider.CapabilityEmbedder)},
 	}
+	return registry.RegisterExecutorFactory(semanticExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewSemanticExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityEmbedder, e.DefaultEmbedder); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type SemanticExecutor struct {
-	DefaultEmbedProvider provider.Embedder
-	operators            map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
+	// DefaultEmbedder is used unless the node args or the
+	// configuration select another provider.
+	DefaultEmbedder string
+	operators       map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 }
 
 func NewSemanticExecutor() (*SemanticExecutor, error) {
-	ep, err := provider.NewEmbedder(provider.EmbedderTypeOpenai)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %e", err)
-	}
-
 	e := &SemanticExecutor{
-		DefaultEmbedProvider: ep,
+		DefaultEmbedder: "openai",
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
 		"dense": e.denseRetrieval,
@@ -68,6 +69,24 @@ func NewSemanticExecutor() (*SemanticExecutor, error) {
 	return e, nil
 }
 
+func (e *SemanticExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "dense",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "dense",
+				Description: "Retrieves documents similar to the query from the vector store.",
+				Outputs:     []string{"context_docs"},
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[string]("collection_name", "vector store collection to query"),
+					executor.OptionalArg[uint64]("top_n", "maximum amount of documents returned"),
+					executor.OptionalArg[string]("embedder", "embedding provider, as 'name' or 'name/model'"),
+				},
+			},
+		},
+	}
+}
+
 func (e *SemanticExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "dense"
@@ -97,7 +116,12 @@ func (e *SemanticExecutor) denseRetrieval(ctx context.Context, p *executor.Execu
 		return nil, fmt.Errorf("operator failed: vector store is not initialized")
 	}
 
-	vec, err := e.DefaultEmbedProvider.EmbedQuery(ctx, p.GetQuery())
+	embedder, err := provider.SelectEmbedder(p.Args, e.DefaultEmbedder)
+	if err != nil {
+		return nil, err
+	}
+
+	vec, err := embedder.EmbedQuery(ctx, p.GetQuery())
 	if err != nil {
 		return nil, fmt.Errorf("failed to embed query '%s': %e", p.GetQuery(), err)
 	}
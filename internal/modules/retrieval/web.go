@@ -35,32 +35,33 @@ import (
 
 var webRetrieverExecutorDescriptor = "retrieval.Web"
 
-func init() {
-	exec, err := NewWebExecutor()
-	if err != nil {
-		slog.Error("failed to initialize executor", "name", webRetrieverExecutorDescriptor, "err", err)
-		return
-	}
-
-	err = registry.RegisterExecutor(webRetrieverExecutorDescriptor, exec)
-	if err != nil {
-		slog.Error("failed to register executor", "name", webRetrieverExecutorDescriptor)
+func registerWebExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description:  "Retrieves context documents from a web search.",
+		Capabilities: []string{string(provider.CapabilityWebSearcher)},
 	}
+	return registry.RegisterExecutorFactory(webRetrieverExecutorDescriptor, meta, func() (executor.Executor, error) {
+		e, err := NewWebExecutor()
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Check(provider.CapabilityWebSearcher, e.DefaultWebSearcher); err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 type WebExecutor struct {
-	DefaultWebSearcher provider.WebSearcher
+	// DefaultWebSearcher is used unless the node args or the
+	// configuration select another provider.
+	DefaultWebSearcher string
 	operators          map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
 }
 
 func NewWebExecutor() (*WebExecutor, error) {
-	wp, err := provider.NewWebSearcher(provider.WebSearcherTypeTavily)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize default providers: %w", err)
-	}
-
 	e := &WebExecutor{
-		DefaultWebSearcher: wp,
+		DefaultWebSearcher: "tavily",
 	}
 	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
 		"search": e.webSearch,
@@ -68,6 +69,23 @@ func NewWebExecutor() (*WebExecutor, error) {
 	return e, nil
 }
 
+func (e WebExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "search",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "search",
+				Description: "Searches the web for the query.",
+				Outputs:     []string{"context_docs"},
+				Args: []executor.ArgSpec{
+					executor.OptionalArg[uint64]("top_n", "maximum amount of documents returned"),
+					executor.OptionalArg[string]("web_searcher", "web search provider"),
+				},
+			},
+		},
+	}
+}
+
 func (e WebExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "search"
@@ -112,7 +130,12 @@ func (e WebExecutor) webSearch(ctx context.Context, p *executor.ExecutorParams)
 		req.Limit = int(topN)
 	}
 
-	resp, err := e.DefaultWebSearcher.Search(ctx, req)
+	searcher, err := provider.SelectWebSearcher(p.Args, e.DefaultWebSearcher)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := searcher.Search(ctx, req)
 	if err != nil {
 		return nil, err
 	}
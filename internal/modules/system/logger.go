@@ -31,12 +31,13 @@ import (
 
 var loggerExecutorDescriptor = "system.Logger"
 
-func init() {
-	e := NewLoggerExecutor()
-	err := registry.RegisterExecutor(loggerExecutorDescriptor, e)
-	if err != nil {
-		slog.Error("failed to register executor", "name", loggerExecutorDescriptor)
+func registerLoggerExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description: "Logs the content of the message stream.",
 	}
+	return registry.RegisterExecutorFactory(loggerExecutorDescriptor, meta, func() (executor.Executor, error) {
+		return NewLoggerExecutor(), nil
+	})
 }
 
 type LoggerExecutor struct {
@@ -51,6 +52,19 @@ func NewLoggerExecutor() *LoggerExecutor {
 	return e
 }
 
+func (e *LoggerExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "acc_stream",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "acc_stream",
+				Description: "Logs the accumulated content of the message stream.",
+				Outputs:     []string{"content"},
+			},
+		},
+	}
+}
+
 func (e *LoggerExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "acc_stream"
@@ -36,12 +36,13 @@ import (
 
 var readerExecutorDescriptor = "system.Reader"
 
-func init() {
-	e := NewReaderExecutor()
-	err := registry.RegisterExecutor(readerExecutorDescriptor, e)
-	if err != nil {
-		slog.Error("failed to register executor", "name", readerExecutorDescriptor)
+func registerReaderExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description: "Reads the files of a directory as file contents.",
 	}
+	return registry.RegisterExecutorFactory(readerExecutorDescriptor, meta, func() (executor.Executor, error) {
+		return NewReaderExecutor(), nil
+	})
 }
 
 type ReaderExecutor struct {
@@ -56,6 +57,22 @@ func NewReaderExecutor() *ReaderExecutor {
 	return e
 }
 
+func (e *ReaderExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "read_dir_base64",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "read_dir_base64",
+				Description: "Reads all files of a directory as base64 encoded file contents.",
+				Outputs:     []string{"file_contents"},
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[string]("path", "directory to read, relative or absolute"),
+				},
+			},
+		},
+	}
+}
+
 func (e *ReaderExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
 	if p.Operator == "" {
 		p.Operator = "read_dir_base64"
@@ -0,0 +1,144 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/alan-mat/awe/internal/auth"
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/registry"
+)
+
+var callExecutorDescriptor = "workflow.Call"
+
+var ErrRecursiveCall = errors.New("workflow calls itself")
+
+func registerCallExecutor() error {
+	meta := registry.ExecutorMeta{
+		Description: "Runs another workflow as a node.",
+	}
+	return registry.RegisterExecutorFactory(callExecutorDescriptor, meta, func() (executor.Executor, error) {
+		return NewCallExecutor(), nil
+	})
+}
+
+// CallExecutor executes a registered workflow as a sub-workflow.
+// The sub-workflow shares the message stream and trace of the calling workflow.
+type CallExecutor struct {
+	operators map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error)
+}
+
+func NewCallExecutor() *CallExecutor {
+	e := &CallExecutor{}
+	e.operators = map[string]func(context.Context, *executor.ExecutorParams) (map[string]any, error){
+		"call": e.call,
+	}
+	return e
+}
+
+func (e *CallExecutor) Spec() executor.ExecutorSpec {
+	return executor.ExecutorSpec{
+		DefaultOperator: "call",
+		Operators: []executor.OperatorSpec{
+			{
+				Name:        "call",
+				Description: "Executes a workflow with the current args and returns its resulting state.",
+				Args: []executor.ArgSpec{
+					executor.RequiredArg[string]("workflow", "workflow to call, as 'name' or 'name@version'"),
+					executor.OptionalArg[string]("query", "query passed to the workflow, defaults to the current query"),
+				},
+				Outputs: []string{"context_docs", "query_transformed"},
+			},
+		},
+	}
+}
+
+func (e *CallExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
+	if p.Operator == "" {
+		p.Operator = "call"
+	}
+	slog.Info("executing", "name", callExecutorDescriptor, "op", p.Operator, "query", p.GetQuery(), "id", p.GetTaskID())
+
+	opFunc, exists := e.operators[p.Operator]
+	if !exists {
+		return e.buildResult(p.Operator, executor.ErrOperatorNotFound{
+			ExecutorName: callExecutorDescriptor, OperatorName: p.Operator}, nil)
+	}
+
+	vals, err := opFunc(ctx, p)
+	return e.buildResult(p.Operator, err, vals)
+}
+
+func (e *CallExecutor) call(ctx context.Context, p *executor.ExecutorParams) (map[string]any, error) {
+	ref, err := executor.GetTypedArg[string](p, "workflow")
+	if err != nil {
+		return nil, err
+	}
+
+	wf, err := registry.GetWorkflow(ref)
+	if err != nil {
+		return nil, err
+	}
+	if !wf.Allows(auth.FromContext(ctx)) {
+		return nil, fmt.Errorf("%w: workflow '%s'", auth.ErrAccessDenied, wf.Ref())
+	}
+
+	// cycles are rejected when loading workflows,
+	// this guards against workflows registered by other means
+	stack, _ := ctx.Value(callStackKey{}).([]string)
+	if slices.Contains(stack, wf.Ref()) {
+		cycle := append(slices.Clone(stack), wf.Ref())
+		return nil, fmt.Errorf("%w: %s", ErrRecursiveCall, strings.Join(cycle, " -> "))
+	}
+	ctx = context.WithValue(ctx, callStackKey{}, append(slices.Clip(stack), wf.Ref()))
+
+	before := p.Copy()
+	delete(before.Args, "workflow")
+	if query, err := executor.GetTypedArg[string](p, "query"); err == nil && query != "" {
+		before.SetQuery(query)
+		delete(before.Args, "query")
+	}
+
+	after, err := wf.Call(ctx, before.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("workflow '%s' failed: %w", wf.Ref(), err)
+	}
+
+	return executor.StateResult(callExecutorDescriptor, p.Operator, before, after).Values, nil
+}
+
+func (e *CallExecutor) buildResult(operator string, err error, values map[string]any) *executor.ExecutorResult {
+	return &executor.ExecutorResult{
+		Name:     callExecutorDescriptor,
+		Operator: operator,
+		Err:      err,
+		Values:   values,
+	}
+}
+
+type callStackKey struct{}
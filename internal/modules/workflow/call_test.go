@@ -0,0 +1,75 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/auth"
+	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+type nopExecutor struct{}
+
+func (nopExecutor) Execute(ctx context.Context, p *executor.ExecutorParams) *executor.ExecutorResult {
+	return &executor.ExecutorResult{}
+}
+
+func TestCallChecksAccess(t *testing.T) {
+	wf := executor.NewWorkflow("test.restricted", "", "", false,
+		[]*executor.WorkflowNode{executor.NewWorkflowNode(nopExecutor{}, "", "")},
+		executor.WithAccess(auth.Access{Users: []string{"alice"}}),
+	)
+	if err := registry.RegisterWorkflow(wf); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		p       *auth.Principal
+		allowed bool
+	}{
+		{"allowed", &auth.Principal{User: "alice"}, true},
+		{"denied", &auth.Principal{User: "bob"}, false},
+		{"authentication disabled", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := executor.NewExecutorParams("id", "query",
+				executor.WithTransport(transport.NewMemoryTransport()),
+				executor.WithArgs(map[string]any{"workflow": "test.restricted"}),
+			)
+			res := NewCallExecutor().Execute(auth.WithPrincipal(context.Background(), tt.p), p)
+
+			if tt.allowed && res.Err != nil {
+				t.Errorf("call failed: %v", res.Err)
+			}
+			if !tt.allowed && !errors.Is(res.Err, auth.ErrAccessDenied) {
+				t.Errorf("err = %v, want %v", res.Err, auth.ErrAccessDenied)
+			}
+		})
+	}
+}
@@ -27,6 +27,7 @@ const (
 	ChatRole_ROLE_UNSPECIFIED ChatRole = 0
 	ChatRole_USER             ChatRole = 1
 	ChatRole_ASSISTANT        ChatRole = 2
+	ChatRole_SYSTEM           ChatRole = 3
 )
 
 // Enum value maps for ChatRole.
@@ -35,11 +36,13 @@ var (
 		0: "ROLE_UNSPECIFIED",
 		1: "USER",
 		2: "ASSISTANT",
+		3: "SYSTEM",
 	}
 	ChatRole_value = map[string]int32{
 		"ROLE_UNSPECIFIED": 0,
 		"USER":             1,
 		"ASSISTANT":        2,
+		"SYSTEM":           3,
 	}
 )
 
@@ -77,6 +80,7 @@ const (
 	TraceStatus_RUNNING            TraceStatus = 1
 	TraceStatus_COMPLETED          TraceStatus = 2
 	TraceStatus_FAILED             TraceStatus = 3
+	TraceStatus_CANCELLED          TraceStatus = 4
 )
 
 // Enum value maps for TraceStatus.
@@ -86,12 +90,14 @@ var (
 		1: "RUNNING",
 		2: "COMPLETED",
 		3: "FAILED",
+		4: "CANCELLED",
 	}
 	TraceStatus_value = map[string]int32{
 		"STATUS_UNSPECIFIED": 0,
 		"RUNNING":            1,
 		"COMPLETED":          2,
 		"FAILED":             3,
+		"CANCELLED":          4,
 	}
 )
 
@@ -499,12 +505,14 @@ func (x *SearchResponse) GetDocument() *Document {
 }
 
 type ExecuteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	WorkflowId    string                 `protobuf:"bytes,1,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
-	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
-	User          string                 `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
-	History       []*ChatMessage         `protobuf:"bytes,4,rep,name=history,proto3" json:"history,omitempty"`
-	Args          map[string]string      `protobuf:"bytes,101,rep,name=args,proto3" json:"args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name of the workflow to execute, optionally pinned
+	// to a version in the form 'name@version'
+	WorkflowId    string            `protobuf:"bytes,1,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	Query         string            `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	User          string            `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	History       []*ChatMessage    `protobuf:"bytes,4,rep,name=history,proto3" json:"history,omitempty"`
+	Args          map[string]string `protobuf:"bytes,101,rep,name=args,proto3" json:"args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -725,13 +733,23 @@ func (x *TraceRequest) GetTraceId() string {
 }
 
 type TraceResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TraceId       string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
-	Status        TraceStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=awe.TraceStatus" json:"status,omitempty"`
-	StartedAt     int64                  `protobuf:"varint,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
-	CompletedAt   int64                  `protobuf:"varint,4,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
-	Query         string                 `protobuf:"bytes,5,opt,name=query,proto3" json:"query,omitempty"`
-	User          string                 `protobuf:"bytes,6,opt,name=user,proto3" json:"user,omitempty"`
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TraceId         string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	Status          TraceStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=awe.TraceStatus" json:"status,omitempty"`
+	StartedAt       int64                  `protobuf:"varint,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt     int64                  `protobuf:"varint,4,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Query           string                 `protobuf:"bytes,5,opt,name=query,proto3" json:"query,omitempty"`
+	User            string                 `protobuf:"bytes,6,opt,name=user,proto3" json:"user,omitempty"`
+	WorkflowId      string                 `protobuf:"bytes,7,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	WorkflowVersion string                 `protobuf:"bytes,8,opt,name=workflow_version,json=workflowVersion,proto3" json:"workflow_version,omitempty"`
+	Usage           []*ProviderUsage       `protobuf:"bytes,9,rep,name=usage,proto3" json:"usage,omitempty"`
+	UsageTotals     *UsageTotals           `protobuf:"bytes,10,opt,name=usage_totals,json=usageTotals,proto3" json:"usage_totals,omitempty"`
+	// workflow nodes executed for the request, as a tree of spans
+	Spans []*Span `protobuf:"bytes,11,rep,name=spans,proto3" json:"spans,omitempty"`
+	// spans which were not recorded, as the limit of spans was reached
+	SpansDropped int32 `protobuf:"varint,12,opt,name=spans_dropped,json=spansDropped,proto3" json:"spans_dropped,omitempty"`
+	// when the trace is removed, in unix nanoseconds
+	ExpiresAt     int64 `protobuf:"varint,13,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -808,27 +826,86 @@ func (x *TraceResponse) GetUser() string {
 	return ""
 }
 
-type AttachRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TraceId       string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+func (x *TraceResponse) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *TraceResponse) GetWorkflowVersion() string {
+	if x != nil {
+		return x.WorkflowVersion
+	}
+	return ""
+}
+
+func (x *TraceResponse) GetUsage() []*ProviderUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *TraceResponse) GetUsageTotals() *UsageTotals {
+	if x != nil {
+		return x.UsageTotals
+	}
+	return nil
+}
+
+func (x *TraceResponse) GetSpans() []*Span {
+	if x != nil {
+		return x.Spans
+	}
+	return nil
+}
+
+func (x *TraceResponse) GetSpansDropped() int32 {
+	if x != nil {
+		return x.SpansDropped
+	}
+	return 0
+}
+
+func (x *TraceResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type ListTracesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// defaults to the authenticated user, admins list the traces of all users if not set
+	User       string      `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	WorkflowId string      `protobuf:"bytes,2,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	Status     TraceStatus `protobuf:"varint,3,opt,name=status,proto3,enum=awe.TraceStatus" json:"status,omitempty"`
+	// start of the traces, in unix nanoseconds, within [started_after, started_before)
+	StartedAfter  int64 `protobuf:"varint,4,opt,name=started_after,json=startedAfter,proto3" json:"started_after,omitempty"`
+	StartedBefore int64 `protobuf:"varint,5,opt,name=started_before,json=startedBefore,proto3" json:"started_before,omitempty"`
+	// defaults to 50, at most 1000
+	PageSize int32 `protobuf:"varint,6,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page
+	PageToken     string `protobuf:"bytes,7,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AttachRequest) Reset() {
-	*x = AttachRequest{}
+func (x *ListTracesRequest) Reset() {
+	*x = ListTracesRequest{}
 	mi := &file_awe_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AttachRequest) String() string {
+func (x *ListTracesRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AttachRequest) ProtoMessage() {}
+func (*ListTracesRequest) ProtoMessage() {}
 
-func (x *AttachRequest) ProtoReflect() protoreflect.Message {
+func (x *ListTracesRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_awe_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -840,169 +917,1482 @@ func (x *AttachRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AttachRequest.ProtoReflect.Descriptor instead.
-func (*AttachRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListTracesRequest.ProtoReflect.Descriptor instead.
+func (*ListTracesRequest) Descriptor() ([]byte, []int) {
 	return file_awe_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *AttachRequest) GetTraceId() string {
+func (x *ListTracesRequest) GetUser() string {
 	if x != nil {
-		return x.TraceId
+		return x.User
 	}
 	return ""
 }
 
-var File_awe_proto protoreflect.FileDescriptor
+func (x *ListTracesRequest) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
 
-const file_awe_proto_rawDesc = "" +
-	"\n" +
-	"\tawe.proto\x12\x03awe\"J\n" +
-	"\vChatMessage\x12!\n" +
-	"\x04role\x18\x01 \x01(\x0e2\r.awe.ChatRoleR\x04role\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\tR\acontent\"\xcc\x01\n" +
-	"\vChatRequest\x12\x14\n" +
-	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
-	"\x04user\x18\x02 \x01(\tR\x04user\x12*\n" +
-	"\ahistory\x18\x03 \x03(\v2\x10.awe.ChatMessageR\ahistory\x12.\n" +
-	"\x04args\x18e \x03(\v2\x1a.awe.ChatRequest.ArgsEntryR\x04args\x1a7\n" +
-	"\tArgsEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"r\n" +
-	"\fChatResponse\x12\x15\n" +
-	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x19\n" +
-	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x16\n" +
-	"\x06status\x18\x03 \x01(\tR\x06status\x12\x18\n" +
-	"\acontent\x18\x04 \x01(\tR\acontent\"\xa4\x01\n" +
-	"\rSearchRequest\x12\x14\n" +
-	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
-	"\x04user\x18\x02 \x01(\tR\x04user\x120\n" +
-	"\x04args\x18e \x03(\v2\x1c.awe.SearchRequest.ArgsEntryR\x04args\x1a7\n" +
-	"\tArgsEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"R\n" +
-	"\bDocument\x12\x14\n" +
-	"\x05title\x18\x01 \x01(\tR\x05title\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\tR\acontent\x12\x16\n" +
-	"\x06source\x18\x03 \x01(\tR\x06source\"\x85\x01\n" +
-	"\x0eSearchResponse\x12\x15\n" +
-	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x19\n" +
-	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x16\n" +
-	"\x06status\x18\x03 \x01(\tR\x06status\x12)\n" +
-	"\bdocument\x18\x04 \x01(\v2\r.awe.DocumentR\bdocument\"\xf3\x01\n" +
-	"\x0eExecuteRequest\x12\x1f\n" +
-	"\vworkflow_id\x18\x01 \x01(\tR\n" +
-	"workflowId\x12\x14\n" +
-	"\x05query\x18\x02 \x01(\tR\x05query\x12\x12\n" +
-	"\x04user\x18\x03 \x01(\tR\x04user\x12*\n" +
-	"\ahistory\x18\x04 \x03(\v2\x10.awe.ChatMessageR\ahistory\x121\n" +
-	"\x04args\x18e \x03(\v2\x1d.awe.ExecuteRequest.ArgsEntryR\x04args\x1a7\n" +
-	"\tArgsEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xaf\x01\n" +
-	"\x0fExecuteResponse\x12\x15\n" +
-	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x19\n" +
-	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x16\n" +
-	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1a\n" +
-	"\acontent\x18\x1e \x01(\tH\x00R\acontent\x12+\n" +
-	"\bdocument\x18\x1f \x01(\v2\r.awe.DocumentH\x00R\bdocumentB\t\n" +
-	"\apayload\")\n" +
-	"\fTraceRequest\x12\x19\n" +
-	"\btrace_id\x18\x01 \x01(\tR\atraceId\"\xc0\x01\n" +
-	"\rTraceResponse\x12\x19\n" +
-	"\btrace_id\x18\x01 \x01(\tR\atraceId\x12(\n" +
-	"\x06status\x18\x02 \x01(\x0e2\x10.awe.TraceStatusR\x06status\x12\x1d\n" +
-	"\n" +
-	"started_at\x18\x03 \x01(\x03R\tstartedAt\x12!\n" +
-	"\fcompleted_at\x18\x04 \x01(\x03R\vcompletedAt\x12\x14\n" +
-	"\x05query\x18\x05 \x01(\tR\x05query\x12\x12\n" +
-	"\x04user\x18\x06 \x01(\tR\x04user\"*\n" +
-	"\rAttachRequest\x12\x19\n" +
-	"\btrace_id\x18\x01 \x01(\tR\atraceId*9\n" +
-	"\bChatRole\x12\x14\n" +
-	"\x10ROLE_UNSPECIFIED\x10\x00\x12\b\n" +
-	"\x04USER\x10\x01\x12\r\n" +
-	"\tASSISTANT\x10\x02*M\n" +
-	"\vTraceStatus\x12\x16\n" +
-	"\x12STATUS_UNSPECIFIED\x10\x00\x12\v\n" +
-	"\aRUNNING\x10\x01\x12\r\n" +
-	"\tCOMPLETED\x10\x02\x12\n" +
-	"\n" +
-	"\x06FAILED\x10\x032\x98\x02\n" +
-	"\n" +
-	"AWEService\x12/\n" +
-	"\x04Chat\x12\x10.awe.ChatRequest\x1a\x11.awe.ChatResponse\"\x000\x01\x125\n" +
-	"\x06Search\x12\x12.awe.SearchRequest\x1a\x13.awe.SearchResponse\"\x000\x01\x128\n" +
-	"\aExecute\x12\x13.awe.ExecuteRequest\x1a\x14.awe.ExecuteResponse\"\x000\x01\x120\n" +
-	"\x05Trace\x12\x11.awe.TraceRequest\x1a\x12.awe.TraceResponse\"\x00\x126\n" +
-	"\x06Attach\x12\x12.awe.AttachRequest\x1a\x14.awe.ExecuteResponse\"\x000\x01B(Z&github.com/alan-mat/awe/internal/protob\x06proto3"
+func (x *ListTracesRequest) GetStatus() TraceStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TraceStatus_STATUS_UNSPECIFIED
+}
 
-var (
-	file_awe_proto_rawDescOnce sync.Once
-	file_awe_proto_rawDescData []byte
-)
+func (x *ListTracesRequest) GetStartedAfter() int64 {
+	if x != nil {
+		return x.StartedAfter
+	}
+	return 0
+}
 
-func file_awe_proto_rawDescGZIP() []byte {
-	file_awe_proto_rawDescOnce.Do(func() {
-		file_awe_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_awe_proto_rawDesc), len(file_awe_proto_rawDesc)))
-	})
-	return file_awe_proto_rawDescData
+func (x *ListTracesRequest) GetStartedBefore() int64 {
+	if x != nil {
+		return x.StartedBefore
+	}
+	return 0
 }
 
-var file_awe_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_awe_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
-var file_awe_proto_goTypes = []any{
-	(ChatRole)(0),           // 0: awe.ChatRole
-	(TraceStatus)(0),        // 1: awe.TraceStatus
-	(*ChatMessage)(nil),     // 2: awe.ChatMessage
-	(*ChatRequest)(nil),     // 3: awe.ChatRequest
-	(*ChatResponse)(nil),    // 4: awe.ChatResponse
-	(*SearchRequest)(nil),   // 5: awe.SearchRequest
-	(*Document)(nil),        // 6: awe.Document
-	(*SearchResponse)(nil),  // 7: awe.SearchResponse
-	(*ExecuteRequest)(nil),  // 8: awe.ExecuteRequest
-	(*ExecuteResponse)(nil), // 9: awe.ExecuteResponse
-	(*TraceRequest)(nil),    // 10: awe.TraceRequest
-	(*TraceResponse)(nil),   // 11: awe.TraceResponse
-	(*AttachRequest)(nil),   // 12: awe.AttachRequest
-	nil,                     // 13: awe.ChatRequest.ArgsEntry
-	nil,                     // 14: awe.SearchRequest.ArgsEntry
-	nil,                     // 15: awe.ExecuteRequest.ArgsEntry
+func (x *ListTracesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
 }
-var file_awe_proto_depIdxs = []int32{
-	0,  // 0: awe.ChatMessage.role:type_name -> awe.ChatRole
-	2,  // 1: awe.ChatRequest.history:type_name -> awe.ChatMessage
-	13, // 2: awe.ChatRequest.args:type_name -> awe.ChatRequest.ArgsEntry
-	14, // 3: awe.SearchRequest.args:type_name -> awe.SearchRequest.ArgsEntry
-	6,  // 4: awe.SearchResponse.document:type_name -> awe.Document
-	2,  // 5: awe.ExecuteRequest.history:type_name -> awe.ChatMessage
-	15, // 6: awe.ExecuteRequest.args:type_name -> awe.ExecuteRequest.ArgsEntry
-	6,  // 7: awe.ExecuteResponse.document:type_name -> awe.Document
-	1,  // 8: awe.TraceResponse.status:type_name -> awe.TraceStatus
-	3,  // 9: awe.AWEService.Chat:input_type -> awe.ChatRequest
-	5,  // 10: awe.AWEService.Search:input_type -> awe.SearchRequest
-	8,  // 11: awe.AWEService.Execute:input_type -> awe.ExecuteRequest
-	10, // 12: awe.AWEService.Trace:input_type -> awe.TraceRequest
-	12, // 13: awe.AWEService.Attach:input_type -> awe.AttachRequest
-	4,  // 14: awe.AWEService.Chat:output_type -> awe.ChatResponse
-	7,  // 15: awe.AWEService.Search:output_type -> awe.SearchResponse
-	9,  // 16: awe.AWEService.Execute:output_type -> awe.ExecuteResponse
-	11, // 17: awe.AWEService.Trace:output_type -> awe.TraceResponse
-	9,  // 18: awe.AWEService.Attach:output_type -> awe.ExecuteResponse
-	14, // [14:19] is the sub-list for method output_type
-	9,  // [9:14] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+
+func (x *ListTracesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
 }
 
-func init() { file_awe_proto_init() }
-func file_awe_proto_init() {
-	if File_awe_proto != nil {
-		return
+type ListTracesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// most recently started first, without their spans
+	Traces []*TraceResponse `protobuf:"bytes,1,rep,name=traces,proto3" json:"traces,omitempty"`
+	// empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTracesResponse) Reset() {
+	*x = ListTracesResponse{}
+	mi := &file_awe_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTracesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTracesResponse) ProtoMessage() {}
+
+func (x *ListTracesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	file_awe_proto_msgTypes[7].OneofWrappers = []any{
-		(*ExecuteResponse_Content)(nil),
-		(*ExecuteResponse_Document)(nil),
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTracesResponse.ProtoReflect.Descriptor instead.
+func (*ListTracesResponse) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListTracesResponse) GetTraces() []*TraceResponse {
+	if x != nil {
+		return x.Traces
+	}
+	return nil
+}
+
+func (x *ListTracesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type Span struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	SpanId int32                  `protobuf:"varint,1,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`
+	// id of the parent span, 0 for top level spans
+	ParentId int32 `protobuf:"varint,2,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	// id of the node, or the group of nodes, e.g. 'iteration 1' or 'branch web'
+	Name        string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Module      string `protobuf:"bytes,4,opt,name=module,proto3" json:"module,omitempty"`
+	Operator    string `protobuf:"bytes,5,opt,name=operator,proto3" json:"operator,omitempty"`
+	StartedAt   int64  `protobuf:"varint,6,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt int64  `protobuf:"varint,7,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Query       string `protobuf:"bytes,8,opt,name=query,proto3" json:"query,omitempty"`
+	// snapshot of the args and summary of the outputs of the node
+	Args    map[string]string `protobuf:"bytes,9,rep,name=args,proto3" json:"args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Outputs map[string]string `protobuf:"bytes,10,rep,name=outputs,proto3" json:"outputs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// key of the route selected by conditional nodes
+	Route         string `protobuf:"bytes,11,opt,name=route,proto3" json:"route,omitempty"`
+	Attempts      int32  `protobuf:"varint,12,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	Error         string `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Span) Reset() {
+	*x = Span{}
+	mi := &file_awe_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Span) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Span) ProtoMessage() {}
+
+func (x *Span) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Span.ProtoReflect.Descriptor instead.
+func (*Span) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Span) GetSpanId() int32 {
+	if x != nil {
+		return x.SpanId
+	}
+	return 0
+}
+
+func (x *Span) GetParentId() int32 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+func (x *Span) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Span) GetModule() string {
+	if x != nil {
+		return x.Module
+	}
+	return ""
+}
+
+func (x *Span) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *Span) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *Span) GetCompletedAt() int64 {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return 0
+}
+
+func (x *Span) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *Span) GetArgs() map[string]string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *Span) GetOutputs() map[string]string {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *Span) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *Span) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *Span) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ProviderUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Capability    string                 `protobuf:"bytes,3,opt,name=capability,proto3" json:"capability,omitempty"`
+	Calls         int64                  `protobuf:"varint,4,opt,name=calls,proto3" json:"calls,omitempty"`
+	InputTokens   int64                  `protobuf:"varint,5,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens  int64                  `protobuf:"varint,6,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	Units         int64                  `protobuf:"varint,7,opt,name=units,proto3" json:"units,omitempty"`
+	Cost          float64                `protobuf:"fixed64,8,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProviderUsage) Reset() {
+	*x = ProviderUsage{}
+	mi := &file_awe_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProviderUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProviderUsage) ProtoMessage() {}
+
+func (x *ProviderUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProviderUsage.ProtoReflect.Descriptor instead.
+func (*ProviderUsage) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ProviderUsage) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ProviderUsage) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ProviderUsage) GetCapability() string {
+	if x != nil {
+		return x.Capability
+	}
+	return ""
+}
+
+func (x *ProviderUsage) GetCalls() int64 {
+	if x != nil {
+		return x.Calls
+	}
+	return 0
+}
+
+func (x *ProviderUsage) GetInputTokens() int64 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *ProviderUsage) GetOutputTokens() int64 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *ProviderUsage) GetUnits() int64 {
+	if x != nil {
+		return x.Units
+	}
+	return 0
+}
+
+func (x *ProviderUsage) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+type UsageTotals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      int64                  `protobuf:"varint,1,opt,name=requests,proto3" json:"requests,omitempty"`
+	Calls         int64                  `protobuf:"varint,2,opt,name=calls,proto3" json:"calls,omitempty"`
+	InputTokens   int64                  `protobuf:"varint,3,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens  int64                  `protobuf:"varint,4,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	Units         int64                  `protobuf:"varint,5,opt,name=units,proto3" json:"units,omitempty"`
+	Cost          float64                `protobuf:"fixed64,6,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageTotals) Reset() {
+	*x = UsageTotals{}
+	mi := &file_awe_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageTotals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageTotals) ProtoMessage() {}
+
+func (x *UsageTotals) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageTotals.ProtoReflect.Descriptor instead.
+func (*UsageTotals) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UsageTotals) GetRequests() int64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *UsageTotals) GetCalls() int64 {
+	if x != nil {
+		return x.Calls
+	}
+	return 0
+}
+
+func (x *UsageTotals) GetInputTokens() int64 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *UsageTotals) GetOutputTokens() int64 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *UsageTotals) GetUnits() int64 {
+	if x != nil {
+		return x.Units
+	}
+	return 0
+}
+
+func (x *UsageTotals) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+type AttachRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraceId       string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AttachRequest) Reset() {
+	*x = AttachRequest{}
+	mi := &file_awe_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttachRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttachRequest) ProtoMessage() {}
+
+func (x *AttachRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttachRequest.ProtoReflect.Descriptor instead.
+func (*AttachRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *AttachRequest) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+type CancelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraceId       string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_awe_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CancelRequest) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+type CancelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelResponse) Reset() {
+	*x = CancelResponse{}
+	mi := &file_awe_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelResponse) ProtoMessage() {}
+
+func (x *CancelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelResponse.ProtoReflect.Descriptor instead.
+func (*CancelResponse) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{17}
+}
+
+type UsageRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	User       string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	WorkflowId string                 `protobuf:"bytes,2,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	// month 'YYYY-MM' or day 'YYYY-MM-DD', defaults to the current month
+	Period        string `protobuf:"bytes,3,opt,name=period,proto3" json:"period,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageRequest) Reset() {
+	*x = UsageRequest{}
+	mi := &file_awe_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageRequest) ProtoMessage() {}
+
+func (x *UsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageRequest.ProtoReflect.Descriptor instead.
+func (*UsageRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UsageRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *UsageRequest) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *UsageRequest) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+type UsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	WorkflowId    string                 `protobuf:"bytes,2,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	Period        string                 `protobuf:"bytes,3,opt,name=period,proto3" json:"period,omitempty"`
+	Totals        *UsageTotals           `protobuf:"bytes,4,opt,name=totals,proto3" json:"totals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageResponse) Reset() {
+	*x = UsageResponse{}
+	mi := &file_awe_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageResponse) ProtoMessage() {}
+
+func (x *UsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageResponse.ProtoReflect.Descriptor instead.
+func (*UsageResponse) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UsageResponse) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *UsageResponse) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *UsageResponse) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *UsageResponse) GetTotals() *UsageTotals {
+	if x != nil {
+		return x.Totals
+	}
+	return nil
+}
+
+type CreateSessionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	User  string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	// workflow used for every turn of the session, defaults to the chat workflow
+	WorkflowId string `protobuf:"bytes,2,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	// arguments passed to every turn, merged with the arguments of the turn
+	Args          map[string]string `protobuf:"bytes,101,rep,name=args,proto3" json:"args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionRequest) Reset() {
+	*x = CreateSessionRequest{}
+	mi := &file_awe_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionRequest) ProtoMessage() {}
+
+func (x *CreateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CreateSessionRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *CreateSessionRequest) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *CreateSessionRequest) GetArgs() map[string]string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type Session struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	SessionId  string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	User       string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	WorkflowId string                 `protobuf:"bytes,3,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	CreatedAt  int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt  int64                  `protobuf:"varint,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	History    []*ChatMessage         `protobuf:"bytes,6,rep,name=history,proto3" json:"history,omitempty"`
+	// summary of the messages dropped from the history
+	Summary       string `protobuf:"bytes,7,opt,name=summary,proto3" json:"summary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_awe_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Session) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Session) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Session) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *Session) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Session) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *Session) GetHistory() []*ChatMessage {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+func (x *Session) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+type GetSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionRequest) Reset() {
+	*x = GetSessionRequest{}
+	mi := &file_awe_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionRequest) ProtoMessage() {}
+
+func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type DeleteSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSessionRequest) Reset() {
+	*x = DeleteSessionRequest{}
+	mi := &file_awe_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSessionRequest) ProtoMessage() {}
+
+func (x *DeleteSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSessionRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSessionRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DeleteSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type DeleteSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSessionResponse) Reset() {
+	*x = DeleteSessionResponse{}
+	mi := &file_awe_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSessionResponse) ProtoMessage() {}
+
+func (x *DeleteSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSessionResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSessionResponse) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{24}
+}
+
+type SessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Args          map[string]string      `protobuf:"bytes,101,rep,name=args,proto3" json:"args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionRequest) Reset() {
+	*x = SessionRequest{}
+	mi := &file_awe_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionRequest) ProtoMessage() {}
+
+func (x *SessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionRequest.ProtoReflect.Descriptor instead.
+func (*SessionRequest) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *SessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SessionRequest) GetArgs() map[string]string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type SessionResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	MsgId     int32                  `protobuf:"varint,1,opt,name=msg_id,json=msgId,proto3" json:"msg_id,omitempty"`
+	SessionId string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	TraceId   string                 `protobuf:"bytes,3,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	Status    string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*SessionResponse_Content
+	//	*SessionResponse_Document
+	Payload       isSessionResponse_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionResponse) Reset() {
+	*x = SessionResponse{}
+	mi := &file_awe_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionResponse) ProtoMessage() {}
+
+func (x *SessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_awe_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionResponse.ProtoReflect.Descriptor instead.
+func (*SessionResponse) Descriptor() ([]byte, []int) {
+	return file_awe_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SessionResponse) GetMsgId() int32 {
+	if x != nil {
+		return x.MsgId
+	}
+	return 0
+}
+
+func (x *SessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionResponse) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *SessionResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SessionResponse) GetPayload() isSessionResponse_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SessionResponse) GetContent() string {
+	if x != nil {
+		if x, ok := x.Payload.(*SessionResponse_Content); ok {
+			return x.Content
+		}
+	}
+	return ""
+}
+
+func (x *SessionResponse) GetDocument() *Document {
+	if x != nil {
+		if x, ok := x.Payload.(*SessionResponse_Document); ok {
+			return x.Document
+		}
+	}
+	return nil
+}
+
+type isSessionResponse_Payload interface {
+	isSessionResponse_Payload()
+}
+
+type SessionResponse_Content struct {
+	Content string `protobuf:"bytes,30,opt,name=content,proto3,oneof"`
+}
+
+type SessionResponse_Document struct {
+	Document *Document `protobuf:"bytes,31,opt,name=document,proto3,oneof"`
+}
+
+func (*SessionResponse_Content) isSessionResponse_Payload() {}
+
+func (*SessionResponse_Document) isSessionResponse_Payload() {}
+
+var File_awe_proto protoreflect.FileDescriptor
+
+const file_awe_proto_rawDesc = "" +
+	"\n" +
+	"\tawe.proto\x12\x03awe\"J\n" +
+	"\vChatMessage\x12!\n" +
+	"\x04role\x18\x01 \x01(\x0e2\r.awe.ChatRoleR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\xcc\x01\n" +
+	"\vChatRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12*\n" +
+	"\ahistory\x18\x03 \x03(\v2\x10.awe.ChatMessageR\ahistory\x12.\n" +
+	"\x04args\x18e \x03(\v2\x1a.awe.ChatRequest.ArgsEntryR\x04args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"r\n" +
+	"\fChatResponse\x12\x15\n" +
+	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x19\n" +
+	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\"\xa4\x01\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x120\n" +
+	"\x04args\x18e \x03(\v2\x1c.awe.SearchRequest.ArgsEntryR\x04args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"R\n" +
+	"\bDocument\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\"\x85\x01\n" +
+	"\x0eSearchResponse\x12\x15\n" +
+	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x19\n" +
+	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12)\n" +
+	"\bdocument\x18\x04 \x01(\v2\r.awe.DocumentR\bdocument\"\xf3\x01\n" +
+	"\x0eExecuteRequest\x12\x1f\n" +
+	"\vworkflow_id\x18\x01 \x01(\tR\n" +
+	"workflowId\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x12\n" +
+	"\x04user\x18\x03 \x01(\tR\x04user\x12*\n" +
+	"\ahistory\x18\x04 \x03(\v2\x10.awe.ChatMessageR\ahistory\x121\n" +
+	"\x04args\x18e \x03(\v2\x1d.awe.ExecuteRequest.ArgsEntryR\x04args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xaf\x01\n" +
+	"\x0fExecuteResponse\x12\x15\n" +
+	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x19\n" +
+	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1a\n" +
+	"\acontent\x18\x1e \x01(\tH\x00R\acontent\x12+\n" +
+	"\bdocument\x18\x1f \x01(\v2\r.awe.DocumentH\x00R\bdocumentB\t\n" +
+	"\apayload\")\n" +
+	"\fTraceRequest\x12\x19\n" +
+	"\btrace_id\x18\x01 \x01(\tR\atraceId\"\xd0\x03\n" +
+	"\rTraceResponse\x12\x19\n" +
+	"\btrace_id\x18\x01 \x01(\tR\atraceId\x12(\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x10.awe.TraceStatusR\x06status\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\x03R\tstartedAt\x12!\n" +
+	"\fcompleted_at\x18\x04 \x01(\x03R\vcompletedAt\x12\x14\n" +
+	"\x05query\x18\x05 \x01(\tR\x05query\x12\x12\n" +
+	"\x04user\x18\x06 \x01(\tR\x04user\x12\x1f\n" +
+	"\vworkflow_id\x18\a \x01(\tR\n" +
+	"workflowId\x12)\n" +
+	"\x10workflow_version\x18\b \x01(\tR\x0fworkflowVersion\x12(\n" +
+	"\x05usage\x18\t \x03(\v2\x12.awe.ProviderUsageR\x05usage\x123\n" +
+	"\fusage_totals\x18\n" +
+	" \x01(\v2\x10.awe.UsageTotalsR\vusageTotals\x12\x1f\n" +
+	"\x05spans\x18\v \x03(\v2\t.awe.SpanR\x05spans\x12#\n" +
+	"\rspans_dropped\x18\f \x01(\x05R\fspansDropped\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\r \x01(\x03R\texpiresAt\"\xfa\x01\n" +
+	"\x11ListTracesRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x1f\n" +
+	"\vworkflow_id\x18\x02 \x01(\tR\n" +
+	"workflowId\x12(\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x10.awe.TraceStatusR\x06status\x12#\n" +
+	"\rstarted_after\x18\x04 \x01(\x03R\fstartedAfter\x12%\n" +
+	"\x0estarted_before\x18\x05 \x01(\x03R\rstartedBefore\x12\x1b\n" +
+	"\tpage_size\x18\x06 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\a \x01(\tR\tpageToken\"h\n" +
+	"\x12ListTracesResponse\x12*\n" +
+	"\x06traces\x18\x01 \x03(\v2\x12.awe.TraceResponseR\x06traces\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xf4\x03\n" +
+	"\x04Span\x12\x17\n" +
+	"\aspan_id\x18\x01 \x01(\x05R\x06spanId\x12\x1b\n" +
+	"\tparent_id\x18\x02 \x01(\x05R\bparentId\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x16\n" +
+	"\x06module\x18\x04 \x01(\tR\x06module\x12\x1a\n" +
+	"\boperator\x18\x05 \x01(\tR\boperator\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x06 \x01(\x03R\tstartedAt\x12!\n" +
+	"\fcompleted_at\x18\a \x01(\x03R\vcompletedAt\x12\x14\n" +
+	"\x05query\x18\b \x01(\tR\x05query\x12'\n" +
+	"\x04args\x18\t \x03(\v2\x13.awe.Span.ArgsEntryR\x04args\x120\n" +
+	"\aoutputs\x18\n" +
+	" \x03(\v2\x16.awe.Span.OutputsEntryR\aoutputs\x12\x14\n" +
+	"\x05route\x18\v \x01(\tR\x05route\x12\x1a\n" +
+	"\battempts\x18\f \x01(\x05R\battempts\x12\x14\n" +
+	"\x05error\x18\r \x01(\tR\x05error\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
+	"\fOutputsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe9\x01\n" +
+	"\rProviderUsage\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x1e\n" +
+	"\n" +
+	"capability\x18\x03 \x01(\tR\n" +
+	"capability\x12\x14\n" +
+	"\x05calls\x18\x04 \x01(\x03R\x05calls\x12!\n" +
+	"\finput_tokens\x18\x05 \x01(\x03R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x06 \x01(\x03R\foutputTokens\x12\x14\n" +
+	"\x05units\x18\a \x01(\x03R\x05units\x12\x12\n" +
+	"\x04cost\x18\b \x01(\x01R\x04cost\"\xb1\x01\n" +
+	"\vUsageTotals\x12\x1a\n" +
+	"\brequests\x18\x01 \x01(\x03R\brequests\x12\x14\n" +
+	"\x05calls\x18\x02 \x01(\x03R\x05calls\x12!\n" +
+	"\finput_tokens\x18\x03 \x01(\x03R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x04 \x01(\x03R\foutputTokens\x12\x14\n" +
+	"\x05units\x18\x05 \x01(\x03R\x05units\x12\x12\n" +
+	"\x04cost\x18\x06 \x01(\x01R\x04cost\"*\n" +
+	"\rAttachRequest\x12\x19\n" +
+	"\btrace_id\x18\x01 \x01(\tR\atraceId\"*\n" +
+	"\rCancelRequest\x12\x19\n" +
+	"\btrace_id\x18\x01 \x01(\tR\atraceId\"\x10\n" +
+	"\x0eCancelResponse\"[\n" +
+	"\fUsageRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x1f\n" +
+	"\vworkflow_id\x18\x02 \x01(\tR\n" +
+	"workflowId\x12\x16\n" +
+	"\x06period\x18\x03 \x01(\tR\x06period\"\x86\x01\n" +
+	"\rUsageResponse\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x1f\n" +
+	"\vworkflow_id\x18\x02 \x01(\tR\n" +
+	"workflowId\x12\x16\n" +
+	"\x06period\x18\x03 \x01(\tR\x06period\x12(\n" +
+	"\x06totals\x18\x04 \x01(\v2\x10.awe.UsageTotalsR\x06totals\"\xbd\x01\n" +
+	"\x14CreateSessionRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x1f\n" +
+	"\vworkflow_id\x18\x02 \x01(\tR\n" +
+	"workflowId\x127\n" +
+	"\x04args\x18e \x03(\v2#.awe.CreateSessionRequest.ArgsEntryR\x04args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe1\x01\n" +
+	"\aSession\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12\x1f\n" +
+	"\vworkflow_id\x18\x03 \x01(\tR\n" +
+	"workflowId\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\x03R\tupdatedAt\x12*\n" +
+	"\ahistory\x18\x06 \x03(\v2\x10.awe.ChatMessageR\ahistory\x12\x18\n" +
+	"\asummary\x18\a \x01(\tR\asummary\"2\n" +
+	"\x11GetSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"5\n" +
+	"\x14DeleteSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x17\n" +
+	"\x15DeleteSessionResponse\"\xb1\x01\n" +
+	"\x0eSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x121\n" +
+	"\x04args\x18e \x03(\v2\x1d.awe.SessionRequest.ArgsEntryR\x04args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xce\x01\n" +
+	"\x0fSessionResponse\x12\x15\n" +
+	"\x06msg_id\x18\x01 \x01(\x05R\x05msgId\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x19\n" +
+	"\btrace_id\x18\x03 \x01(\tR\atraceId\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x1a\n" +
+	"\acontent\x18\x1e \x01(\tH\x00R\acontent\x12+\n" +
+	"\bdocument\x18\x1f \x01(\v2\r.awe.DocumentH\x00R\bdocumentB\t\n" +
+	"\apayload*E\n" +
+	"\bChatRole\x12\x14\n" +
+	"\x10ROLE_UNSPECIFIED\x10\x00\x12\b\n" +
+	"\x04USER\x10\x01\x12\r\n" +
+	"\tASSISTANT\x10\x02\x12\n" +
+	"\n" +
+	"\x06SYSTEM\x10\x03*\\\n" +
+	"\vTraceStatus\x12\x16\n" +
+	"\x12STATUS_UNSPECIFIED\x10\x00\x12\v\n" +
+	"\aRUNNING\x10\x01\x12\r\n" +
+	"\tCOMPLETED\x10\x02\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x03\x12\r\n" +
+	"\tCANCELLED\x10\x042\xbc\x05\n" +
+	"\n" +
+	"AWEService\x12/\n" +
+	"\x04Chat\x12\x10.awe.ChatRequest\x1a\x11.awe.ChatResponse\"\x000\x01\x125\n" +
+	"\x06Search\x12\x12.awe.SearchRequest\x1a\x13.awe.SearchResponse\"\x000\x01\x128\n" +
+	"\aExecute\x12\x13.awe.ExecuteRequest\x1a\x14.awe.ExecuteResponse\"\x000\x01\x120\n" +
+	"\x05Trace\x12\x11.awe.TraceRequest\x1a\x12.awe.TraceResponse\"\x00\x12?\n" +
+	"\n" +
+	"ListTraces\x12\x16.awe.ListTracesRequest\x1a\x17.awe.ListTracesResponse\"\x00\x126\n" +
+	"\x06Attach\x12\x12.awe.AttachRequest\x1a\x14.awe.ExecuteResponse\"\x000\x01\x123\n" +
+	"\x06Cancel\x12\x12.awe.CancelRequest\x1a\x13.awe.CancelResponse\"\x00\x120\n" +
+	"\x05Usage\x12\x11.awe.UsageRequest\x1a\x12.awe.UsageResponse\"\x00\x12:\n" +
+	"\rCreateSession\x12\x19.awe.CreateSessionRequest\x1a\f.awe.Session\"\x00\x124\n" +
+	"\n" +
+	"GetSession\x12\x16.awe.GetSessionRequest\x1a\f.awe.Session\"\x00\x12H\n" +
+	"\rDeleteSession\x12\x19.awe.DeleteSessionRequest\x1a\x1a.awe.DeleteSessionResponse\"\x00\x12>\n" +
+	"\vChatSession\x12\x13.awe.SessionRequest\x1a\x14.awe.SessionResponse\"\x00(\x010\x01B(Z&github.com/alan-mat/awe/internal/protob\x06proto3"
+
+var (
+	file_awe_proto_rawDescOnce sync.Once
+	file_awe_proto_rawDescData []byte
+)
+
+func file_awe_proto_rawDescGZIP() []byte {
+	file_awe_proto_rawDescOnce.Do(func() {
+		file_awe_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_awe_proto_rawDesc), len(file_awe_proto_rawDesc)))
+	})
+	return file_awe_proto_rawDescData
+}
+
+var file_awe_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_awe_proto_msgTypes = make([]protoimpl.MessageInfo, 34)
+var file_awe_proto_goTypes = []any{
+	(ChatRole)(0),                 // 0: awe.ChatRole
+	(TraceStatus)(0),              // 1: awe.TraceStatus
+	(*ChatMessage)(nil),           // 2: awe.ChatMessage
+	(*ChatRequest)(nil),           // 3: awe.ChatRequest
+	(*ChatResponse)(nil),          // 4: awe.ChatResponse
+	(*SearchRequest)(nil),         // 5: awe.SearchRequest
+	(*Document)(nil),              // 6: awe.Document
+	(*SearchResponse)(nil),        // 7: awe.SearchResponse
+	(*ExecuteRequest)(nil),        // 8: awe.ExecuteRequest
+	(*ExecuteResponse)(nil),       // 9: awe.ExecuteResponse
+	(*TraceRequest)(nil),          // 10: awe.TraceRequest
+	(*TraceResponse)(nil),         // 11: awe.TraceResponse
+	(*ListTracesRequest)(nil),     // 12: awe.ListTracesRequest
+	(*ListTracesResponse)(nil),    // 13: awe.ListTracesResponse
+	(*Span)(nil),                  // 14: awe.Span
+	(*ProviderUsage)(nil),         // 15: awe.ProviderUsage
+	(*UsageTotals)(nil),           // 16: awe.UsageTotals
+	(*AttachRequest)(nil),         // 17: awe.AttachRequest
+	(*CancelRequest)(nil),         // 18: awe.CancelRequest
+	(*CancelResponse)(nil),        // 19: awe.CancelResponse
+	(*UsageRequest)(nil),          // 20: awe.UsageRequest
+	(*UsageResponse)(nil),         // 21: awe.UsageResponse
+	(*CreateSessionRequest)(nil),  // 22: awe.CreateSessionRequest
+	(*Session)(nil),               // 23: awe.Session
+	(*GetSessionRequest)(nil),     // 24: awe.GetSessionRequest
+	(*DeleteSessionRequest)(nil),  // 25: awe.DeleteSessionRequest
+	(*DeleteSessionResponse)(nil), // 26: awe.DeleteSessionResponse
+	(*SessionRequest)(nil),        // 27: awe.SessionRequest
+	(*SessionResponse)(nil),       // 28: awe.SessionResponse
+	nil,                           // 29: awe.ChatRequest.ArgsEntry
+	nil,                           // 30: awe.SearchRequest.ArgsEntry
+	nil,                           // 31: awe.ExecuteRequest.ArgsEntry
+	nil,                           // 32: awe.Span.ArgsEntry
+	nil,                           // 33: awe.Span.OutputsEntry
+	nil,                           // 34: awe.CreateSessionRequest.ArgsEntry
+	nil,                           // 35: awe.SessionRequest.ArgsEntry
+}
+var file_awe_proto_depIdxs = []int32{
+	0,  // 0: awe.ChatMessage.role:type_name -> awe.ChatRole
+	2,  // 1: awe.ChatRequest.history:type_name -> awe.ChatMessage
+	29, // 2: awe.ChatRequest.args:type_name -> awe.ChatRequest.ArgsEntry
+	30, // 3: awe.SearchRequest.args:type_name -> awe.SearchRequest.ArgsEntry
+	6,  // 4: awe.SearchResponse.document:type_name -> awe.Document
+	2,  // 5: awe.ExecuteRequest.history:type_name -> awe.ChatMessage
+	31, // 6: awe.ExecuteRequest.args:type_name -> awe.ExecuteRequest.ArgsEntry
+	6,  // 7: awe.ExecuteResponse.document:type_name -> awe.Document
+	1,  // 8: awe.TraceResponse.status:type_name -> awe.TraceStatus
+	15, // 9: awe.TraceResponse.usage:type_name -> awe.ProviderUsage
+	16, // 10: awe.TraceResponse.usage_totals:type_name -> awe.UsageTotals
+	14, // 11: awe.TraceResponse.spans:type_name -> awe.Span
+	1,  // 12: awe.ListTracesRequest.status:type_name -> awe.TraceStatus
+	11, // 13: awe.ListTracesResponse.traces:type_name -> awe.TraceResponse
+	32, // 14: awe.Span.args:type_name -> awe.Span.ArgsEntry
+	33, // 15: awe.Span.outputs:type_name -> awe.Span.OutputsEntry
+	16, // 16: awe.UsageResponse.totals:type_name -> awe.UsageTotals
+	34, // 17: awe.CreateSessionRequest.args:type_name -> awe.CreateSessionRequest.ArgsEntry
+	2,  // 18: awe.Session.history:type_name -> awe.ChatMessage
+	35, // 19: awe.SessionRequest.args:type_name -> awe.SessionRequest.ArgsEntry
+	6,  // 20: awe.SessionResponse.document:type_name -> awe.Document
+	3,  // 21: awe.AWEService.Chat:input_type -> awe.ChatRequest
+	5,  // 22: awe.AWEService.Search:input_type -> awe.SearchRequest
+	8,  // 23: awe.AWEService.Execute:input_type -> awe.ExecuteRequest
+	10, // 24: awe.AWEService.Trace:input_type -> awe.TraceRequest
+	12, // 25: awe.AWEService.ListTraces:input_type -> awe.ListTracesRequest
+	17, // 26: awe.AWEService.Attach:input_type -> awe.AttachRequest
+	18, // 27: awe.AWEService.Cancel:input_type -> awe.CancelRequest
+	20, // 28: awe.AWEService.Usage:input_type -> awe.UsageRequest
+	22, // 29: awe.AWEService.CreateSession:input_type -> awe.CreateSessionRequest
+	24, // 30: awe.AWEService.GetSession:input_type -> awe.GetSessionRequest
+	25, // 31: awe.AWEService.DeleteSession:input_type -> awe.DeleteSessionRequest
+	27, // 32: awe.AWEService.ChatSession:input_type -> awe.SessionRequest
+	4,  // 33: awe.AWEService.Chat:output_type -> awe.ChatResponse
+	7,  // 34: awe.AWEService.Search:output_type -> awe.SearchResponse
+	9,  // 35: awe.AWEService.Execute:output_type -> awe.ExecuteResponse
+	11, // 36: awe.AWEService.Trace:output_type -> awe.TraceResponse
+	13, // 37: awe.AWEService.ListTraces:output_type -> awe.ListTracesResponse
+	9,  // 38: awe.AWEService.Attach:output_type -> awe.ExecuteResponse
+	19, // 39: awe.AWEService.Cancel:output_type -> awe.CancelResponse
+	21, // 40: awe.AWEService.Usage:output_type -> awe.UsageResponse
+	23, // 41: awe.AWEService.CreateSession:output_type -> awe.Session
+	23, // 42: awe.AWEService.GetSession:output_type -> awe.Session
+	26, // 43: awe.AWEService.DeleteSession:output_type -> awe.DeleteSessionResponse
+	28, // 44: awe.AWEService.ChatSession:output_type -> awe.SessionResponse
+	33, // [33:45] is the sub-list for method output_type
+	21, // [21:33] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_awe_proto_init() }
+func file_awe_proto_init() {
+	if File_awe_proto != nil {
+		return
+	}
+	file_awe_proto_msgTypes[7].OneofWrappers = []any{
+		(*ExecuteResponse_Content)(nil),
+		(*ExecuteResponse_Document)(nil),
+	}
+	file_awe_proto_msgTypes[26].OneofWrappers = []any{
+		(*SessionResponse_Content)(nil),
+		(*SessionResponse_Document)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1010,7 +2400,7 @@ func file_awe_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_awe_proto_rawDesc), len(file_awe_proto_rawDesc)),
 			NumEnums:      2,
-			NumMessages:   14,
+			NumMessages:   34,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,11 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	AWEService_Chat_FullMethodName    = "/awe.AWEService/Chat"
-	AWEService_Search_FullMethodName  = "/awe.AWEService/Search"
-	AWEService_Execute_FullMethodName = "/awe.AWEService/Execute"
-	AWEService_Trace_FullMethodName   = "/awe.AWEService/Trace"
-	AWEService_Attach_FullMethodName  = "/awe.AWEService/Attach"
+	AWEService_Chat_FullMethodName          = "/awe.AWEService/Chat"
+	AWEService_Search_FullMethodName        = "/awe.AWEService/Search"
+	AWEService_Execute_FullMethodName       = "/awe.AWEService/Execute"
+	AWEService_Trace_FullMethodName         = "/awe.AWEService/Trace"
+	AWEService_ListTraces_FullMethodName    = "/awe.AWEService/ListTraces"
+	AWEService_Attach_FullMethodName        = "/awe.AWEService/Attach"
+	AWEService_Cancel_FullMethodName        = "/awe.AWEService/Cancel"
+	AWEService_Usage_FullMethodName         = "/awe.AWEService/Usage"
+	AWEService_CreateSession_FullMethodName = "/awe.AWEService/CreateSession"
+	AWEService_GetSession_FullMethodName    = "/awe.AWEService/GetSession"
+	AWEService_DeleteSession_FullMethodName = "/awe.AWEService/DeleteSession"
+	AWEService_ChatSession_FullMethodName   = "/awe.AWEService/ChatSession"
 )
 
 // AWEServiceClient is the client API for AWEService service.
@@ -34,7 +41,14 @@ type AWEServiceClient interface {
 	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SearchResponse], error)
 	Execute(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteResponse], error)
 	Trace(ctx context.Context, in *TraceRequest, opts ...grpc.CallOption) (*TraceResponse, error)
+	ListTraces(ctx context.Context, in *ListTracesRequest, opts ...grpc.CallOption) (*ListTracesResponse, error)
 	Attach(ctx context.Context, in *AttachRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteResponse], error)
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
+	Usage(ctx context.Context, in *UsageRequest, opts ...grpc.CallOption) (*UsageResponse, error)
+	CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*Session, error)
+	GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*Session, error)
+	DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error)
+	ChatSession(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionResponse], error)
 }
 
 type aWEServiceClient struct {
@@ -112,6 +126,16 @@ func (c *aWEServiceClient) Trace(ctx context.Context, in *TraceRequest, opts ...
 	return out, nil
 }
 
+func (c *aWEServiceClient) ListTraces(ctx context.Context, in *ListTracesRequest, opts ...grpc.CallOption) (*ListTracesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTracesResponse)
+	err := c.cc.Invoke(ctx, AWEService_ListTraces_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *aWEServiceClient) Attach(ctx context.Context, in *AttachRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &AWEService_ServiceDesc.Streams[3], AWEService_Attach_FullMethodName, cOpts...)
@@ -131,6 +155,69 @@ func (c *aWEServiceClient) Attach(ctx context.Context, in *AttachRequest, opts .
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type AWEService_AttachClient = grpc.ServerStreamingClient[ExecuteResponse]
 
+func (c *aWEServiceClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, AWEService_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aWEServiceClient) Usage(ctx context.Context, in *UsageRequest, opts ...grpc.CallOption) (*UsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UsageResponse)
+	err := c.cc.Invoke(ctx, AWEService_Usage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aWEServiceClient) CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*Session, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Session)
+	err := c.cc.Invoke(ctx, AWEService_CreateSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aWEServiceClient) GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*Session, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Session)
+	err := c.cc.Invoke(ctx, AWEService_GetSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aWEServiceClient) DeleteSession(ctx context.Context, in *DeleteSessionRequest, opts ...grpc.CallOption) (*DeleteSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSessionResponse)
+	err := c.cc.Invoke(ctx, AWEService_DeleteSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aWEServiceClient) ChatSession(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AWEService_ServiceDesc.Streams[4], AWEService_ChatSession_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SessionRequest, SessionResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AWEService_ChatSessionClient = grpc.BidiStreamingClient[SessionRequest, SessionResponse]
+
 // AWEServiceServer is the server API for AWEService service.
 // All implementations must embed UnimplementedAWEServiceServer
 // for forward compatibility.
@@ -139,7 +226,14 @@ type AWEServiceServer interface {
 	Search(*SearchRequest, grpc.ServerStreamingServer[SearchResponse]) error
 	Execute(*ExecuteRequest, grpc.ServerStreamingServer[ExecuteResponse]) error
 	Trace(context.Context, *TraceRequest) (*TraceResponse, error)
+	ListTraces(context.Context, *ListTracesRequest) (*ListTracesResponse, error)
 	Attach(*AttachRequest, grpc.ServerStreamingServer[ExecuteResponse]) error
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+	Usage(context.Context, *UsageRequest) (*UsageResponse, error)
+	CreateSession(context.Context, *CreateSessionRequest) (*Session, error)
+	GetSession(context.Context, *GetSessionRequest) (*Session, error)
+	DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error)
+	ChatSession(grpc.BidiStreamingServer[SessionRequest, SessionResponse]) error
 	mustEmbedUnimplementedAWEServiceServer()
 }
 
@@ -162,9 +256,30 @@ func (UnimplementedAWEServiceServer) Execute(*ExecuteRequest, grpc.ServerStreami
 func (UnimplementedAWEServiceServer) Trace(context.Context, *TraceRequest) (*TraceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Trace not implemented")
 }
+func (UnimplementedAWEServiceServer) ListTraces(context.Context, *ListTracesRequest) (*ListTracesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTraces not implemented")
+}
 func (UnimplementedAWEServiceServer) Attach(*AttachRequest, grpc.ServerStreamingServer[ExecuteResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method Attach not implemented")
 }
+func (UnimplementedAWEServiceServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedAWEServiceServer) Usage(context.Context, *UsageRequest) (*UsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Usage not implemented")
+}
+func (UnimplementedAWEServiceServer) CreateSession(context.Context, *CreateSessionRequest) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSession not implemented")
+}
+func (UnimplementedAWEServiceServer) GetSession(context.Context, *GetSessionRequest) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSession not implemented")
+}
+func (UnimplementedAWEServiceServer) DeleteSession(context.Context, *DeleteSessionRequest) (*DeleteSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSession not implemented")
+}
+func (UnimplementedAWEServiceServer) ChatSession(grpc.BidiStreamingServer[SessionRequest, SessionResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatSession not implemented")
+}
 func (UnimplementedAWEServiceServer) mustEmbedUnimplementedAWEServiceServer() {}
 func (UnimplementedAWEServiceServer) testEmbeddedByValue()                    {}
 
@@ -237,6 +352,24 @@ func _AWEService_Trace_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AWEService_ListTraces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTracesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AWEServiceServer).ListTraces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AWEService_ListTraces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AWEServiceServer).ListTraces(ctx, req.(*ListTracesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AWEService_Attach_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(AttachRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -248,6 +381,103 @@ func _AWEService_Attach_Handler(srv interface{}, stream grpc.ServerStream) error
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type AWEService_AttachServer = grpc.ServerStreamingServer[ExecuteResponse]
 
+func _AWEService_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AWEServiceServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AWEService_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AWEServiceServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AWEService_Usage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AWEServiceServer).Usage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AWEService_Usage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AWEServiceServer).Usage(ctx, req.(*UsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AWEService_CreateSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AWEServiceServer).CreateSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AWEService_CreateSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AWEServiceServer).CreateSession(ctx, req.(*CreateSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AWEService_GetSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AWEServiceServer).GetSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AWEService_GetSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AWEServiceServer).GetSession(ctx, req.(*GetSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AWEService_DeleteSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AWEServiceServer).DeleteSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AWEService_DeleteSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AWEServiceServer).DeleteSession(ctx, req.(*DeleteSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AWEService_ChatSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AWEServiceServer).ChatSession(&grpc.GenericServerStream[SessionRequest, SessionResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AWEService_ChatSessionServer = grpc.BidiStreamingServer[SessionRequest, SessionResponse]
+
 // AWEService_ServiceDesc is the grpc.ServiceDesc for AWEService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -259,6 +489,30 @@ var AWEService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Trace",
 			Handler:    _AWEService_Trace_Handler,
 		},
+		{
+			MethodName: "ListTraces",
+			Handler:    _AWEService_ListTraces_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _AWEService_Cancel_Handler,
+		},
+		{
+			MethodName: "Usage",
+			Handler:    _AWEService_Usage_Handler,
+		},
+		{
+			MethodName: "CreateSession",
+			Handler:    _AWEService_CreateSession_Handler,
+		},
+		{
+			MethodName: "GetSession",
+			Handler:    _AWEService_GetSession_Handler,
+		},
+		{
+			MethodName: "DeleteSession",
+			Handler:    _AWEService_DeleteSession_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -281,6 +535,12 @@ var AWEService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _AWEService_Attach_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ChatSession",
+			Handler:       _AWEService_ChatSession_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "awe.proto",
 }
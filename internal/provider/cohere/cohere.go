@@ -23,14 +23,16 @@ package awe_cohere
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/usage"
 	cohere "github.com/cohere-ai/cohere-go/v2"
 	cohereclient "github.com/cohere-ai/cohere-go/v2/client"
 	coherecore "github.com/cohere-ai/cohere-go/v2/core"
@@ -38,6 +40,10 @@ import (
 
 const (
 	EmbedMaxTexts = 96
+
+	defaultModel       = "command-r-08-2024"
+	defaultEmbedModel  = "embed-multilingual-v3.0"
+	defaultRerankModel = "rerank-v3.5"
 )
 
 type embedRequestWrapper struct {
@@ -54,26 +60,28 @@ type embedResponseWrapper struct {
 
 type CohereProvider struct {
 	client *cohereclient.Client
+	opts   options.Options
 }
 
-func New() *CohereProvider {
-	c := cohereclient.NewClient(
-		cohereclient.WithToken(os.Getenv("COHERE_API_KEY")),
-		cohereclient.WithHTTPClient(
-			&http.Client{
-				Timeout: 60 * time.Second,
-			},
-		),
-	)
+func New(opts ...options.Option) *CohereProvider {
+	o := options.New(opts...)
+	clientOpts := []coherecore.RequestOption{
+		cohereclient.WithToken(o.APIKeyOr(os.Getenv("COHERE_API_KEY"))),
+		cohereclient.WithHTTPClient(o.HTTPClient(60*time.Second, 1)),
+	}
+	if o.BaseURL != "" {
+		clientOpts = append(clientOpts, cohereclient.WithBaseURL(o.BaseURL))
+	}
 	return &CohereProvider{
-		client: c,
+		client: cohereclient.NewClient(clientOpts...),
+		opts:   o,
 	}
 }
 
 func (p CohereProvider) Generate(ctx context.Context, req api.GenerationRequest) (api.CompletionStream, error) {
 	temp := float64(req.Temperature)
 	cohereReq := &cohere.V2ChatStreamRequest{
-		Model:       "command-r-08-2024",
+		Model:       p.opts.ModelOr(defaultModel),
 		Temperature: &temp,
 	}
 
@@ -93,16 +101,17 @@ func (p CohereProvider) Generate(ctx context.Context, req api.GenerationRequest)
 		return nil, fmt.Errorf("chat streaming request failed: %w", err)
 	}
 
-	return &CohereCompletionStream{stream: stream}, nil
+	return &CohereCompletionStream{stream: stream, model: cohereReq.Model}, nil
 }
 
 func (p CohereProvider) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
-	if req.Query == "" {
+	if req.Query == "" && len(req.History) == 0 {
 		return nil, fmt.Errorf("completion request failed: missing parameter 'query' in request")
 	}
 
 	cohereReq := &cohere.V2ChatStreamRequest{
-		Model: "command-r-08-2024",
+		Model: p.opts.ModelOr(defaultModel),
+		Tools: p.parseTools(req.Tools),
 	}
 
 	if req.ModelName != "" {
@@ -123,19 +132,21 @@ func (p CohereProvider) Chat(ctx context.Context, req api.ChatRequest) (api.Comp
 		cohereReq.Messages = append(cohereReq.Messages, history...)
 	}
 
-	cohereReq.Messages = append(cohereReq.Messages, &cohere.ChatMessageV2{
-		Role: "user",
-		User: &cohere.UserMessage{Content: &cohere.UserMessageContent{
-			String: req.Query,
-		}},
-	})
+	if req.Query != "" {
+		cohereReq.Messages = append(cohereReq.Messages, &cohere.ChatMessageV2{
+			Role: "user",
+			User: &cohere.UserMessage{Content: &cohere.UserMessageContent{
+				String: req.Query,
+			}},
+		})
+	}
 
 	stream, err := p.client.V2.ChatStream(ctx, cohereReq)
 	if err != nil {
 		return nil, fmt.Errorf("chat streaming request failed: %w", err)
 	}
 
-	return &CohereCompletionStream{stream: stream}, nil
+	return &CohereCompletionStream{stream: stream, model: cohereReq.Model}, nil
 }
 
 func (p CohereProvider) EmbedQuery(ctx context.Context, q string) ([]float32, error) {
@@ -143,7 +154,7 @@ func (p CohereProvider) EmbedQuery(ctx context.Context, q string) ([]float32, er
 		ctx,
 		&cohere.V2EmbedRequest{
 			Texts:          []string{q},
-			Model:          "embed-multilingual-v3.0",
+			Model:          p.opts.ModelOr(defaultEmbedModel),
 			InputType:      cohere.EmbedInputTypeSearchQuery,
 			EmbeddingTypes: []cohere.EmbeddingType{cohere.EmbeddingTypeFloat},
 		},
@@ -151,6 +162,7 @@ func (p CohereProvider) EmbedQuery(ctx context.Context, q string) ([]float32, er
 	if err != nil {
 		return nil, fmt.Errorf("embed request failed: %w", err)
 	}
+	reportMeta(ctx, p.opts.ModelOr(defaultEmbedModel), resp.Meta)
 
 	f32 := make([]float32, 0, len(resp.Embeddings.Float[0]))
 	for _, f := range resp.Embeddings.Float[0] {
@@ -166,7 +178,7 @@ func (p CohereProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 		if len(doc.Chunks) <= EmbedMaxTexts {
 			req := &cohere.V2EmbedRequest{
 				Texts:          doc.Chunks,
-				Model:          "embed-multilingual-v3.0",
+				Model:          p.opts.ModelOr(defaultEmbedModel),
 				InputType:      cohere.EmbedInputTypeSearchDocument,
 				EmbeddingTypes: []cohere.EmbeddingType{cohere.EmbeddingTypeFloat},
 			}
@@ -187,7 +199,7 @@ func (p CohereProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 
 			req := &cohere.V2EmbedRequest{
 				Texts:          doc.Chunks[start:end],
-				Model:          "embed-multilingual-v3.0",
+				Model:          p.opts.ModelOr(defaultEmbedModel),
 				InputType:      cohere.EmbedInputTypeSearchDocument,
 				EmbeddingTypes: []cohere.EmbeddingType{cohere.EmbeddingTypeFloat},
 			}
@@ -212,6 +224,7 @@ func (p CohereProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 			defer wg.Done()
 			resp, err := p.client.V2.Embed(ctx, ereq.Request)
 			if err == nil {
+				reportMeta(ctx, ereq.Request.Model, resp.Meta)
 				embedRespMu.Lock()
 				embedResponses = append(embedResponses, &embedResponseWrapper{
 					Title:    ereq.Title,
@@ -261,7 +274,7 @@ func (p CohereProvider) Rerank(ctx context.Context, req api.RerankRequest) (*api
 	coReq := &cohere.V2RerankRequest{
 		Query:           req.Query,
 		Documents:       req.Documents,
-		Model:           "rerank-v3.5",
+		Model:           p.opts.ModelOr(defaultRerankModel),
 		ReturnDocuments: &returnDocuments,
 	}
 
@@ -277,6 +290,7 @@ func (p CohereProvider) Rerank(ctx context.Context, req api.RerankRequest) (*api
 	if err != nil {
 		return nil, fmt.Errorf("rerank request failed: %w", err)
 	}
+	reportMeta(ctx, coReq.Model, resp.Meta)
 
 	threshold := api.RerankScoreThreshold
 	if req.Threshold != nil {
@@ -300,6 +314,17 @@ func (p CohereProvider) Rerank(ctx context.Context, req api.RerankRequest) (*api
 	}, nil
 }
 
+// reportMeta reports the billed units of a response, see usage.Report.
+func reportMeta(ctx context.Context, model string, meta *cohere.ApiMeta) {
+	billed := meta.GetBilledUnits()
+	usage.Report(ctx, usage.Record{
+		Model:        model,
+		InputTokens:  int(derefFloat(billed.GetInputTokens())),
+		OutputTokens: int(derefFloat(billed.GetOutputTokens())),
+		Units:        int(derefFloat(billed.GetSearchUnits())),
+	})
+}
+
 func (p CohereProvider) parseRequestHistory(h []*api.ChatMessage) cohere.ChatMessages {
 	messages := make([]*cohere.ChatMessageV2, 0, len(h))
 	for _, chatMsg := range h {
@@ -313,12 +338,40 @@ func (p CohereProvider) parseRequestHistory(h []*api.ChatMessage) cohere.ChatMes
 				}},
 			}
 		case api.RoleAssistant:
+			assistant := &cohere.AssistantMessage{}
+			if chatMsg.Content != "" {
+				assistant.Content = &cohere.AssistantMessageContent{String: chatMsg.Content}
+			}
+			for _, tc := range chatMsg.ToolCalls {
+				args, _ := json.Marshal(tc.Arguments)
+				assistant.ToolCalls = append(assistant.ToolCalls, &cohere.ToolCallV2{
+					Id:   &tc.ID,
+					Type: cohere.String("function"),
+					Function: &cohere.ToolCallV2Function{
+						Name:      &tc.Name,
+						Arguments: cohere.String(string(args)),
+					},
+				})
+			}
+			coMsg = &cohere.ChatMessageV2{
+				Role:      "assistant",
+				Assistant: assistant,
+			}
+		case api.RoleSystem:
 			coMsg = &cohere.ChatMessageV2{
-				Role: "assistant",
-				Assistant: &cohere.AssistantMessage{Content: &cohere.AssistantMessageContent{
+				Role: "system",
+				System: &cohere.SystemMessage{Content: &cohere.SystemMessageContent{
 					String: chatMsg.Content,
 				}},
 			}
+		case api.RoleTool:
+			coMsg = &cohere.ChatMessageV2{
+				Role: "tool",
+				Tool: &cohere.ToolMessageV2{
+					ToolCallId: chatMsg.ToolCallID,
+					Content:    &cohere.ToolMessageV2Content{String: chatMsg.Content},
+				},
+			}
 		default:
 			slog.Warn("failed to parse chat message from history", "role", chatMsg.Role, "content", chatMsg.Content, "err", "unrecognized role")
 			continue
@@ -330,26 +383,130 @@ func (p CohereProvider) parseRequestHistory(h []*api.ChatMessage) cohere.ChatMes
 	return messages
 }
 
+func (p CohereProvider) parseTools(tools []*api.Tool) []*cohere.ToolV2 {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	parsed := make([]*cohere.ToolV2, 0, len(tools))
+	for _, t := range tools {
+		var params map[string]any
+		if t.Parameters != nil {
+			data, _ := json.Marshal(t.Parameters)
+			json.Unmarshal(data, &params)
+		}
+		parsed = append(parsed, &cohere.ToolV2{
+			Type: cohere.String("function"),
+			Function: &cohere.ToolV2Function{
+				Name:        t.Name,
+				Description: cohere.String(t.Description),
+				Parameters:  params,
+			},
+		})
+	}
+	return parsed
+}
+
 type CohereCompletionStream struct {
-	stream *coherecore.Stream[cohere.StreamedChatResponseV2]
+	stream    *coherecore.Stream[cohere.StreamedChatResponseV2]
+	model     string
+	events    api.StreamEventQueue
+	toolCalls api.ToolCallAccumulator
 }
 
-func (s CohereCompletionStream) Recv() (string, error) {
+func (s *CohereCompletionStream) Recv() (*api.StreamEvent, error) {
 	for {
+		if ev, ok := s.events.Pop(); ok {
+			return ev, nil
+		}
+
 		resp, err := s.stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.receive(resp); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *CohereCompletionStream) receive(resp cohere.StreamedChatResponseV2) error {
+	if resp.ContentDelta != nil {
+		if text := deref(resp.ContentDelta.GetDelta().GetMessage().GetContent().GetText()); text != "" {
+			s.events.Push(api.ContentEvent(text))
+		}
+	}
+
+	if start := resp.ToolCallStart; start != nil && start.Index != nil {
+		tc := start.GetDelta().GetMessage().GetToolCalls()
+		s.toolCalls.Add(*start.Index,
+			deref(tc.GetId()),
+			deref(tc.GetFunction().GetName()),
+			deref(tc.GetFunction().GetArguments()))
+	}
 
-		slog.Info("cohere", "resp", resp, "err", err)
+	if delta := resp.ToolCallDelta; delta != nil && delta.Index != nil {
+		fn := delta.GetDelta().GetMessage().GetToolCalls().GetFunction()
+		s.toolCalls.Add(*delta.Index, "", "", deref(fn.GetArguments()))
+	}
 
+	if end := resp.MessageEnd; end != nil {
+		calls, err := s.toolCalls.Flush()
 		if err != nil {
-			return "", err
+			return err
+		}
+		for _, tc := range calls {
+			s.events.Push(api.ToolCallEvent(tc))
+		}
+
+		delta := end.GetDelta()
+		if tokens := delta.GetUsage().GetTokens(); tokens != nil {
+			s.events.Push(api.UsageEvent(api.Usage{
+				InputTokens:  int(derefFloat(tokens.InputTokens)),
+				OutputTokens: int(derefFloat(tokens.OutputTokens)),
+			}, s.model))
 		}
 
-		if resp.ContentDelta != nil {
-			return *resp.ContentDelta.Delta.Message.Content.Text, nil
+		reason := delta.GetFinishReason()
+		if reason != nil && *reason == cohere.ChatFinishReasonError {
+			return &api.StreamError{Code: string(*reason), Message: "generation failed"}
 		}
+		s.events.Push(api.FinishEvent(finishReason(reason), s.model))
+	}
+	return nil
+}
+
+func finishReason(reason *cohere.ChatFinishReason) api.FinishReason {
+	if reason == nil {
+		return api.FinishReasonOther
+	}
+
+	switch *reason {
+	case cohere.ChatFinishReasonComplete, cohere.ChatFinishReasonStopSequence:
+		return api.FinishReasonStop
+	case cohere.ChatFinishReasonMaxTokens:
+		return api.FinishReasonLength
+	case cohere.ChatFinishReasonToolCall:
+		return api.FinishReasonToolCalls
+	}
+	return api.FinishReasonOther
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefFloat(f *float64) float64 {
+	if f == nil {
+		return 0
 	}
+	return *f
 }
 
-func (s CohereCompletionStream) Close() error {
+func (s *CohereCompletionStream) Close() error {
 	return s.stream.Close()
 }
@@ -27,8 +27,11 @@ import (
 	"io"
 	"iter"
 	"os"
+	"strings"
 
 	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/usage"
 	"google.golang.org/genai"
 )
 
@@ -45,21 +48,34 @@ const segmentPrompt = `You are an expert document chunker, responsible for segme
 5.  **Artifact Removal & Repair:** Identify and remove any nonsensical artifacts or inconsistencies that may have resulted from document parsing (e.g., broken characters, redundant whitespace, misplaced punctuation, OCR errors). Repair minor grammatical errors or inconsistencies to improve readability and searchability.
 `
 
+const (
+	defaultModel      = "gemini-2.0-flash"
+	defaultEmbedModel = "gemini-embedding-exp-03-07"
+	defaultChunkModel = "models/gemini-2.5-flash-preview-05-20"
+)
+
 type GeminiProvider struct {
 	client     *genai.Client
 	vectorDims *int32
+	opts       options.Options
 }
 
-func New() *GeminiProvider {
+func New(opts ...options.Option) *GeminiProvider {
 	// New methods might need error return
 	// to handle error returns from client libs like genai
+	o := options.New(opts...)
 	c, _ := genai.NewClient(context.Background(), &genai.ClientConfig{
-		APIKey:  os.Getenv("GEMINI_API_KEY"),
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     o.APIKeyOr(os.Getenv("GEMINI_API_KEY")),
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: o.HTTPClient(0, 1),
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL: o.BaseURL,
+		},
 	})
 	p := &GeminiProvider{
 		client:     c,
 		vectorDims: new(int32),
+		opts:       o,
 	}
 	*(p.vectorDims) = 1536
 	return p
@@ -74,7 +90,7 @@ func (p GeminiProvider) Generate(ctx context.Context, req api.GenerationRequest)
 	if req.ModelName != "" {
 		modelName = req.ModelName
 	} else {
-		modelName = "gemini-2.0-flash"
+		modelName = p.opts.ModelOr(defaultModel)
 	}
 
 	if req.ResponseSchema != nil {
@@ -94,16 +110,26 @@ func (p GeminiProvider) Generate(ctx context.Context, req api.GenerationRequest)
 
 func (p GeminiProvider) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
 	contents := parseRequestHistory(req.History)
-	contents = append(contents, genai.NewContentFromText(req.Query, genai.RoleUser))
+	if req.Query != "" {
+		contents = append(contents, genai.NewContentFromText(req.Query, genai.RoleUser))
+	}
 
 	config := &genai.GenerateContentConfig{}
-	if req.SystemPrompt != "" {
-		config.SystemInstruction = genai.NewContentFromText(req.SystemPrompt, "")
+	if system := systemInstruction(req); system != "" {
+		config.SystemInstruction = genai.NewContentFromText(system, "")
+	}
+	if len(req.Tools) > 0 {
+		config.Tools = []*genai.Tool{parseTools(req.Tools)}
+	}
+
+	modelName := p.opts.ModelOr(defaultModel)
+	if req.ModelName != "" {
+		modelName = req.ModelName
 	}
 
 	i := p.client.Models.GenerateContentStream(
 		ctx,
-		"gemini-2.0-flash",
+		modelName,
 		contents,
 		config,
 	)
@@ -123,7 +149,7 @@ func (p GeminiProvider) EmbedQuery(ctx context.Context, q string) ([]float32, er
 		OutputDimensionality: p.vectorDims,
 	}
 
-	res, err := p.client.Models.EmbedContent(ctx, "gemini-embedding-exp-03-07", contents, config)
+	res, err := p.client.Models.EmbedContent(ctx, p.opts.ModelOr(defaultEmbedModel), contents, config)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +174,7 @@ func (p GeminiProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 			OutputDimensionality: p.vectorDims,
 		}
 
-		res, err := p.client.Models.EmbedContent(ctx, "gemini-embedding-exp-03-07", contents, config)
+		res, err := p.client.Models.EmbedContent(ctx, p.opts.ModelOr(defaultEmbedModel), contents, config)
 		if err != nil {
 			return nil, err
 		}
@@ -200,13 +226,20 @@ func (p GeminiProvider) ChunkDocument(ctx context.Context, doc *api.DocumentCont
 
 	resp, err := p.client.Models.GenerateContent(
 		ctx,
-		"models/gemini-2.5-flash-preview-05-20",
+		p.opts.ModelOr(defaultChunkModel),
 		genai.Text(content),
 		reqConfig,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if u := resp.UsageMetadata; u != nil {
+		usage.Report(ctx, usage.Record{
+			Model:        resp.ModelVersion,
+			InputTokens:  int(u.PromptTokenCount),
+			OutputTokens: int(u.CandidatesTokenCount + u.ThoughtsTokenCount),
+		})
+	}
 
 	var respChunks struct {
 		Chunks []string `json:"chunks"`
@@ -220,25 +253,85 @@ func (p GeminiProvider) ChunkDocument(ctx context.Context, doc *api.DocumentCont
 	return respChunks.Chunks, nil
 }
 
+// systemInstruction returns the system prompt of req, followed by
+// the system messages of its history.
+func systemInstruction(req api.ChatRequest) string {
+	parts := make([]string, 0, 1)
+	if req.SystemPrompt != "" {
+		parts = append(parts, req.SystemPrompt)
+	}
+	for _, m := range req.History {
+		if m.Role == api.RoleSystem && m.Content != "" {
+			parts = append(parts, m.Content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
 func parseRequestHistory(h []*api.ChatMessage) []*genai.Content {
-	contents := make([]*genai.Content, len(h))
+	contents := make([]*genai.Content, 0, len(h))
 	roleTypes := map[api.ChatMessageRole]genai.Role{
 		api.RoleUser:      genai.RoleUser,
 		api.RoleAssistant: genai.RoleModel,
 	}
 	for i, m := range h {
-		c := genai.NewContentFromText(m.Content, roleTypes[m.Role])
-		contents[i] = c
+		if m.Role == api.RoleSystem {
+			// system messages are part of the system instruction
+			continue
+		}
+		if m.Role == api.RoleTool {
+			part := &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:       m.ToolCallID,
+				Name:     m.ToolName,
+				Response: map[string]any{"output": m.Content},
+			}}
+			// responses to the calls of one turn are sent together
+			if i > 0 && h[i-1].Role == api.RoleTool {
+				last := contents[len(contents)-1]
+				last.Parts = append(last.Parts, part)
+				continue
+			}
+			contents = append(contents, genai.NewContentFromParts([]*genai.Part{part}, genai.RoleUser))
+			continue
+		}
+
+		parts := make([]*genai.Part, 0, 1+len(m.ToolCalls))
+		if m.Content != "" {
+			parts = append(parts, genai.NewPartFromText(m.Content))
+		}
+		for _, tc := range m.ToolCalls {
+			parts = append(parts, &genai.Part{FunctionCall: &genai.FunctionCall{
+				ID:   tc.ID,
+				Name: tc.Name,
+				Args: tc.Arguments,
+			}})
+		}
+		contents = append(contents, genai.NewContentFromParts(parts, roleTypes[m.Role]))
 	}
 	return contents
 }
 
+func parseTools(tools []*api.Tool) *genai.Tool {
+	decls := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, t := range tools {
+		decl := &genai.FunctionDeclaration{
+			Name:        t.Name,
+			Description: t.Description,
+		}
+		if t.Parameters != nil {
+			decl.Parameters = parseResponseSchema(t.Parameters)
+		}
+		decls = append(decls, decl)
+	}
+	return &genai.Tool{FunctionDeclarations: decls}
+}
+
 func parseResponseSchema(s *api.Schema) *genai.Schema {
 	schema := &genai.Schema{
 		Description: s.Description,
 		Title:       s.Title,
 		Required:    s.Required,
-		Type:        genai.Type(s.Type),
+		Type:        genai.Type(strings.ToUpper(string(s.Type))),
 	}
 
 	if s.Items != nil {
@@ -259,24 +352,89 @@ func parseResponseSchema(s *api.Schema) *genai.Schema {
 type GeminiCompletionStream struct {
 	next func() (*genai.GenerateContentResponse, error, bool)
 	stop func()
+
+	events    api.StreamEventQueue
+	toolCalls int
 }
 
-func (s GeminiCompletionStream) Recv() (string, error) {
-	res, err, valid := s.next()
-	// slog.Info("res", "", res, "err", err)
-	if !valid {
-		// iterator is finished
-		return "", io.EOF
+func (s *GeminiCompletionStream) Recv() (*api.StreamEvent, error) {
+	for {
+		if ev, ok := s.events.Pop(); ok {
+			return ev, nil
+		}
+
+		res, err, valid := s.next()
+		if !valid {
+			// iterator is finished
+			return nil, io.EOF
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.receive(res)
 	}
+}
 
-	if err != nil {
-		return "", err
+func (s *GeminiCompletionStream) receive(res *genai.GenerateContentResponse) {
+	if len(res.Candidates) == 0 {
+		return
+	}
+	candidate := res.Candidates[0]
+
+	if candidate.Content != nil {
+		var text string
+		for _, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				s.events.Push(api.ToolCallEvent(&api.ToolCall{
+					ID:        part.FunctionCall.ID,
+					Name:      part.FunctionCall.Name,
+					Arguments: part.FunctionCall.Args,
+				}))
+				s.toolCalls += 1
+				continue
+			}
+			if !part.Thought {
+				text += part.Text
+			}
+		}
+		if text != "" {
+			s.events.Push(api.ContentEvent(text))
+		}
 	}
 
-	return res.Text(), nil
+	// usage is reported with every chunk, only the last one is complete
+	if candidate.FinishReason != "" {
+		if u := res.UsageMetadata; u != nil {
+			s.events.Push(api.UsageEvent(api.Usage{
+				InputTokens:  int(u.PromptTokenCount),
+				OutputTokens: int(u.CandidatesTokenCount + u.ThoughtsTokenCount),
+				TotalTokens:  int(u.TotalTokenCount),
+			}, res.ModelVersion))
+		}
+		s.events.Push(api.FinishEvent(s.finishReason(candidate.FinishReason), res.ModelVersion))
+	}
+}
+
+func (s *GeminiCompletionStream) finishReason(reason genai.FinishReason) api.FinishReason {
+	switch reason {
+	case genai.FinishReasonStop:
+		// gemini does not finish with a distinct reason after function calls
+		if s.toolCalls > 0 {
+			return api.FinishReasonToolCalls
+		}
+		return api.FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		return api.FinishReasonLength
+	case genai.FinishReasonSafety, genai.FinishReasonRecitation, genai.FinishReasonBlocklist,
+		genai.FinishReasonProhibitedContent, genai.FinishReasonSPII:
+		return api.FinishReasonContentFilter
+	}
+	return api.FinishReasonOther
 }
 
-func (s GeminiCompletionStream) Close() error {
+func (s *GeminiCompletionStream) Close() error {
 	s.stop()
 	return nil
 }
@@ -29,9 +29,12 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/alan-mat/awe/internal/api"
 	"github.com/alan-mat/awe/internal/http"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/usage"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -67,17 +70,19 @@ type embeddingResponse struct {
 type JinaAIProvider struct {
 	client     http.Client
 	vectorDims uint
+	opts       options.Options
 }
 
-func New() *JinaAIProvider {
+func New(opts ...options.Option) *JinaAIProvider {
+	o := options.New(opts...)
 	c := http.NewClient(
-		Endpoint,
-		http.WithMaxRetries(3),
-		http.WithApiKey(os.Getenv("JINA_API_KEY")),
+		o.BaseURLOr(Endpoint),
+		o.HTTPClientOptions(os.Getenv("JINA_API_KEY"), 60*time.Second, 3)...,
 	)
 	p := &JinaAIProvider{
 		client:     c,
 		vectorDims: 1024,
+		opts:       o,
 	}
 	return p
 }
@@ -90,7 +95,7 @@ func (p JinaAIProvider) ChunkDocument(ctx context.Context, doc *api.DocumentCont
 	var g errgroup.Group
 	for _, c := range contents {
 		g.Go(func() error {
-			resp, err := p.requestSegmenter(c)
+			resp, err := p.requestSegmenter(ctx, c)
 			if err == nil {
 				responses = append(responses, resp)
 			}
@@ -132,7 +137,7 @@ func (p JinaAIProvider) ChunkDocument(ctx context.Context, doc *api.DocumentCont
 }
 
 func (p JinaAIProvider) EmbedQuery(ctx context.Context, q string) ([]float32, error) {
-	resp, err := p.requestEmbedding([]string{q})
+	resp, err := p.requestEmbedding(ctx, []string{q})
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +165,7 @@ func (p JinaAIProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 		}
 		slog.Info("msg", "largest chunk", largest, "total", total)
 
-		resp, err := p.requestEmbedding(doc.Chunks)
+		resp, err := p.requestEmbedding(ctx, doc.Chunks)
 		if err != nil {
 			slog.Error("error", "err", err)
 			return nil, err
@@ -186,7 +191,7 @@ func (p JinaAIProvider) GetDimensions() uint {
 	return p.vectorDims
 }
 
-func (p JinaAIProvider) requestSegmenter(content string) (*segmentResponse, error) {
+func (p JinaAIProvider) requestSegmenter(ctx context.Context, content string) (*segmentResponse, error) {
 	requestData := map[string]any{
 		"return_chunks":    true,
 		"max_chunk_length": 768,
@@ -208,14 +213,15 @@ func (p JinaAIProvider) requestSegmenter(content string) (*segmentResponse, erro
 	if err != nil {
 		return nil, err
 	}
+	usage.Report(ctx, usage.Record{InputTokens: segmentResponse.Usage.Tokens})
 
 	return &segmentResponse, nil
 }
 
-func (p JinaAIProvider) requestEmbedding(input []string) (*embeddingResponse, error) {
+func (p JinaAIProvider) requestEmbedding(ctx context.Context, input []string) (*embeddingResponse, error) {
 	requestData := map[string]any{
 		"input":      input,
-		"model":      "jina-embeddings-v3",
+		"model":      p.opts.ModelOr("jina-embeddings-v3"),
 		"task":       "retrieval.passage",
 		"dimensions": p.vectorDims,
 	}
@@ -235,6 +241,7 @@ func (p JinaAIProvider) requestEmbedding(input []string) (*embeddingResponse, er
 	if err != nil {
 		return nil, err
 	}
+	usage.Report(ctx, usage.Record{Model: embeddingResponse.Model, InputTokens: embeddingResponse.UsageInfo.TotalTokens})
 
 	return &embeddingResponse, nil
 }
@@ -0,0 +1,204 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+// limiter is a semaphore bounding the requests in flight to a provider instance.
+type limiter chan struct{}
+
+func newLimiter(n int) limiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(limiter, n)
+}
+
+func (l limiter) acquire(ctx context.Context) error {
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l limiter) release() {
+	<-l
+}
+
+// limit wraps the provider p used as capability c, so that its calls
+// wait for a free slot of l. Providers are returned as is if l is nil.
+func limit(c Capability, p any, l limiter) any {
+	if l == nil {
+		return p
+	}
+
+	switch c {
+	case CapabilityLM:
+		return limitedLM{lm: p.(LM), l: l}
+	case CapabilityEmbedder:
+		return limitedEmbedder{e: p.(Embedder), l: l}
+	case CapabilityReranker:
+		return limitedReranker{r: p.(Reranker), l: l}
+	case CapabilityDocParser:
+		return limitedDocParser{p: p.(DocParser), l: l}
+	case CapabilitySegmenter:
+		return limitedSegmenter{s: p.(Segmenter), l: l}
+	case CapabilityWebSearcher:
+		return limitedWebSearcher{s: p.(WebSearcher), l: l}
+	}
+	return p
+}
+
+// limitedLM holds a slot for the whole lifetime of a completion stream,
+// until it is closed or fully received.
+type limitedLM struct {
+	lm LM
+	l  limiter
+}
+
+func (m limitedLM) Generate(ctx context.Context, req api.GenerationRequest) (api.CompletionStream, error) {
+	return m.stream(ctx, func() (api.CompletionStream, error) {
+		return m.lm.Generate(ctx, req)
+	})
+}
+
+func (m limitedLM) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
+	return m.stream(ctx, func() (api.CompletionStream, error) {
+		return m.lm.Chat(ctx, req)
+	})
+}
+
+func (m limitedLM) stream(ctx context.Context, open func() (api.CompletionStream, error)) (api.CompletionStream, error) {
+	if err := m.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	cs, err := open()
+	if err != nil {
+		m.l.release()
+		return nil, err
+	}
+	return &limitedStream{CompletionStream: cs, l: m.l}, nil
+}
+
+type limitedStream struct {
+	api.CompletionStream
+	l    limiter
+	once sync.Once
+}
+
+func (s *limitedStream) Recv() (*api.StreamEvent, error) {
+	ev, err := s.CompletionStream.Recv()
+	if err != nil {
+		s.once.Do(s.l.release)
+	}
+	return ev, err
+}
+
+func (s *limitedStream) Close() error {
+	s.once.Do(s.l.release)
+	return s.CompletionStream.Close()
+}
+
+type limitedEmbedder struct {
+	e Embedder
+	l limiter
+}
+
+func (e limitedEmbedder) EmbedQuery(ctx context.Context, q string) ([]float32, error) {
+	if err := e.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer e.l.release()
+	return e.e.EmbedQuery(ctx, q)
+}
+
+func (e limitedEmbedder) EmbedDocuments(ctx context.Context, docs []*api.EmbedDocumentRequest) ([]*api.DocumentEmbedding, error) {
+	if err := e.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer e.l.release()
+	return e.e.EmbedDocuments(ctx, docs)
+}
+
+func (e limitedEmbedder) GetDimensions() uint {
+	return e.e.GetDimensions()
+}
+
+type limitedReranker struct {
+	r Reranker
+	l limiter
+}
+
+func (r limitedReranker) Rerank(ctx context.Context, req api.RerankRequest) (*api.RerankResponse, error) {
+	if err := r.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.l.release()
+	return r.r.Rerank(ctx, req)
+}
+
+type limitedDocParser struct {
+	p DocParser
+	l limiter
+}
+
+func (p limitedDocParser) Parse(ctx context.Context, base64file string) (*api.DocumentContent, error) {
+	if err := p.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.l.release()
+	return p.p.Parse(ctx, base64file)
+}
+
+type limitedSegmenter struct {
+	s Segmenter
+	l limiter
+}
+
+func (s limitedSegmenter) ChunkDocument(ctx context.Context, doc *api.DocumentContent) ([]string, error) {
+	if err := s.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.l.release()
+	return s.s.ChunkDocument(ctx, doc)
+}
+
+type limitedWebSearcher struct {
+	s WebSearcher
+	l limiter
+}
+
+func (s limitedWebSearcher) Search(ctx context.Context, req api.WebSearchRequest) (*api.WebSearchResponse, error) {
+	if err := s.l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.l.release()
+	return s.s.Search(ctx, req)
+}
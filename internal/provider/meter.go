@@ -0,0 +1,225 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/telemetry"
+	"github.com/alan-mat/awe/internal/usage"
+)
+
+// meter wraps the provider p used as capability c, so that its calls are
+// tracked by the usage tracker of the calling context, see usage.StartCall,
+// and exported as spans and metrics.
+func meter(c Capability, ref string, p any) any {
+	name, model := ParseRef(ref)
+	m := meterInfo{provider: name, model: model, capability: string(c)}
+
+	switch c {
+	case CapabilityLM:
+		return meteredLM{lm: p.(LM), m: m}
+	case CapabilityEmbedder:
+		return meteredEmbedder{e: p.(Embedder), m: m}
+	case CapabilityReranker:
+		return meteredReranker{r: p.(Reranker), m: m}
+	case CapabilityDocParser:
+		return meteredDocParser{p: p.(DocParser), m: m}
+	case CapabilitySegmenter:
+		return meteredSegmenter{s: p.(Segmenter), m: m}
+	case CapabilityWebSearcher:
+		return meteredWebSearcher{s: p.(WebSearcher), m: m}
+	}
+	return p
+}
+
+type meterInfo struct {
+	provider, model, capability string
+}
+
+func (m meterInfo) start(ctx context.Context) (context.Context, *meterCall) {
+	ctx, uc := usage.StartCall(ctx, m.provider, m.capability, m.model)
+	ctx, tc := telemetry.StartProviderCall(ctx, m.provider, m.capability, m.model)
+	return ctx, &meterCall{usage: uc, telemetry: tc}
+}
+
+// meterCall is a provider call in progress.
+type meterCall struct {
+	usage     *usage.Call
+	telemetry *telemetry.ProviderCall
+}
+
+// end ends the call, which failed if err is not nil.
+func (c *meterCall) end(err error) {
+	c.usage.End()
+	rec := c.usage.Record()
+	c.telemetry.End(rec.Model, rec.InputTokens, rec.OutputTokens, err)
+}
+
+// meteredLM tracks the tokens reported in the usage events of completion
+// streams, the call ends when the stream is closed or fully received.
+type meteredLM struct {
+	lm LM
+	m  meterInfo
+}
+
+func (m meteredLM) Generate(ctx context.Context, req api.GenerationRequest) (api.CompletionStream, error) {
+	ctx, call := m.m.start(ctx)
+	cs, err := m.lm.Generate(ctx, req)
+	return m.stream(call, cs, err)
+}
+
+func (m meteredLM) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
+	ctx, call := m.m.start(ctx)
+	cs, err := m.lm.Chat(ctx, req)
+	return m.stream(call, cs, err)
+}
+
+func (m meteredLM) stream(call *meterCall, cs api.CompletionStream, err error) (api.CompletionStream, error) {
+	if err != nil {
+		call.end(err)
+		return nil, err
+	}
+	return &meteredStream{CompletionStream: cs, call: call}, nil
+}
+
+type meteredStream struct {
+	api.CompletionStream
+	call *meterCall
+	once sync.Once
+
+	// usage events hold running totals, only the last one counts
+	last  *api.Usage
+	model string
+}
+
+func (s *meteredStream) Recv() (*api.StreamEvent, error) {
+	ev, err := s.CompletionStream.Recv()
+	if err != nil {
+		s.once.Do(func() {
+			if errors.Is(err, io.EOF) {
+				s.end(nil)
+			} else {
+				s.end(err)
+			}
+		})
+		return ev, err
+	}
+
+	if ev.Model != "" {
+		s.model = ev.Model
+	}
+	if ev.Type == api.StreamEventUsage {
+		s.last = ev.Usage
+	}
+	return ev, nil
+}
+
+func (s *meteredStream) end(err error) {
+	rec := usage.Record{Model: s.model}
+	if s.last != nil {
+		rec.InputTokens = s.last.InputTokens
+		rec.OutputTokens = s.last.OutputTokens
+	}
+	s.call.usage.Add(rec)
+	s.call.end(err)
+}
+
+func (s *meteredStream) Close() error {
+	s.once.Do(func() { s.end(nil) })
+	return s.CompletionStream.Close()
+}
+
+type meteredEmbedder struct {
+	e Embedder
+	m meterInfo
+}
+
+func (e meteredEmbedder) EmbedQuery(ctx context.Context, q string) ([]float32, error) {
+	ctx, call := e.m.start(ctx)
+	v, err := e.e.EmbedQuery(ctx, q)
+	call.end(err)
+	return v, err
+}
+
+func (e meteredEmbedder) EmbedDocuments(ctx context.Context, docs []*api.EmbedDocumentRequest) ([]*api.DocumentEmbedding, error) {
+	ctx, call := e.m.start(ctx)
+	embeddings, err := e.e.EmbedDocuments(ctx, docs)
+	call.end(err)
+	return embeddings, err
+}
+
+func (e meteredEmbedder) GetDimensions() uint {
+	return e.e.GetDimensions()
+}
+
+type meteredReranker struct {
+	r Reranker
+	m meterInfo
+}
+
+func (r meteredReranker) Rerank(ctx context.Context, req api.RerankRequest) (*api.RerankResponse, error) {
+	ctx, call := r.m.start(ctx)
+	resp, err := r.r.Rerank(ctx, req)
+	call.end(err)
+	return resp, err
+}
+
+type meteredDocParser struct {
+	p DocParser
+	m meterInfo
+}
+
+func (p meteredDocParser) Parse(ctx context.Context, base64file string) (*api.DocumentContent, error) {
+	ctx, call := p.m.start(ctx)
+	doc, err := p.p.Parse(ctx, base64file)
+	call.end(err)
+	return doc, err
+}
+
+type meteredSegmenter struct {
+	s Segmenter
+	m meterInfo
+}
+
+func (s meteredSegmenter) ChunkDocument(ctx context.Context, doc *api.DocumentContent) ([]string, error) {
+	ctx, call := s.m.start(ctx)
+	chunks, err := s.s.ChunkDocument(ctx, doc)
+	call.end(err)
+	return chunks, err
+}
+
+type meteredWebSearcher struct {
+	s WebSearcher
+	m meterInfo
+}
+
+func (s meteredWebSearcher) Search(ctx context.Context, req api.WebSearchRequest) (*api.WebSearchResponse, error) {
+	ctx, call := s.m.start(ctx)
+	resp, err := s.s.Search(ctx, req)
+	call.end(err)
+	return resp, err
+}
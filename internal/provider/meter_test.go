@@ -0,0 +1,127 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/usage"
+)
+
+// eventsLM streams the given events, followed by err.
+type eventsLM struct {
+	events []*api.StreamEvent
+	err    error
+}
+
+func (lm eventsLM) Generate(ctx context.Context, req api.GenerationRequest) (api.CompletionStream, error) {
+	return &eventsStream{events: slices.Clone(lm.events), err: lm.err}, nil
+}
+
+func (lm eventsLM) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
+	return lm.Generate(ctx, api.GenerationRequest{})
+}
+
+type eventsStream struct {
+	events []*api.StreamEvent
+	err    error
+}
+
+func (s *eventsStream) Recv() (*api.StreamEvent, error) {
+	if len(s.events) == 0 {
+		return nil, s.err
+	}
+	ev := s.events[0]
+	s.events = s.events[1:]
+	return ev, nil
+}
+
+func (s *eventsStream) Close() error {
+	return nil
+}
+
+func TestMeteredLMTracksUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		lm   eventsLM
+		recv int
+		want usage.Record
+	}{
+		{
+			name: "last usage event counts",
+			lm: eventsLM{events: []*api.StreamEvent{
+				api.ContentEvent("a"),
+				api.UsageEvent(api.Usage{InputTokens: 10, OutputTokens: 1}, ""),
+				api.UsageEvent(api.Usage{InputTokens: 10, OutputTokens: 5}, "gpt-2025"),
+			}, err: io.EOF},
+			recv: 4,
+			want: usage.Record{Provider: "openai", Model: "gpt-2025", Capability: "lm", Calls: 1, InputTokens: 10, OutputTokens: 5},
+		},
+		{
+			name: "failed stream",
+			lm: eventsLM{events: []*api.StreamEvent{
+				api.UsageEvent(api.Usage{InputTokens: 7}, ""),
+			}, err: errors.New("connection reset")},
+			recv: 2,
+			want: usage.Record{Provider: "openai", Model: "gpt", Capability: "lm", Calls: 1, InputTokens: 7},
+		},
+		{
+			name: "stream closed early",
+			lm: eventsLM{events: []*api.StreamEvent{
+				api.UsageEvent(api.Usage{InputTokens: 3, OutputTokens: 2}, ""),
+				api.ContentEvent("a"),
+			}, err: io.EOF},
+			recv: 1,
+			want: usage.Record{Provider: "openai", Model: "gpt", Capability: "lm", Calls: 1, InputTokens: 3, OutputTokens: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := usage.NewTracker()
+			ctx := usage.WithTracker(context.Background(), tracker)
+			lm := meter(CapabilityLM, "openai/gpt", tt.lm).(LM)
+
+			cs, err := lm.Chat(ctx, api.ChatRequest{})
+			if err != nil {
+				t.Fatalf("failed to start chat: %v", err)
+			}
+			for range tt.recv {
+				if _, err := cs.Recv(); err != nil {
+					break
+				}
+			}
+			if err := cs.Close(); err != nil {
+				t.Fatalf("failed to close stream: %v", err)
+			}
+
+			if got := tracker.Records(); !slices.Equal(got, usage.Records{tt.want}) {
+				t.Errorf("records = %+v, want %+v", got, usage.Records{tt.want})
+			}
+		})
+	}
+}
@@ -26,9 +26,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/alan-mat/awe/internal/api"
 	"github.com/alan-mat/awe/internal/http"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/usage"
 )
 
 const (
@@ -55,16 +58,18 @@ type OCRResponse struct {
 
 type MistralProvider struct {
 	client http.Client
+	opts   options.Options
 }
 
-func New() *MistralProvider {
+func New(opts ...options.Option) *MistralProvider {
+	o := options.New(opts...)
 	c := http.NewClient(
-		Endpoint,
-		http.WithMaxRetries(3),
-		http.WithApiKey(os.Getenv("MISTRAL_API_KEY")),
+		o.BaseURLOr(Endpoint),
+		o.HTTPClientOptions(os.Getenv("MISTRAL_API_KEY"), 60*time.Second, 3)...,
 	)
 	p := &MistralProvider{
 		client: c,
+		opts:   o,
 	}
 	return p
 }
@@ -76,7 +81,7 @@ func (p MistralProvider) Parse(ctx context.Context, base64file string) (*api.Doc
 	}
 
 	requestData := map[string]any{
-		"model":    "mistral-ocr-latest",
+		"model":    p.opts.ModelOr("mistral-ocr-latest"),
 		"document": documentUrl,
 	}
 
@@ -95,6 +100,7 @@ func (p MistralProvider) Parse(ctx context.Context, base64file string) (*api.Doc
 	if err != nil {
 		return nil, err
 	}
+	usage.Report(ctx, usage.Record{Model: ocrResponse.Model, Units: ocrResponse.UsageInfo.PagesProcessed})
 
 	doc := &api.DocumentContent{
 		Pages: make([]api.DocumentPage, 0, len(ocrResponse.Pages)),
@@ -23,13 +23,17 @@ package ollama
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/alan-mat/awe/internal/api"
 	"github.com/alan-mat/awe/internal/http"
+	"github.com/alan-mat/awe/internal/provider/options"
 )
 
 const (
@@ -42,26 +46,54 @@ type OllamaProvider struct {
 }
 
 type chatMsgPayload struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string            `json:"role"`
+	Content   string            `json:"content"`
+	ToolCalls []toolCallPayload `json:"tool_calls,omitempty"`
+	ToolName  string            `json:"tool_name,omitempty"`
+}
+
+type toolCallPayload struct {
+	Function toolCallFunction `json:"function"`
+}
+
+type toolCallFunction struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+type toolPayload struct {
+	Type     string              `json:"type"`
+	Function toolFunctionPayload `json:"function"`
+}
+
+type toolFunctionPayload struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  *api.Schema `json:"parameters,omitempty"`
 }
 
 type streamResponse struct {
-	Model     string         `json:"model"`
-	CreatedAt string         `json:"created_at"`
-	Message   chatMsgPayload `json:"message"`
-	Response  string         `json:"response"`
-	Done      bool           `json:"done"`
+	Model      string         `json:"model"`
+	CreatedAt  string         `json:"created_at"`
+	Message    chatMsgPayload `json:"message"`
+	Response   string         `json:"response"`
+	Done       bool           `json:"done"`
+	DoneReason string         `json:"done_reason"`
+	Error      string         `json:"error"`
+
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
 }
 
-func New() *OllamaProvider {
+func New(opts ...options.Option) *OllamaProvider {
+	o := options.New(opts...)
 	c := http.NewClient(
-		Endpoint,
-		http.WithMaxRetries(3),
+		o.BaseURLOr(Endpoint),
+		o.HTTPClientOptions("", 60*time.Second, 3)...,
 	)
 	p := &OllamaProvider{
 		client:       c,
-		defaultModel: "gemma3:4b",
+		defaultModel: o.ModelOr("gemma3:4b"),
 	}
 	return p
 }
@@ -91,7 +123,7 @@ func (p OllamaProvider) Generate(ctx context.Context, req api.GenerationRequest)
 }
 
 func (p OllamaProvider) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
-	if req.Query == "" {
+	if req.Query == "" && len(req.History) == 0 {
 		return nil, fmt.Errorf("completion request failed: missing parameter 'query' in request")
 	}
 
@@ -111,22 +143,46 @@ func (p OllamaProvider) Chat(ctx context.Context, req api.ChatRequest) (api.Comp
 	}
 
 	for _, cm := range req.History {
+		msg := chatMsgPayload{
+			Role:     cm.Role.String(),
+			Content:  cm.Content,
+			ToolName: cm.ToolName,
+		}
+		for _, tc := range cm.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, toolCallPayload{
+				Function: toolCallFunction{Name: tc.Name, Arguments: tc.Arguments},
+			})
+		}
+		messages = append(messages, msg)
+	}
+
+	if req.Query != "" {
 		messages = append(messages, chatMsgPayload{
-			Role:    cm.Role.String(),
-			Content: cm.Content,
+			Role:    "user",
+			Content: req.Query,
 		})
 	}
 
-	messages = append(messages, chatMsgPayload{
-		Role:    "user",
-		Content: req.Query,
-	})
-
 	requestData := map[string]any{
 		"model":    model,
 		"messages": messages,
 	}
 
+	if len(req.Tools) > 0 {
+		tools := make([]toolPayload, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			tools = append(tools, toolPayload{
+				Type: "function",
+				Function: toolFunctionPayload{
+					Name:        t.Name,
+					Description: t.Description,
+					Parameters:  t.Parameters,
+				},
+			})
+		}
+		requestData["tools"] = tools
+	}
+
 	respBody, err := p.client.RequestStream(http.MethodPost, "/api/chat", requestData)
 	if err != nil {
 		return nil, fmt.Errorf("completion request failed: %w", err)
@@ -136,9 +192,13 @@ func (p OllamaProvider) Chat(ctx context.Context, req api.ChatRequest) (api.Comp
 }
 
 type OllamaCompletionStream struct {
-	body   io.ReadCloser
-	reader *bufio.Reader
-	chat   bool
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	chat      bool
+	events    api.StreamEventQueue
+	toolCalls int
+	// done is set once the server sent the final response
+	done bool
 }
 
 func NewOllamaCompletionStream(body io.ReadCloser, chat bool) *OllamaCompletionStream {
@@ -151,28 +211,76 @@ func NewOllamaCompletionStream(body io.ReadCloser, chat bool) *OllamaCompletionS
 	return s
 }
 
-func (s OllamaCompletionStream) Recv() (string, error) {
-	line, err := s.reader.ReadBytes('\n')
-	if err != nil {
-		return "", err
-	}
+func (s *OllamaCompletionStream) Recv() (*api.StreamEvent, error) {
+	for {
+		if ev, ok := s.events.Pop(); ok {
+			return ev, nil
+		}
+		if s.done {
+			return nil, io.EOF
+		}
 
-	var response streamResponse
-	err = json.Unmarshal(line, &response)
-	if err != nil {
-		return "", fmt.Errorf("failed to deserialize chat stream response: %w", err)
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil && len(bytes.TrimSpace(line)) == 0 {
+			if errors.Is(err, io.EOF) {
+				// the connection was closed before the final response
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		var response streamResponse
+		err = json.Unmarshal(line, &response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deserialize chat stream response: %w", err)
+		}
+
+		if response.Error != "" {
+			return nil, &api.StreamError{Message: response.Error}
+		}
+
+		s.receive(response)
 	}
+}
 
-	var out string
+func (s *OllamaCompletionStream) receive(response streamResponse) {
+	var content string
 	if s.chat {
-		out = response.Message.Content
+		content = response.Message.Content
+		// ollama sends tool calls whole rather than as deltas
+		for _, tc := range response.Message.ToolCalls {
+			s.events.Push(api.ToolCallEvent(&api.ToolCall{
+				ID:        fmt.Sprintf("call_%d", s.toolCalls),
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			}))
+			s.toolCalls += 1
+		}
 	} else {
-		out = response.Response
+		content = response.Response
 	}
 
-	return out, nil
+	if content != "" {
+		s.events.Push(api.ContentEvent(content))
+	}
+
+	if response.Done {
+		s.done = true
+		reason := api.ParseFinishReason(response.DoneReason)
+		if s.toolCalls > 0 {
+			// ollama reports 'stop' after tool calls
+			reason = api.FinishReasonToolCalls
+		}
+		s.events.Push(
+			api.UsageEvent(api.Usage{
+				InputTokens:  response.PromptEvalCount,
+				OutputTokens: response.EvalCount,
+			}, response.Model),
+			api.FinishEvent(reason, response.Model),
+		)
+	}
 }
 
-func (s OllamaCompletionStream) Close() error {
+func (s *OllamaCompletionStream) Close() error {
 	return s.body.Close()
 }
@@ -23,10 +23,15 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/usage"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -35,19 +40,24 @@ const embedMaxDocsLength = 2048
 type OpenAIProvider struct {
 	client     *openai.Client
 	vectorDims int
+	opts       options.Options
 }
 
-func New() *OpenAIProvider {
-	c := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+func New(opts ...options.Option) *OpenAIProvider {
+	o := options.New(opts...)
+	config := openai.DefaultConfig(o.APIKeyOr(os.Getenv("OPENAI_API_KEY")))
+	config.BaseURL = o.BaseURLOr(config.BaseURL)
+	config.HTTPClient = o.HTTPClient(0, 1)
 	return &OpenAIProvider{
-		client:     c,
-		vectorDims: 1024,
+		client:     openai.NewClientWithConfig(config),
+		vectorDims: int(o.DimensionsOr(1024)),
+		opts:       o,
 	}
 }
 
 func (p OpenAIProvider) Generate(ctx context.Context, req api.GenerationRequest) (api.CompletionStream, error) {
 	openaiReq := openai.ChatCompletionRequest{
-		Model:       openai.O4Mini,
+		Model:       p.opts.ModelOr(openai.O4Mini),
 		Temperature: req.Temperature,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -55,7 +65,8 @@ func (p OpenAIProvider) Generate(ctx context.Context, req api.GenerationRequest)
 				Content: req.Prompt,
 			},
 		},
-		Stream: true,
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
 
 	if req.ModelName != "" {
@@ -95,15 +106,23 @@ func (p OpenAIProvider) Chat(ctx context.Context, req api.ChatRequest) (api.Comp
 		messages = append(messages, msgHistory...)
 	}
 
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: req.Query,
-	})
+	if req.Query != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: req.Query,
+		})
+	}
 
 	openaiReq := openai.ChatCompletionRequest{
-		Model:    openai.O4Mini,
-		Messages: messages,
-		Stream:   true,
+		Model:         p.opts.ModelOr(openai.O4Mini),
+		Messages:      messages,
+		Tools:         p.parseTools(req.Tools),
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+
+	if req.ModelName != "" {
+		openaiReq.Model = req.ModelName
 	}
 
 	s, err := p.client.CreateChatCompletionStream(ctx, openaiReq)
@@ -120,7 +139,7 @@ func (p OpenAIProvider) Chat(ctx context.Context, req api.ChatRequest) (api.Comp
 func (p OpenAIProvider) EmbedQuery(ctx context.Context, q string) ([]float32, error) {
 	openaiReq := &openai.EmbeddingRequestStrings{
 		Input:          []string{q},
-		Model:          "text-embedding-3-small",
+		Model:          openai.EmbeddingModel(p.opts.ModelOr(string(openai.SmallEmbedding3))),
 		EncodingFormat: "float",
 		Dimensions:     p.vectorDims,
 	}
@@ -129,6 +148,7 @@ func (p OpenAIProvider) EmbedQuery(ctx context.Context, q string) ([]float32, er
 	if err != nil {
 		return nil, err
 	}
+	usage.Report(ctx, usage.Record{Model: string(res.Model), InputTokens: res.Usage.PromptTokens})
 
 	return res.Data[0].Embedding, nil
 }
@@ -143,7 +163,7 @@ func (p OpenAIProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 
 		openaiReq := &openai.EmbeddingRequestStrings{
 			Input:          doc.Chunks,
-			Model:          "text-embedding-3-small",
+			Model:          openai.EmbeddingModel(p.opts.ModelOr(string(openai.SmallEmbedding3))),
 			EncodingFormat: "float",
 			Dimensions:     p.vectorDims,
 		}
@@ -152,6 +172,7 @@ func (p OpenAIProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDoc
 		if err != nil {
 			return nil, fmt.Errorf("failed to create embeddings for document '%s': %w", doc.Title, err)
 		}
+		usage.Report(ctx, usage.Record{Model: string(res.Model), InputTokens: res.Usage.PromptTokens})
 
 		vals := make([][]float32, 0, len(res.Data))
 		for _, e := range res.Data {
@@ -176,14 +197,45 @@ func (p OpenAIProvider) parseRequestHistory(h []*api.ChatMessage) []openai.ChatC
 	msgs := make([]openai.ChatCompletionMessage, len(h))
 	for i, m := range h {
 		ccm := openai.ChatCompletionMessage{
-			Role:    m.Role.String(),
-			Content: m.Content,
+			Role:       m.Role.String(),
+			Content:    m.Content,
+			ToolCallID: m.ToolCallID,
+		}
+		for _, tc := range m.ToolCalls {
+			args, _ := json.Marshal(tc.Arguments)
+			ccm.ToolCalls = append(ccm.ToolCalls, openai.ToolCall{
+				ID:   tc.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      tc.Name,
+					Arguments: string(args),
+				},
+			})
 		}
 		msgs[i] = ccm
 	}
 	return msgs
 }
 
+func (p OpenAIProvider) parseTools(tools []*api.Tool) []openai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	parsed := make([]openai.Tool, 0, len(tools))
+	for _, t := range tools {
+		parsed = append(parsed, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+	return parsed
+}
+
 func (p OpenAIProvider) parseResponseSchema(s *api.Schema) *openai.ChatCompletionResponseFormatJSONSchema {
 	schema := &openai.ChatCompletionResponseFormatJSONSchema{
 		Name:   s.Title,
@@ -210,18 +262,80 @@ func (s OpenAIGenerationStream) Close() error {
 } */
 
 type OpenAIChatStream struct {
-	stream *openai.ChatCompletionStream
+	stream    *openai.ChatCompletionStream
+	events    api.StreamEventQueue
+	toolCalls api.ToolCallAccumulator
 }
 
-func (s OpenAIChatStream) Recv() (string, error) {
-	res, err := s.stream.Recv()
-	if err != nil {
-		return "", err
+func (s *OpenAIChatStream) Recv() (*api.StreamEvent, error) {
+	for {
+		if ev, ok := s.events.Pop(); ok {
+			return ev, nil
+		}
+
+		res, err := s.stream.Recv()
+		if errors.Is(err, io.EOF) && s.toolCalls.Len() > 0 {
+			// the stream ended without a finish reason
+			if err := s.flushToolCalls(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.receive(res); err != nil {
+			return nil, err
+		}
 	}
+}
+
+func (s *OpenAIChatStream) receive(res openai.ChatCompletionStreamResponse) error {
+	// the last chunk only carries usage, without any choices
+	if len(res.Choices) > 0 {
+		choice := res.Choices[0]
+		if choice.Delta.Content != "" {
+			s.events.Push(api.ContentEvent(choice.Delta.Content))
+		}
+
+		for i, tc := range choice.Delta.ToolCalls {
+			index := i
+			if tc.Index != nil {
+				index = *tc.Index
+			}
+			s.toolCalls.Add(index, tc.ID, tc.Function.Name, tc.Function.Arguments)
+		}
 
-	return res.Choices[0].Delta.Content, nil
+		if choice.FinishReason != "" && choice.FinishReason != openai.FinishReasonNull {
+			if err := s.flushToolCalls(); err != nil {
+				return err
+			}
+			s.events.Push(api.FinishEvent(api.ParseFinishReason(string(choice.FinishReason)), res.Model))
+		}
+	}
+
+	if res.Usage != nil {
+		s.events.Push(api.UsageEvent(api.Usage{
+			InputTokens:  res.Usage.PromptTokens,
+			OutputTokens: res.Usage.CompletionTokens,
+			TotalTokens:  res.Usage.TotalTokens,
+		}, res.Model))
+	}
+	return nil
+}
+
+func (s *OpenAIChatStream) flushToolCalls() error {
+	calls, err := s.toolCalls.Flush()
+	if err != nil {
+		return err
+	}
+	for _, tc := range calls {
+		s.events.Push(api.ToolCallEvent(tc))
+	}
+	return nil
 }
 
-func (s OpenAIChatStream) Close() error {
+func (s *OpenAIChatStream) Close() error {
 	return s.stream.Close()
 }
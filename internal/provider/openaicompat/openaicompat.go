@@ -0,0 +1,468 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package openaicompat implements a provider for servers exposing the
+// OpenAI chat completions and embeddings API, e.g. vLLM, llama.cpp server,
+// LiteLLM or LocalAI. The base URL includes the API version, e.g. 'http://localhost:8000/v1'.
+package openaicompat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/http"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/usage"
+)
+
+const (
+	Endpoint           = "http://localhost:8000/v1"
+	embedMaxDocsLength = 2048
+)
+
+var ErrMissingModel = errors.New("no model configured")
+
+type chatMsgPayload struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	ToolCalls  []toolCallPayload `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+}
+
+type toolCallPayload struct {
+	Index    int    `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+type toolPayload struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description,omitempty"`
+		Parameters  *api.Schema `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type streamResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string            `json:"content"`
+			ToolCalls []toolCallPayload `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type embeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type embeddingResponse struct {
+	Model string          `json:"model"`
+	Data  []embeddingData `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage"`
+}
+
+type OpenAICompatProvider struct {
+	client http.Client
+	opts   options.Options
+
+	// dims holds the vector size detected from the embedding model,
+	// if none is configured
+	dims *detectedDims
+}
+
+type detectedDims struct {
+	once sync.Once
+	n    uint
+}
+
+func New(opts ...options.Option) *OpenAICompatProvider {
+	o := options.New(opts...)
+	c := http.NewClient(
+		o.BaseURLOr(Endpoint),
+		o.HTTPClientOptions(os.Getenv("OPENAICOMPAT_API_KEY"), 120*time.Second, 3)...,
+	)
+	return &OpenAICompatProvider{
+		client: c,
+		opts:   o,
+		dims:   &detectedDims{},
+	}
+}
+
+func (p OpenAICompatProvider) Generate(ctx context.Context, req api.GenerationRequest) (api.CompletionStream, error) {
+	requestData := map[string]any{
+		"messages": []chatMsgPayload{
+			{
+				Role:    "user",
+				Content: req.Prompt,
+			},
+		},
+		"temperature": req.Temperature,
+	}
+
+	if req.ResponseSchema != nil {
+		name := req.ResponseSchema.Title
+		if name == "" {
+			name = "response"
+		}
+		requestData["response_format"] = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   name,
+				"schema": req.ResponseSchema,
+			},
+		}
+	}
+
+	return p.chatCompletion(ctx, req.ModelName, requestData)
+}
+
+func (p OpenAICompatProvider) Chat(ctx context.Context, req api.ChatRequest) (api.CompletionStream, error) {
+	if req.Query == "" && len(req.History) == 0 {
+		return nil, fmt.Errorf("completion request failed: missing parameter 'query' in request")
+	}
+
+	messages := make([]chatMsgPayload, 0, len(req.History)+2)
+	if req.SystemPrompt != "" {
+		messages = append(messages, chatMsgPayload{
+			Role:    "system",
+			Content: req.SystemPrompt,
+		})
+	}
+
+	for _, cm := range req.History {
+		msg := chatMsgPayload{
+			Role:       cm.Role.String(),
+			Content:    cm.Content,
+			ToolCallID: cm.ToolCallID,
+		}
+		for _, tc := range cm.ToolCalls {
+			args, err := json.Marshal(tc.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("completion request failed: invalid arguments for tool call '%s': %w", tc.ID, err)
+			}
+			call := toolCallPayload{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = string(args)
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		messages = append(messages, msg)
+	}
+
+	if req.Query != "" {
+		messages = append(messages, chatMsgPayload{
+			Role:    "user",
+			Content: req.Query,
+		})
+	}
+
+	requestData := map[string]any{
+		"messages": messages,
+	}
+
+	if len(req.Tools) > 0 {
+		tools := make([]toolPayload, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			tool := toolPayload{Type: "function"}
+			tool.Function.Name = t.Name
+			tool.Function.Description = t.Description
+			tool.Function.Parameters = t.Parameters
+			tools = append(tools, tool)
+		}
+		requestData["tools"] = tools
+	}
+
+	return p.chatCompletion(ctx, req.ModelName, requestData)
+}
+
+func (p OpenAICompatProvider) chatCompletion(ctx context.Context, model string, requestData map[string]any) (api.CompletionStream, error) {
+	if model == "" {
+		model = p.opts.Model
+	}
+	if model == "" {
+		return nil, fmt.Errorf("completion request failed: %w", ErrMissingModel)
+	}
+	requestData["model"] = model
+	requestData["stream"] = true
+	requestData["stream_options"] = map[string]any{"include_usage": true}
+
+	respBody, err := p.client.RequestStreamWithContext(ctx, http.MethodPost, "/chat/completions", requestData)
+	if err != nil {
+		return nil, fmt.Errorf("completion request failed: %w", err)
+	}
+
+	return NewOpenAICompatCompletionStream(respBody), nil
+}
+
+func (p OpenAICompatProvider) EmbedQuery(ctx context.Context, q string) ([]float32, error) {
+	vals, err := p.embed(ctx, []string{q})
+	if err != nil {
+		return nil, err
+	}
+	return vals[0], nil
+}
+
+func (p OpenAICompatProvider) EmbedDocuments(ctx context.Context, docs []*api.EmbedDocumentRequest) ([]*api.DocumentEmbedding, error) {
+	docEmbeddings := make([]*api.DocumentEmbedding, 0, len(docs))
+
+	for _, doc := range docs {
+		if len(doc.Chunks) > embedMaxDocsLength {
+			return nil, fmt.Errorf("length of chunks exceeds limit: accepts '%d', received '%d'", embedMaxDocsLength, len(doc.Chunks))
+		}
+
+		vals, err := p.embed(ctx, doc.Chunks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embeddings for document '%s': %w", doc.Title, err)
+		}
+
+		docEmbeddings = append(docEmbeddings, &api.DocumentEmbedding{
+			Title:  doc.Title,
+			Chunks: doc.Chunks,
+			Values: vals,
+		})
+	}
+
+	return docEmbeddings, nil
+}
+
+// GetDimensions returns the configured vector size. If none is configured,
+// the size is detected by embedding a probe text on first use.
+func (p OpenAICompatProvider) GetDimensions() uint {
+	if p.opts.Dimensions != 0 {
+		return p.opts.Dimensions
+	}
+
+	p.dims.once.Do(func() {
+		vals, err := p.EmbedQuery(context.Background(), "dimensions")
+		if err != nil {
+			slog.Error("failed to detect embedding dimensions", "model", p.opts.Model, "err", err)
+			return
+		}
+		p.dims.n = uint(len(vals))
+	})
+	return p.dims.n
+}
+
+func (p OpenAICompatProvider) embed(ctx context.Context, input []string) ([][]float32, error) {
+	if p.opts.Model == "" {
+		return nil, fmt.Errorf("embedding request failed: %w", ErrMissingModel)
+	}
+
+	requestData := map[string]any{
+		"model":           p.opts.Model,
+		"input":           input,
+		"encoding_format": "float",
+	}
+	if p.opts.Dimensions != 0 {
+		requestData["dimensions"] = p.opts.Dimensions
+	}
+
+	resp, err := p.client.RequestWithContext(ctx, http.MethodPost, "/embeddings", requestData)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+
+	jsonData, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var embResponse embeddingResponse
+	if err := json.Unmarshal(jsonData, &embResponse); err != nil {
+		return nil, err
+	}
+	usage.Report(ctx, usage.Record{Model: embResponse.Model, InputTokens: embResponse.Usage.PromptTokens})
+
+	if len(embResponse.Data) != len(input) {
+		return nil, fmt.Errorf("embedding request failed: expected '%d' embeddings, received '%d'", len(input), len(embResponse.Data))
+	}
+
+	slices.SortFunc(embResponse.Data, func(a, b embeddingData) int {
+		return a.Index - b.Index
+	})
+
+	vals := make([][]float32, 0, len(embResponse.Data))
+	for _, e := range embResponse.Data {
+		vals = append(vals, e.Embedding)
+	}
+	return vals, nil
+}
+
+// OpenAICompatCompletionStream reads the server-sent events
+// of a streamed chat completion.
+type OpenAICompatCompletionStream struct {
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	events    api.StreamEventQueue
+	toolCalls api.ToolCallAccumulator
+	// done is set once the server ended the stream with '[DONE]'
+	done bool
+}
+
+func NewOpenAICompatCompletionStream(body io.ReadCloser) *OpenAICompatCompletionStream {
+	return &OpenAICompatCompletionStream{
+		body:   body,
+		reader: bufio.NewReader(body),
+	}
+}
+
+func (s *OpenAICompatCompletionStream) Recv() (*api.StreamEvent, error) {
+	for {
+		if ev, ok := s.events.Pop(); ok {
+			return ev, nil
+		}
+
+		data, err := s.next()
+		if errors.Is(err, io.EOF) && s.toolCalls.Len() > 0 {
+			// the stream ended without a finish reason
+			if err := s.flushToolCalls(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var response streamResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, fmt.Errorf("failed to deserialize chat stream response: %w", err)
+		}
+
+		if err := s.receive(response); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// next returns the data of the next event of the stream. It returns io.EOF
+// once the server sent '[DONE]', and io.ErrUnexpectedEOF if the connection
+// was closed before, so that truncated completions are not taken as complete.
+func (s *OpenAICompatCompletionStream) next() ([]byte, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil && len(line) == 0 {
+			return nil, err
+		}
+
+		// skip keep-alives, comments and other event fields
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		data = bytes.TrimSpace(data)
+		if bytes.Equal(data, []byte("[DONE]")) {
+			s.done = true
+			return nil, io.EOF
+		}
+		return data, nil
+	}
+}
+
+func (s *OpenAICompatCompletionStream) receive(response streamResponse) error {
+	if response.Error != nil {
+		return &api.StreamError{Code: response.Error.Type, Message: response.Error.Message}
+	}
+
+	// chunks without choices only carry metadata, e.g. usage
+	if len(response.Choices) > 0 {
+		choice := response.Choices[0]
+		if choice.Delta.Content != "" {
+			s.events.Push(api.ContentEvent(choice.Delta.Content))
+		}
+
+		for _, tc := range choice.Delta.ToolCalls {
+			s.toolCalls.Add(tc.Index, tc.ID, tc.Function.Name, tc.Function.Arguments)
+		}
+
+		if choice.FinishReason != "" {
+			if err := s.flushToolCalls(); err != nil {
+				return err
+			}
+			s.events.Push(api.FinishEvent(api.ParseFinishReason(choice.FinishReason), response.Model))
+		}
+	}
+
+	if u := response.Usage; u != nil {
+		s.events.Push(api.UsageEvent(api.Usage{
+			InputTokens:  u.PromptTokens,
+			OutputTokens: u.CompletionTokens,
+			TotalTokens:  u.TotalTokens,
+		}, response.Model))
+	}
+	return nil
+}
+
+func (s *OpenAICompatCompletionStream) flushToolCalls() error {
+	calls, err := s.toolCalls.Flush()
+	if err != nil {
+		return err
+	}
+	for _, tc := range calls {
+		s.events.Push(api.ToolCallEvent(tc))
+	}
+	return nil
+}
+
+func (s *OpenAICompatCompletionStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package openaicompat
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readStream(body string) (string, error) {
+	s := NewOpenAICompatCompletionStream(io.NopCloser(strings.NewReader(body)))
+	var content string
+	for {
+		ev, err := s.Recv()
+		if err != nil {
+			return content, err
+		}
+		content += ev.Content
+	}
+}
+
+func TestCompletionStreamEnd(t *testing.T) {
+	chunk := `data: {"choices":[{"delta":{"content":"Hello"}}]}` + "\n\n"
+
+	content, err := readStream(chunk + "data: [DONE]\n\n")
+	if !errors.Is(err, io.EOF) || content != "Hello" {
+		t.Errorf("complete stream: content = %q, err = %v", content, err)
+	}
+
+	content, err = readStream(chunk)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || content != "Hello" {
+		t.Errorf("truncated stream: content = %q, err = %v, want io.ErrUnexpectedEOF", content, err)
+	}
+}
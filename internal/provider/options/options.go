@@ -0,0 +1,184 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package options holds the options shared by all provider implementations.
+package options
+
+import (
+	gohttp "net/http"
+	"time"
+
+	"github.com/alan-mat/awe/internal/http"
+)
+
+// Options configures a provider instance.
+// Unset options keep the defaults of the provider.
+type Options struct {
+	// Model replaces the default model of the provider. It applies to
+	// whichever capability the instance is used for, e.g. the chat model
+	// of an LM or the embedding model of an Embedder.
+	Model string
+
+	// BaseURL replaces the API endpoint of the provider.
+	BaseURL string
+
+	// APIKey replaces the key read from the provider's environment variable.
+	APIKey string
+
+	// Timeout limits how long a request waits for the response headers.
+	// Reading the response, e.g. a streamed completion, is not limited.
+	Timeout time.Duration
+
+	// MaxRetries is the maximum amount of attempts of a request failing
+	// with a retryable status code, nil if not set.
+	MaxRetries *int
+
+	// Concurrency limits the amount of requests in flight
+	// across all users of the instance, 0 means no limit.
+	Concurrency int
+
+	// Dimensions sets the size of the vectors returned by an Embedder,
+	// for providers supporting multiple sizes or unknown models.
+	Dimensions uint
+}
+
+type Option func(*Options)
+
+func WithModel(model string) Option {
+	return func(o *Options) {
+		o.Model = model
+	}
+}
+
+func WithBaseURL(url string) Option {
+	return func(o *Options) {
+		o.BaseURL = url
+	}
+}
+
+func WithAPIKey(key string) Option {
+	return func(o *Options) {
+		o.APIKey = key
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+func WithMaxRetries(maxRetries int) Option {
+	return func(o *Options) {
+		o.MaxRetries = &maxRetries
+	}
+}
+
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		o.Concurrency = n
+	}
+}
+
+func WithDimensions(dims uint) Option {
+	return func(o *Options) {
+		o.Dimensions = dims
+	}
+}
+
+// New returns the Options resulting from applying opts.
+func New(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ModelOr returns the configured model, or def if none is configured.
+func (o Options) ModelOr(def string) string {
+	if o.Model != "" {
+		return o.Model
+	}
+	return def
+}
+
+// BaseURLOr returns the configured base URL, or def if none is configured.
+func (o Options) BaseURLOr(def string) string {
+	if o.BaseURL != "" {
+		return o.BaseURL
+	}
+	return def
+}
+
+// APIKeyOr returns the configured API key, or def if none is configured.
+func (o Options) APIKeyOr(def string) string {
+	if o.APIKey != "" {
+		return o.APIKey
+	}
+	return def
+}
+
+// TimeoutOr returns the configured timeout, or def if none is configured.
+func (o Options) TimeoutOr(def time.Duration) time.Duration {
+	if o.Timeout != 0 {
+		return o.Timeout
+	}
+	return def
+}
+
+// MaxRetriesOr returns the configured amount of attempts, or def if none is configured.
+func (o Options) MaxRetriesOr(def int) int {
+	if o.MaxRetries != nil {
+		return *o.MaxRetries
+	}
+	return def
+}
+
+// DimensionsOr returns the configured vector size, or def if none is configured.
+func (o Options) DimensionsOr(def uint) uint {
+	if o.Dimensions != 0 {
+		return o.Dimensions
+	}
+	return def
+}
+
+// HTTPClient returns a client applying the configured timeout and retries,
+// for providers using a third party SDK. A timeout of 0 means no timeout.
+// Every attempt of a request is traced as a span of its own.
+func (o Options) HTTPClient(timeout time.Duration, maxRetries int) *gohttp.Client {
+	return &gohttp.Client{
+		Transport: http.NewRetryTransport(http.NewTransport(o.TimeoutOr(timeout)), o.MaxRetriesOr(maxRetries)),
+	}
+}
+
+// HTTPClientOptions returns the options of an internal http.Client
+// applying the configured timeout, retries and API key.
+func (o Options) HTTPClientOptions(apiKey string, timeout time.Duration, maxRetries int) []http.ClientOption {
+	opts := []http.ClientOption{
+		http.WithTimeout(o.TimeoutOr(timeout)),
+		http.WithMaxRetries(o.MaxRetriesOr(maxRetries)),
+	}
+	if key := o.APIKeyOr(apiKey); key != "" {
+		opts = append(opts, http.WithApiKey(key))
+	}
+	return opts
+}
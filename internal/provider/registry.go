@@ -0,0 +1,346 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	cohere "github.com/alan-mat/awe/internal/provider/cohere"
+	"github.com/alan-mat/awe/internal/provider/gemini"
+	"github.com/alan-mat/awe/internal/provider/jina"
+	"github.com/alan-mat/awe/internal/provider/mistral"
+	"github.com/alan-mat/awe/internal/provider/ollama"
+	"github.com/alan-mat/awe/internal/provider/openai"
+	"github.com/alan-mat/awe/internal/provider/openaicompat"
+	"github.com/alan-mat/awe/internal/provider/options"
+	"github.com/alan-mat/awe/internal/provider/tavily"
+)
+
+var (
+	ErrUnknownProvider     = errors.New("unknown provider")
+	ErrUnsupportedProvider = errors.New("unsupported provider capability")
+	ErrUnknownCapability   = errors.New("unknown provider capability")
+)
+
+// Capability is a kind of provider used by executors.
+type Capability string
+
+const (
+	CapabilityLM          Capability = "lm"
+	CapabilityEmbedder    Capability = "embedder"
+	CapabilityReranker    Capability = "reranker"
+	CapabilityDocParser   Capability = "parser"
+	CapabilitySegmenter   Capability = "segmenter"
+	CapabilityWebSearcher Capability = "web_searcher"
+)
+
+var capabilities = []Capability{
+	CapabilityLM,
+	CapabilityEmbedder,
+	CapabilityReranker,
+	CapabilityDocParser,
+	CapabilitySegmenter,
+	CapabilityWebSearcher,
+}
+
+// Factory creates a provider instance with the given options.
+type Factory func(opts ...options.Option) any
+
+var factories = map[string]Factory{
+	"openai":  func(opts ...options.Option) any { return openai.New(opts...) },
+	"gemini":  func(opts ...options.Option) any { return gemini.New(opts...) },
+	"cohere":  func(opts ...options.Option) any { return cohere.New(opts...) },
+	"ollama":  func(opts ...options.Option) any { return ollama.New(opts...) },
+	"jina":    func(opts ...options.Option) any { return jina.New(opts...) },
+	"mistral": func(opts ...options.Option) any { return mistral.New(opts...) },
+	"tavily":  func(opts ...options.Option) any { return tavily.New(opts...) },
+
+	"openaicompat": func(opts ...options.Option) any { return openaicompat.New(opts...) },
+}
+
+// Instance configures a named provider instance. Type is the name of
+// the provider implementation, and defaults to the name of the instance,
+// so builtin providers can be configured under their own name.
+type Instance struct {
+	Type    string
+	Options []options.Option
+}
+
+var (
+	instanceLock sync.Mutex
+	instances    = make(map[string]any)
+	configs      = make(map[string]Instance)
+	limiters     = make(map[string]limiter)
+
+	defaultLock sync.RWMutex
+	defaults    = make(map[Capability]string)
+)
+
+// ParseRef splits a provider reference of the form 'name' or 'name/model'.
+// Only the first slash separates the name, so models may contain slashes.
+func ParseRef(ref string) (name, model string) {
+	name, model, _ = strings.Cut(ref, "/")
+	return name, model
+}
+
+// Ref joins a provider name and model into a provider reference.
+func Ref(name, model string) string {
+	if model == "" {
+		return name
+	}
+	return name + "/" + model
+}
+
+// Configure replaces the named provider instances. Instances are referenced
+// by name like builtin providers, and the model of a reference replaces
+// the model configured for the instance.
+func Configure(named map[string]Instance) error {
+	cfgs := make(map[string]Instance, len(named))
+	lims := make(map[string]limiter, len(named))
+	for name, inst := range named {
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("invalid provider instance name '%s'", name)
+		}
+		if inst.Type == "" {
+			inst.Type = name
+		}
+		if factories[inst.Type] == nil {
+			return fmt.Errorf("provider instance '%s': %w '%s'", name, ErrUnknownProvider, inst.Type)
+		}
+		cfgs[name] = inst
+		if l := newLimiter(options.New(inst.Options...).Concurrency); l != nil {
+			lims[name] = l
+		}
+	}
+
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	configs = cfgs
+	limiters = lims
+	instances = make(map[string]any)
+	return nil
+}
+
+// ListProviders returns the names of all available providers,
+// including the configured provider instances.
+func ListProviders() []string {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	names := make([]string, 0, len(factories)+len(configs))
+	for name := range factories {
+		names = append(names, name)
+	}
+	for name := range configs {
+		if factories[name] == nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func exists(name string) bool {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	_, ok := configs[name]
+	return ok || factories[name] != nil
+}
+
+// Get returns the provider instance for ref. Instances are created on first
+// use and shared by all executors referencing the same provider and model.
+func Get(ref string) (any, error) {
+	p, _, err := get(ref)
+	return p, err
+}
+
+func get(ref string) (any, limiter, error) {
+	instanceLock.Lock()
+	defer instanceLock.Unlock()
+
+	name, model := ParseRef(ref)
+	if p, ok := instances[ref]; ok {
+		return p, limiters[name], nil
+	}
+
+	var opts []options.Option
+	factory, ok := factories[name]
+	if inst, named := configs[name]; named {
+		factory = factories[inst.Type]
+		opts = append(opts, inst.Options...)
+	} else if !ok {
+		return nil, nil, fmt.Errorf("%w '%s'", ErrUnknownProvider, name)
+	}
+	if model != "" {
+		opts = append(opts, options.WithModel(model))
+	}
+
+	p := factory(opts...)
+	instances[ref] = p
+	return p, limiters[name], nil
+}
+
+// SetDefaults sets the providers used for each capability when a node
+// does not select a provider, in place of the executor's own default.
+// Defaults may reference provider instances, so Configure must be called first.
+func SetDefaults(refs map[Capability]string) error {
+	for c, ref := range refs {
+		if !slices.Contains(capabilities, c) {
+			return fmt.Errorf("%w '%s'", ErrUnknownCapability, c)
+		}
+		if name, _ := ParseRef(ref); !exists(name) {
+			return fmt.Errorf("default %s: %w '%s'", c, ErrUnknownProvider, name)
+		}
+	}
+
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	defaults = make(map[Capability]string, len(refs))
+	for c, ref := range refs {
+		defaults[c] = ref
+	}
+	return nil
+}
+
+// Default returns the configured default provider of a capability, if any.
+func Default(c Capability) (string, bool) {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+
+	ref, ok := defaults[c]
+	return ref, ok
+}
+
+// SelectionArgs returns the names of the args used by Select, i.e. 'provider'
+// and 'model' for LMs and the names of all other capabilities.
+func SelectionArgs() []string {
+	names := []string{"provider", "model"}
+	for _, c := range capabilities {
+		if c != CapabilityLM {
+			names = append(names, string(c))
+		}
+	}
+	return names
+}
+
+// Select returns the reference of the provider selected for a capability.
+// The provider is taken from the arg named after the capability,
+// then from the configured default, and finally from fallback.
+// For LMs, the arg is 'provider' and the model can be set separately
+// with the 'model' arg.
+func Select(c Capability, args map[string]any, fallback string) string {
+	ref := fallback
+	if d, ok := Default(c); ok {
+		ref = d
+	}
+
+	argName := string(c)
+	if c == CapabilityLM {
+		argName = "provider"
+	}
+	if arg, ok := args[argName].(string); ok && arg != "" {
+		ref = arg
+	}
+
+	if c == CapabilityLM {
+		if model, ok := args["model"].(string); ok && model != "" {
+			name, _ := ParseRef(ref)
+			ref = Ref(name, model)
+		}
+	}
+	return ref
+}
+
+func getAs[T any](c Capability, ref string) (T, error) {
+	p, l, err := get(ref)
+	if err != nil {
+		return *new(T), err
+	}
+
+	if _, ok := p.(T); !ok {
+		name, _ := ParseRef(ref)
+		return *new(T), fmt.Errorf("%w '%s' of provider '%s'", ErrUnsupportedProvider, c, name)
+	}
+	return meter(c, ref, limit(c, p, l)).(T), nil
+}
+
+// Check returns an error if the provider used for capability c by nodes
+// which do not select one, see Select, is unknown or lacks the capability.
+func Check(c Capability, fallback string) error {
+	ref := Select(c, nil, fallback)
+
+	var err error
+	switch c {
+	case CapabilityLM:
+		_, err = getAs[LM](c, ref)
+	case CapabilityEmbedder:
+		_, err = getAs[Embedder](c, ref)
+	case CapabilityReranker:
+		_, err = getAs[Reranker](c, ref)
+	case CapabilityDocParser:
+		_, err = getAs[DocParser](c, ref)
+	case CapabilitySegmenter:
+		_, err = getAs[Segmenter](c, ref)
+	case CapabilityWebSearcher:
+		_, err = getAs[WebSearcher](c, ref)
+	default:
+		err = fmt.Errorf("%w '%s'", ErrUnknownCapability, c)
+	}
+	return err
+}
+
+// SelectLM returns the LM selected by the node args, see Select.
+func SelectLM(args map[string]any, fallback string) (LM, error) {
+	return getAs[LM](CapabilityLM, Select(CapabilityLM, args, fallback))
+}
+
+// SelectEmbedder returns the Embedder selected by the node args, see Select.
+func SelectEmbedder(args map[string]any, fallback string) (Embedder, error) {
+	return getAs[Embedder](CapabilityEmbedder, Select(CapabilityEmbedder, args, fallback))
+}
+
+// SelectReranker returns the Reranker selected by the node args, see Select.
+func SelectReranker(args map[string]any, fallback string) (Reranker, error) {
+	return getAs[Reranker](CapabilityReranker, Select(CapabilityReranker, args, fallback))
+}
+
+// SelectDocParser returns the DocParser selected by the node args, see Select.
+func SelectDocParser(args map[string]any, fallback string) (DocParser, error) {
+	return getAs[DocParser](CapabilityDocParser, Select(CapabilityDocParser, args, fallback))
+}
+
+// SelectSegmenter returns the Segmenter selected by the node args, see Select.
+func SelectSegmenter(args map[string]any, fallback string) (Segmenter, error) {
+	return getAs[Segmenter](CapabilitySegmenter, Select(CapabilitySegmenter, args, fallback))
+}
+
+// SelectWebSearcher returns the WebSearcher selected by the node args, see Select.
+func SelectWebSearcher(args map[string]any, fallback string) (WebSearcher, error) {
+	return getAs[WebSearcher](CapabilityWebSearcher, Select(CapabilityWebSearcher, args, fallback))
+}
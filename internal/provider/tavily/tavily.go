@@ -26,9 +26,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/alan-mat/awe/internal/api"
 	"github.com/alan-mat/awe/internal/http"
+	"github.com/alan-mat/awe/internal/provider/options"
 )
 
 const (
@@ -59,11 +61,13 @@ type TavilyProvider struct {
 	client http.Client
 }
 
-func New() *TavilyProvider {
+// New creates a Tavily provider, which has no models to select.
+// The model option is ignored.
+func New(opts ...options.Option) *TavilyProvider {
+	o := options.New(opts...)
 	c := http.NewClient(
-		Endpoint,
-		http.WithMaxRetries(3),
-		http.WithApiKey(os.Getenv("TAVILY_API_KEY")),
+		o.BaseURLOr(Endpoint),
+		o.HTTPClientOptions(os.Getenv("TAVILY_API_KEY"), 60*time.Second, 3)...,
 	)
 	p := &TavilyProvider{
 		client: c,
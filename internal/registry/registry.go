@@ -22,21 +22,60 @@
 package registry
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/alan-mat/awe/internal/executor"
 )
 
+var (
+	ErrExecutorNotFound    = errors.New("executor does not exist")
+	ErrExecutorUnavailable = errors.New("executor is unavailable")
+)
+
+// ExecutorFactory creates an executor. It is called on first use of the executor,
+// once the providers are configured, and again on later uses if it fails.
+type ExecutorFactory func() (executor.Executor, error)
+
+// ExecutorMeta describes a registered executor without creating it.
+type ExecutorMeta struct {
+	Description string
+
+	// Capabilities lists the kinds of providers used by the executor, e.g. 'lm'.
+	Capabilities []string
+}
+
+// ExecutorStatus reports whether a registered executor could be created.
+type ExecutorStatus struct {
+	Name string
+	Meta ExecutorMeta
+	Err  error
+}
+
+type executorEntry struct {
+	meta    ExecutorMeta
+	factory ExecutorFactory
+
+	// mu guards exec, the factory is called holding it so that it runs once
+	// per entry without blocking the lookup of other executors
+	mu   sync.Mutex
+	exec executor.Executor
+}
+
 var (
 	executorLock sync.RWMutex
-	executors    = make(map[string]executor.Executor)
+	executors    = make(map[string]*executorEntry)
 
 	workflowLock sync.RWMutex
-	workflows    = make(map[string]*executor.Workflow)
+	workflows    = make(map[string]*workflowVersions)
 )
 
+// RegisterExecutor registers an executor which is already created.
 func RegisterExecutor(name string, exec executor.Executor) error {
 	executorLock.Lock()
 	defer executorLock.Unlock()
@@ -44,23 +83,82 @@ func RegisterExecutor(name string, exec executor.Executor) error {
 	if _, exists := executors[name]; exists {
 		return fmt.Errorf("failed to register, executor with name '%s' already exists", name)
 	}
-	slog.Info("registering executor", "name", name)
-	executors[name] = exec
+	slog.Debug("registering executor", "name", name)
+	executors[name] = &executorEntry{exec: exec}
+	registerStateTypes(exec)
 	return nil
 }
 
+// RegisterExecutorFactory registers an executor which is created by factory on first use.
+func RegisterExecutorFactory(name string, meta ExecutorMeta, factory ExecutorFactory) error {
+	executorLock.Lock()
+	defer executorLock.Unlock()
+
+	if _, exists := executors[name]; exists {
+		return fmt.Errorf("failed to register, executor with name '%s' already exists", name)
+	}
+	slog.Debug("registering executor", "name", name)
+	executors[name] = &executorEntry{
+		meta:    meta,
+		factory: factory,
+	}
+	return nil
+}
+
+// GetExecutor returns the executor registered as name, creating it if needed.
+// If the executor can not be created, the error wraps ErrExecutorUnavailable.
 func GetExecutor(name string) (executor.Executor, error) {
 	executorLock.RLock()
-	defer executorLock.RUnlock()
+	entry, exists := executors[name]
+	executorLock.RUnlock()
 
-	exec, exists := executors[name]
 	if !exists {
-		return nil, fmt.Errorf("executor with name '%s' does not exist", name)
+		return nil, fmt.Errorf("%w: '%s'", ErrExecutorNotFound, name)
 	}
 
+	exec, err := entry.create()
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", ErrExecutorUnavailable, name, err)
+	}
 	return exec, nil
 }
 
+// create returns the executor of the entry, calling its factory
+// unless the executor already exists.
+func (e *executorEntry) create() (executor.Executor, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.exec != nil {
+		return e.exec, nil
+	}
+
+	exec, err := e.factory()
+	if err == nil && exec == nil {
+		err = errors.New("factory returned no executor")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.exec = exec
+	registerStateTypes(exec)
+	return exec, nil
+}
+
+// args of described operators may be stored in checkpoints
+func registerStateTypes(exec executor.Executor) {
+	if d, ok := exec.(executor.Describer); ok {
+		for _, op := range d.Spec().Operators {
+			for _, arg := range op.Args {
+				executor.RegisterStateTypeOf(arg.Type)
+			}
+		}
+	}
+}
+
+// ListExecutors returns the names of all registered executors,
+// including those which are unavailable.
 func ListExecutors() []string {
 	executorLock.RLock()
 	defer executorLock.RUnlock()
@@ -69,41 +167,107 @@ func ListExecutors() []string {
 	for name := range executors {
 		names = append(names, name)
 	}
+	slices.Sort(names)
 	return names
 }
 
-func BatchRegisterWorkflows(workflows map[string]*executor.Workflow) error {
-	for name, wf := range workflows {
-		err := RegisterWorkflow(name, wf)
-		if err != nil {
-			return err
+// InitExecutors creates all registered executors which do not exist yet,
+// and returns the status of every executor sorted by name.
+func InitExecutors() []ExecutorStatus {
+	executorLock.RLock()
+	entries := maps.Clone(executors)
+	executorLock.RUnlock()
+
+	statuses := make([]ExecutorStatus, 0, len(entries))
+	for name, entry := range entries {
+		_, err := entry.create()
+		statuses = append(statuses, ExecutorStatus{
+			Name: name,
+			Meta: entry.meta,
+			Err:  err,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b ExecutorStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return statuses
+}
+
+// BatchRegisterWorkflows registers all given workflows at once,
+// either all of them are registered or none.
+func BatchRegisterWorkflows(wfs map[string]*executor.Workflow) error {
+	workflowLock.Lock()
+	defer workflowLock.Unlock()
+
+	all := make([]*executor.Workflow, 0, len(wfs))
+	for _, versions := range workflows {
+		for _, wf := range versions.versions {
+			all = append(all, wf)
 		}
 	}
-	fmt.Println("registered workflows: ", ListWorkflows())
+	for _, wf := range wfs {
+		all = append(all, wf)
+	}
+
+	next, err := indexWorkflows(all)
+	if err != nil {
+		return err
+	}
+	for _, wf := range wfs {
+		slog.Info("registering workflow", "name", wf.Identifier(), "version", wf.Version())
+	}
+	workflows = next
+
+	fmt.Println("registered workflows: ", listWorkflowRefs())
 	return nil
 }
 
-func RegisterWorkflow(name string, wf *executor.Workflow) error {
+func RegisterWorkflow(wf *executor.Workflow) error {
+	return BatchRegisterWorkflows(map[string]*executor.Workflow{wf.Ref(): wf})
+}
+
+// ReplaceWorkflows atomically replaces all registered workflows. Tasks that
+// already obtained a workflow keep running on it, new tasks use the new set.
+func ReplaceWorkflows(wfs map[string]*executor.Workflow) error {
+	all := make([]*executor.Workflow, 0, len(wfs))
+	for _, wf := range wfs {
+		all = append(all, wf)
+	}
+
+	next, err := indexWorkflows(all)
+	if err != nil {
+		return err
+	}
+
 	workflowLock.Lock()
 	defer workflowLock.Unlock()
 
-	if _, exists := workflows[name]; exists {
-		return fmt.Errorf("failed to register, workflow with name '%s' already exists", name)
-	}
-	slog.Info("registering workflow", "name", name)
-	workflows[name] = wf
+	workflows = next
+	slog.Info("replaced workflows", "count", len(all))
 	return nil
 }
 
-func GetWorkflow(name string) (*executor.Workflow, error) {
+// GetWorkflow returns the workflow referenced by ref, which is either
+// a workflow name or 'name@version'. Without a version,
+// the default version of the workflow is returned.
+func GetWorkflow(ref string) (*executor.Workflow, error) {
 	workflowLock.RLock()
 	defer workflowLock.RUnlock()
 
-	wf, exists := workflows[name]
+	name, version := ParseWorkflowRef(ref)
+	versions, exists := workflows[name]
 	if !exists {
 		return nil, fmt.Errorf("workflow with name '%s' does not exist", name)
 	}
 
+	if version == "" {
+		version = versions.defaultVersion
+	}
+	wf, exists := versions.versions[version]
+	if !exists {
+		return nil, fmt.Errorf("workflow '%s' has no version '%s'", name, version)
+	}
+
 	return wf, nil
 }
 
@@ -117,3 +281,77 @@ func ListWorkflows() []string {
 	}
 	return names
 }
+
+// ListWorkflowVersions returns the registered versions of the named workflow.
+func ListWorkflowVersions(name string) []string {
+	workflowLock.RLock()
+	defer workflowLock.RUnlock()
+
+	versions, exists := workflows[name]
+	if !exists {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(versions.versions))
+}
+
+// ParseWorkflowRef splits a workflow reference of the form 'name@version'.
+// The version is empty if ref only holds a name.
+func ParseWorkflowRef(ref string) (name, version string) {
+	name, version, _ = strings.Cut(ref, "@")
+	return name, version
+}
+
+type workflowVersions struct {
+	versions       map[string]*executor.Workflow
+	defaultVersion string
+}
+
+// indexWorkflows groups the workflows by name and resolves the default
+// version of each. A workflow with multiple versions must mark exactly
+// one of them as default.
+func indexWorkflows(wfs []*executor.Workflow) (map[string]*workflowVersions, error) {
+	index := make(map[string]*workflowVersions)
+	for _, wf := range wfs {
+		versions, ok := index[wf.Identifier()]
+		if !ok {
+			versions = &workflowVersions{versions: make(map[string]*executor.Workflow)}
+			index[wf.Identifier()] = versions
+		}
+
+		if _, exists := versions.versions[wf.Version()]; exists {
+			return nil, fmt.Errorf("failed to register, workflow '%s' already exists", wf.Ref())
+		}
+		versions.versions[wf.Version()] = wf
+
+		if wf.IsDefault() {
+			if versions.defaultVersion != "" {
+				return nil, fmt.Errorf("failed to register, workflow '%s' has multiple default versions", wf.Identifier())
+			}
+			versions.defaultVersion = wf.Version()
+		}
+	}
+
+	for name, versions := range index {
+		if versions.defaultVersion != "" {
+			continue
+		}
+		if len(versions.versions) > 1 {
+			return nil, fmt.Errorf("failed to register, workflow '%s' has %d versions but none is marked as default", name, len(versions.versions))
+		}
+		for version := range versions.versions {
+			versions.defaultVersion = version
+		}
+	}
+	return index, nil
+}
+
+func listWorkflowRefs() []string {
+	var refs []string
+	for _, versions := range workflows {
+		for _, wf := range versions.versions {
+			refs = append(refs, wf.Ref())
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package registry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alan-mat/awe/internal/executor"
+)
+
+type nopExecutor struct{}
+
+func (nopExecutor) Execute(ctx context.Context, params *executor.ExecutorParams) *executor.ExecutorResult {
+	return &executor.ExecutorResult{}
+}
+
+func TestFactoryDoesNotBlockOtherExecutors(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	err := RegisterExecutorFactory("test.Slow", ExecutorMeta{}, func() (executor.Executor, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return nopExecutor{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterExecutor("test.Fast", nopExecutor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := GetExecutor("test.Slow"); err != nil {
+				t.Errorf("failed to get slow executor: %v", err)
+			}
+		}()
+	}
+
+	<-started
+	got := make(chan error, 1)
+	go func() {
+		_, err := GetExecutor("test.Fast")
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("failed to get fast executor: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("getting an executor was blocked by the factory of another")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("factory called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package session keeps the history of chat sessions within the limits
+// configured for their workflow.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/provider"
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+// Strategy is what happens to messages removed from the history.
+type Strategy string
+
+const (
+	// StrategyTruncate drops removed messages.
+	StrategyTruncate Strategy = "truncate"
+	// StrategySummarize summarizes removed messages into the session summary,
+	// which is passed to the workflow ahead of the history.
+	StrategySummarize Strategy = "summarize"
+)
+
+// DefaultLM is the provider summarizing histories,
+// if neither the policy nor the provider defaults select one.
+var DefaultLM = "openai"
+
+var ErrInvalidStrategy = errors.New("invalid history strategy")
+
+// ParseStrategy returns the strategy of the given name, an empty name is StrategyTruncate.
+func ParseStrategy(name string) (Strategy, error) {
+	switch Strategy(name) {
+	case "", StrategyTruncate:
+		return StrategyTruncate, nil
+	case StrategySummarize:
+		return StrategySummarize, nil
+	}
+	return "", fmt.Errorf("%w '%s', must be one of 'truncate' or 'summarize'", ErrInvalidStrategy, name)
+}
+
+// Policy limits the history of the sessions of a workflow.
+// The zero Policy keeps the full history.
+type Policy struct {
+	// MaxMessages and MaxChars limit the amount of messages and their total
+	// length, 0 means no limit. The oldest turns are removed first.
+	MaxMessages int
+	MaxChars    int
+
+	Strategy Strategy
+
+	// Provider and Model select the LM summarizing the history,
+	// like the 'provider' and 'model' args of modules.
+	Provider string
+	Model    string
+}
+
+// History returns the messages passed to the workflow in the next turn of s,
+// which is its history preceded by its summary, if any.
+func History(s *transport.Session) []*api.ChatMessage {
+	if s.Summary == "" {
+		return s.History
+	}
+
+	history := make([]*api.ChatMessage, 0, len(s.History)+1)
+	history = append(history, &api.ChatMessage{
+		Role:    api.RoleSystem,
+		Content: "Summary of the earlier conversation:\n" + s.Summary,
+	})
+	return append(history, s.History...)
+}
+
+// AddTurn appends a turn of the user's query and the assistant's answer to the
+// history of s, and removes the messages exceeding the policy. If summarizing
+// the removed messages fails, they are removed anyway and the error is returned.
+func (p Policy) AddTurn(ctx context.Context, s *transport.Session, query, answer string) error {
+	s.History = append(s.History,
+		&api.ChatMessage{Role: api.RoleUser, Content: query},
+		&api.ChatMessage{Role: api.RoleAssistant, Content: answer},
+	)
+
+	cut := p.cut(s.History)
+	if cut == 0 {
+		return nil
+	}
+	removed := s.History[:cut]
+	s.History = s.History[cut:]
+
+	if p.Strategy != StrategySummarize {
+		return nil
+	}
+	summary, err := p.summarize(ctx, s.Summary, removed)
+	if err != nil {
+		return fmt.Errorf("failed to summarize history: %w", err)
+	}
+	s.Summary = summary
+	return nil
+}
+
+// cut returns the amount of messages to remove from the start of h.
+// The history always starts with a user message, so turns are removed as a whole.
+func (p Policy) cut(h []*api.ChatMessage) int {
+	chars := 0
+	for _, m := range h {
+		chars += len(m.Content)
+	}
+
+	cut := 0
+	for cut < len(h) && p.exceeded(len(h)-cut, chars) {
+		chars -= len(h[cut].Content)
+		cut++
+	}
+	for cut > 0 && cut < len(h) && h[cut].Role != api.RoleUser {
+		cut++
+	}
+	return cut
+}
+
+func (p Policy) exceeded(messages, chars int) bool {
+	return (p.MaxMessages > 0 && messages > p.MaxMessages) ||
+		(p.MaxChars > 0 && chars > p.MaxChars)
+}
+
+const summarizePrompt = `Summarize the following conversation between a user and an assistant, so that it can be continued without it. Keep all facts, names, decisions and open questions, and write the summary in the language of the conversation. Respond with the summary only.`
+
+func (p Policy) summarize(ctx context.Context, summary string, removed []*api.ChatMessage) (string, error) {
+	lm, err := provider.SelectLM(map[string]any{"provider": p.Provider, "model": p.Model}, DefaultLM)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(summarizePrompt)
+	if summary != "" {
+		b.WriteString("\n\nSummary of the conversation before:\n")
+		b.WriteString(summary)
+	}
+	b.WriteString("\n\nConversation:\n")
+	for _, m := range removed {
+		fmt.Fprintf(&b, "%s: %s\n", m.Role, m.Content)
+	}
+
+	stream, err := lm.Generate(ctx, api.GenerationRequest{Prompt: b.String()})
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	result, err := api.StreamReadAll(ctx, stream)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result), nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package session
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+func contents(h []*api.ChatMessage) []string {
+	c := make([]string, 0, len(h))
+	for _, m := range h {
+		c = append(c, m.Content)
+	}
+	return c
+}
+
+func TestAddTurnCutsHistory(t *testing.T) {
+	turns := [][2]string{{"q1", "a1"}, {"q2", "a2"}, {"q3", "a3"}}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{"no limits", Policy{}, []string{"q1", "a1", "q2", "a2", "q3", "a3"}},
+		{"max messages", Policy{MaxMessages: 4}, []string{"q2", "a2", "q3", "a3"}},
+		// whole turns are removed, so the history starts with a query
+		{"max messages within turn", Policy{MaxMessages: 3}, []string{"q3", "a3"}},
+		{"max chars", Policy{MaxChars: 8}, []string{"q2", "a2", "q3", "a3"}},
+		{"max chars within turn", Policy{MaxChars: 7}, []string{"q3", "a3"}},
+		{"both", Policy{MaxMessages: 4, MaxChars: 4}, []string{"q3", "a3"}},
+		{"exceeded by the last turn", Policy{MaxMessages: 1}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &transport.Session{}
+			for _, turn := range turns {
+				if err := tt.policy.AddTurn(context.Background(), s, turn[0], turn[1]); err != nil {
+					t.Fatalf("failed to add turn: %v", err)
+				}
+			}
+
+			if got := contents(s.History); !slices.Equal(got, tt.want) {
+				t.Errorf("history = %v, want %v", got, tt.want)
+			}
+			if s.Summary != "" {
+				t.Errorf("summary = %q, want none", s.Summary)
+			}
+		})
+	}
+}
+
+func TestHistoryStartsWithSummary(t *testing.T) {
+	s := &transport.Session{
+		History: []*api.ChatMessage{{Role: api.RoleUser, Content: "q"}},
+		Summary: "earlier",
+	}
+
+	h := History(s)
+	if len(h) != 2 || h[0].Role != api.RoleSystem || !strings.HasSuffix(h[0].Content, "earlier") {
+		t.Errorf("history = %v, want the summary followed by the history", contents(h))
+	}
+	if got := History(&transport.Session{History: s.History}); len(got) != 1 {
+		t.Errorf("history without summary = %v", contents(got))
+	}
+}
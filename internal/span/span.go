@@ -0,0 +1,253 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package span records how a request was executed, as a tree of spans.
+//
+// A Recorder is attached to the context of a request. Every workflow node
+// started with Start is recorded as a span, which is the child of the span
+// in whose context it was started, along with a snapshot of its args and a
+// summary of its outputs.
+package span
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MaxSpans limits the spans recorded for a request,
+// further spans are counted by Recorder.Dropped only.
+var MaxSpans = 1000
+
+// Span is the execution of a workflow node, or of a group of nodes
+// such as an iteration of a loop or a branch.
+type Span struct {
+	ID int `json:"id"`
+	// ParentID is the ID of the parent span, 0 for top level spans.
+	ParentID int    `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+	Module   string `json:"module,omitempty"`
+	Operator string `json:"operator,omitempty"`
+
+	StartedAt   int64 `json:"started_at"`
+	CompletedAt int64 `json:"completed_at,omitempty"`
+
+	// Query and Args are the input of the node, Outputs summarizes its results.
+	Query   string            `json:"query,omitempty"`
+	Args    map[string]string `json:"args,omitempty"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Route is the key of the route selected by conditional nodes.
+	Route string `json:"route,omitempty"`
+	// Attempts is the amount of attempts of nodes which were retried.
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Spans are the spans of a request, ordered by ID. They are stored as JSON.
+type Spans []Span
+
+func (ss Spans) MarshalBinary() ([]byte, error) {
+	if ss == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Span(ss))
+}
+
+// ScanRedis decodes spans stored in a redis hash.
+func (ss *Spans) ScanRedis(s string) error {
+	if s == "" {
+		*ss = nil
+		return nil
+	}
+	return json.Unmarshal([]byte(s), (*[]Span)(ss))
+}
+
+// Recorder records the spans of a request. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	spans   []*Span
+	nextID  int
+	dropped int
+}
+
+// NewRecorder returns a recorder holding the given spans,
+// e.g. the spans of a previous attempt of the request.
+func NewRecorder(spans ...Span) *Recorder {
+	r := &Recorder{nextID: 1}
+	for _, s := range spans {
+		r.spans = append(r.spans, &s)
+		r.nextID = max(r.nextID, s.ID+1)
+	}
+	return r
+}
+
+// Spans returns the spans recorded so far, including those not yet ended.
+func (r *Recorder) Spans() Spans {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spans := make(Spans, 0, len(r.spans))
+	for _, s := range r.spans {
+		spans = append(spans, *s)
+	}
+	slices.SortFunc(spans, func(a, b Span) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return spans
+}
+
+// Dropped returns the amount of spans which were not recorded, see MaxSpans.
+func (r *Recorder) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+type recorderKey struct{}
+
+type spanKey struct{}
+
+// WithRecorder returns a context whose spans are recorded by r.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the recorder of ctx, or nil if its spans are not recorded.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Handle is a recorded span in progress. All methods of a nil Handle do
+// nothing, so spans need not be checked for being recorded.
+type Handle struct {
+	rec  *Recorder
+	span *Span
+}
+
+// Start starts a span named name, as child of the current span of ctx.
+// The returned context holds the new span as its current span.
+// The handle is nil if ctx has no recorder or MaxSpans were recorded.
+func Start(ctx context.Context, name string) (context.Context, *Handle) {
+	r := FromContext(ctx)
+	if r == nil {
+		return ctx, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.spans) >= MaxSpans {
+		r.dropped += 1
+		return ctx, nil
+	}
+
+	s := &Span{
+		ID:        r.nextID,
+		Name:      name,
+		StartedAt: time.Now().UnixNano(),
+	}
+	if parent := Current(ctx); parent != nil {
+		s.ParentID = parent.span.ID
+	}
+	r.nextID += 1
+	r.spans = append(r.spans, s)
+
+	h := &Handle{rec: r, span: s}
+	return context.WithValue(ctx, spanKey{}, h), h
+}
+
+// Current returns the current span of ctx, or nil if there is none.
+func Current(ctx context.Context) *Handle {
+	h, _ := ctx.Value(spanKey{}).(*Handle)
+	return h
+}
+
+// SetNode sets the module and operator executed by the span.
+func (h *Handle) SetNode(module, operator string) {
+	h.update(func(s *Span) {
+		s.Module = module
+		s.Operator = operator
+	})
+}
+
+// SetInput sets the query and a snapshot of the args the span is executed with.
+func (h *Handle) SetInput(query string, args map[string]any) {
+	if h == nil {
+		return
+	}
+	summary := SummarizeValues(args)
+	h.update(func(s *Span) {
+		s.Query = query
+		s.Args = summary
+	})
+}
+
+// SetRoute sets the key of the route selected by the span.
+func (h *Handle) SetRoute(key string) {
+	h.update(func(s *Span) {
+		s.Route = key
+	})
+}
+
+// SetAttempts sets the amount of attempts of the span.
+func (h *Handle) SetAttempts(n int) {
+	h.update(func(s *Span) {
+		s.Attempts = n
+	})
+}
+
+// SetError records err, e.g. of a node whose error is handled by its policy.
+func (h *Handle) SetError(err error) {
+	if err == nil {
+		return
+	}
+	h.update(func(s *Span) {
+		s.Error = err.Error()
+	})
+}
+
+// End ends the span with a summary of its outputs, and err if it failed.
+func (h *Handle) End(outputs map[string]any, err error) {
+	if h == nil {
+		return
+	}
+	summary := SummarizeValues(outputs)
+	h.update(func(s *Span) {
+		s.CompletedAt = time.Now().UnixNano()
+		s.Outputs = summary
+		if err != nil {
+			s.Error = err.Error()
+		}
+	})
+}
+
+func (h *Handle) update(f func(s *Span)) {
+	if h == nil {
+		return
+	}
+	h.rec.mu.Lock()
+	defer h.rec.mu.Unlock()
+	f(h.span)
+}
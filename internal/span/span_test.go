@@ -0,0 +1,79 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package span
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+func TestRecorderContinuesSpans(t *testing.T) {
+	defer func(n int) { MaxSpans = n }(MaxSpans)
+	MaxSpans = 3
+
+	// spans of a previous attempt are kept
+	rec := NewRecorder(Span{ID: 1, Name: "search"})
+	ctx := WithRecorder(context.Background(), rec)
+
+	pctx, parent := Start(ctx, "loop")
+	_, child := Start(pctx, "iteration 1")
+	child.End(nil, nil)
+	parent.End(nil, nil)
+	if _, h := Start(ctx, "answer"); h != nil {
+		t.Errorf("span exceeding MaxSpans was recorded")
+	}
+
+	spans := rec.Spans()
+	if len(spans) != 3 || spans[1].ID != 2 || spans[1].ParentID != 0 || spans[2].ID != 3 || spans[2].ParentID != 2 {
+		t.Errorf("spans = %+v, want search, loop and its iteration", spans)
+	}
+	if rec.Dropped() != 1 {
+		t.Errorf("dropped = %d, want 1", rec.Dropped())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "string", value: "query", want: "query"},
+		{name: "long string", value: strings.Repeat("ä", maxValueLen), want: strings.Repeat("ä", maxValueLen/2) + "..."},
+		{name: "number", value: 5, want: "5"},
+		{name: "documents", value: []*api.ScoredDocument{{Title: "a", Score: 0.5}, {Content: "b  c", Url: "https://b"}}, want: "2 documents\n1. a (0.5000)\n2. b c (0.0000) https://b"},
+		{name: "messages", value: []*api.ChatMessage{{}, {}}, want: "2 messages"},
+		{name: "slice", value: []string{"a", "b", "c"}, want: "[]string (3)"},
+		{name: "nil", value: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.value); got != tt.want {
+				t.Errorf("Summarize = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
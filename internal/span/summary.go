@@ -0,0 +1,108 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package span
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+const (
+	// maxValueLen limits the length of summarized strings.
+	maxValueLen = 500
+	// maxDocuments limits the documents listed in summaries.
+	maxDocuments = 10
+)
+
+// SummarizeValues summarizes each of the values, see Summarize.
+func SummarizeValues(values map[string]any) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	summary := make(map[string]string, len(values))
+	for k, v := range values {
+		summary[k] = Summarize(v)
+	}
+	return summary
+}
+
+// Summarize returns a short readable representation of an arg or output value.
+// Documents are listed with their title and score, long strings are truncated
+// and other slices are summarized by their length.
+func Summarize(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return truncate(v, maxValueLen)
+	case []*api.ScoredDocument:
+		return summarizeDocuments(v)
+	case []*api.ChatMessage:
+		return fmt.Sprintf("%d messages", len(v))
+	case fmt.Stringer:
+		return truncate(v.String(), maxValueLen)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("%T (%d)", v, rv.Len())
+	case reflect.Pointer, reflect.Struct, reflect.Func, reflect.Chan:
+		return fmt.Sprintf("%T", v)
+	}
+	return truncate(fmt.Sprint(v), maxValueLen)
+}
+
+func summarizeDocuments(docs []*api.ScoredDocument) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d documents", len(docs))
+	for i, d := range docs {
+		if i == maxDocuments {
+			fmt.Fprintf(&b, "\n...")
+			break
+		}
+		title := d.Title
+		if title == "" {
+			title = truncate(strings.Join(strings.Fields(d.Content), " "), 80)
+		}
+		fmt.Fprintf(&b, "\n%d. %s (%.4f)", i+1, title, d.Score)
+		if d.Url != "" {
+			fmt.Fprintf(&b, " %s", d.Url)
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
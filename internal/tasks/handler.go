@@ -22,15 +22,23 @@
 package tasks
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
+	"github.com/alan-mat/awe/internal/auth"
 	"github.com/alan-mat/awe/internal/executor"
+	"github.com/alan-mat/awe/internal/provider"
 	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/span"
+	"github.com/alan-mat/awe/internal/telemetry"
 	"github.com/alan-mat/awe/internal/transport"
+	"github.com/alan-mat/awe/internal/usage"
 	"github.com/alan-mat/awe/internal/vector"
 	"github.com/hibiken/asynq"
 )
@@ -38,17 +46,42 @@ import (
 type TaskHandler struct {
 	transport   transport.Transport
 	vectorStore vector.Store
+
+	retention time.Duration
+}
+
+type TaskHandlerOption func(*TaskHandler)
+
+// WithTraceRetention sets how long traces are kept, unless their workflow
+// sets a retention of its own. It defaults to transport.TraceExpiry.
+func WithTraceRetention(retention time.Duration) TaskHandlerOption {
+	return func(h *TaskHandler) {
+		h.retention = retention
+	}
 }
 
-func NewTaskHandler(transport transport.Transport, vectorStore vector.Store) *TaskHandler {
-	return &TaskHandler{
+func NewTaskHandler(transport transport.Transport, vectorStore vector.Store, opts ...TaskHandlerOption) *TaskHandler {
+	h := &TaskHandler{
 		transport:   transport,
 		vectorStore: vectorStore,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	return h.ProcessTaskWithID(ctx, t.ResultWriter().TaskID(), t)
+}
+
+// ProcessTaskWithID processes the given task using id as its trace ID.
+// This is used when tasks are not delivered by asynq, e.g. by a MemoryQueue.
+func (h TaskHandler) ProcessTaskWithID(ctx context.Context, id string, t *asynq.Task) (err error) {
 	var query, workflowId, user string
+	var principal *auth.Principal
+	var sessionID string
+	var traceContext map[string]string
 	args := make(map[string]any)
 
 	switch t.Type() {
@@ -67,6 +100,8 @@ func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		}
 		query = p.Query
 		user = p.User
+		principal = p.Principal
+		traceContext = p.TraceContext
 		workflowId = DefaultWorkflowChat
 
 	case TypeSearch:
@@ -81,14 +116,16 @@ func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		}
 		query = p.Query
 		user = p.User
+		principal = p.Principal
+		traceContext = p.TraceContext
 		workflowId = DefaultWorkflowSearch
 
-	case TypeExecute:
+	case TypeExecute, TypeSession:
 		var p executeTaskPayload
 		if err := json.Unmarshal(t.Payload(), &p); err != nil {
 			return err
 		}
-		slog.Info("received execute task", "workflowId", p.WorkflowId, "user", p.User, "query", p.Query, "history", p.History)
+		slog.Info("received execute task", "workflowId", p.WorkflowId, "session", p.SessionID, "user", p.User, "query", p.Query, "history", p.History)
 
 		for k, v := range p.Args {
 			args[k] = v
@@ -98,21 +135,62 @@ func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		}
 		query = p.Query
 		user = p.User
+		principal = p.Principal
 		workflowId = p.WorkflowId
+		sessionID = p.SessionID
+		traceContext = p.TraceContext
 
 	default:
 		return fmt.Errorf("unrecognized task type (%w)", asynq.SkipRetry)
 	}
 
-	id := t.ResultWriter().TaskID()
 	slog.Info("task id", "id", id)
-	ms, err := h.transport.GetMessageStream(id)
+
+	var trace *transport.RequestTrace
+	interrupted := false
+
+	ctx, task := telemetry.StartTask(ctx, traceContext, t.Type(), id)
+	defer func() {
+		workflow, status := workflowId, "failed"
+		if trace != nil {
+			workflow, status = trace.Workflow, traceStatus(trace.Status)
+		}
+		if interrupted {
+			status = "interrupted"
+		}
+		task.End(workflow, status, err)
+	}()
+
+	// a checkpoint exists if a previous attempt of this task was interrupted
+	tr := h.transport
+	cp, err := executor.LoadCheckpoint(ctx, h.transport, id)
+	if err != nil {
+		if !errors.Is(err, transport.ErrCheckpointNotFound) {
+			slog.Warn("failed to load checkpoint, restarting task", "id", id, "err", err)
+		}
+		cp = nil
+	} else {
+		rt, err := transport.NewResumeTransport(ctx, h.transport, id, cp.StreamLen)
+		if err != nil {
+			slog.Warn("failed to prepare resuming task, restarting task", "id", id, "err", err)
+			cp = nil
+		} else {
+			slog.Info("resuming task from checkpoint", "id", id, "workflowId", cp.WorkflowID, "version", cp.WorkflowVersion)
+			tr = rt
+			// resume on the version the task started with, even if the default changed
+			if cp.WorkflowVersion != "" {
+				workflowId = cp.WorkflowID + "@" + cp.WorkflowVersion
+			}
+		}
+	}
+
+	ms, err := tr.GetMessageStream(id)
 	if err != nil {
 		slog.Error("failed to initialize message stream", "err", err)
 		return fmt.Errorf("failed to initialize message stream: %v (%w)", err, asynq.SkipRetry)
 	}
 
-	trace := &transport.RequestTrace{
+	trace = &transport.RequestTrace{
 		ID:          id,
 		Status:      transport.TraceStatusRunning,
 		StartedAt:   time.Now().UnixNano(),
@@ -120,39 +198,144 @@ func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		Query:       query,
 		User:        user,
 	}
-	err = h.transport.SetTrace(ctx, trace)
-	if err != nil {
-		slog.Error("failed to set trace", "id", id, "err", err)
+	// usage and spans of an interrupted attempt are carried over, so the trace covers all attempts
+	var prevUsage usage.Records
+	var prevSpans span.Spans
+	if cp != nil {
+		if prev, err := h.transport.GetTrace(ctx, id); err == nil {
+			trace.StartedAt = prev.StartedAt
+			prevUsage = prev.Usage
+			prevSpans = prev.Spans
+		}
 	}
+	tracker := usage.NewTracker(prevUsage...)
+	ctx = usage.WithTracker(ctx, tracker)
+	recorder := span.NewRecorder(prevSpans...)
+	ctx = span.WithRecorder(ctx, recorder)
+	// workflows called by nodes check the access of the principal
+	ctx = auth.WithPrincipal(ctx, principal)
 
-	workflow, err := registry.GetWorkflow(workflowId)
-	if err != nil {
-		errf := fmt.Errorf("workflow not found: %v (%w)", err, asynq.SkipRetry)
+	workflow, workflowErr := registry.GetWorkflow(workflowId)
+	retention := cmp.Or(h.retention, transport.TraceExpiry)
+	if workflowErr == nil {
+		trace.Workflow = workflow.Identifier()
+		trace.WorkflowVersion = workflow.Version()
+		retention = cmp.Or(workflow.Retention(), retention)
+	} else {
+		trace.Workflow, _ = registry.ParseWorkflowRef(workflowId)
+	}
+
+	h.setTrace(ctx, trace, retention)
+
+	// the checkpoint and running slots are kept only if the task is interrupted,
+	// so it can be resumed on retry
+	defer func() {
+		if !interrupted {
+			h.deleteCheckpoint(ctx, id)
+			h.releaseSlots(ctx, trace)
+		}
+	}()
+
+	// requests cancelled while they were queued are not started
+	if cancelled, err := h.transport.IsCancelled(ctx, id); err != nil {
+		slog.Warn("failed to check trace cancellation", "id", id, "err", err)
+	} else if cancelled {
+		slog.Info("trace cancelled before it started", "id", id)
+		ms.Send(ctx, transport.MessageStreamPayload{
+			Content: "task cancelled",
+			Status:  "CANCELLED",
+		})
+
+		// a resumed task keeps the usage and spans of its earlier attempts
+		trace.CompletedAt = time.Now().UnixNano()
+		trace.Status = transport.TraceStatusCancelled
+		trace.Usage = tracker.Records()
+		trace.Spans = recorder.Spans()
+		trace.SpansDropped = recorder.Dropped()
+		h.setTrace(ctx, trace, retention)
+		h.addUsage(ctx, trace)
+		return nil
+	}
+
+	if workflowErr != nil {
+		errf := fmt.Errorf("workflow not found: %v (%w)", workflowErr, asynq.SkipRetry)
 		slog.Error(fmt.Sprintf("%v", errf))
 		ms.Send(ctx, transport.MessageStreamPayload{
 			Content: "workflow not found",
 			Status:  "ERR",
 		})
 
-		trace.CompletedAt = time.Now().Unix()
+		trace.CompletedAt = time.Now().UnixNano()
 		trace.Status = transport.TraceStatusFailed
-		err = h.transport.SetTrace(ctx, trace)
-		if err != nil {
-			slog.Error("failed to set trace", "id", id, "err", err)
-		}
+		h.setTrace(ctx, trace, retention)
 
 		return errf
 	}
 
+	if !workflow.Allows(principal) {
+		slog.Warn("workflow access denied", "id", id, "workflowId", workflowId, "user", user)
+		ms.Send(ctx, transport.MessageStreamPayload{
+			Content: "access to workflow denied",
+			Status:  "DENIED",
+		})
+
+		trace.CompletedAt = time.Now().UnixNano()
+		trace.Status = transport.TraceStatusFailed
+		h.setTrace(ctx, trace, retention)
+
+		return fmt.Errorf("workflow access denied (%w)", asynq.SkipRetry)
+	}
+
+	// only the workflow selects providers and models, unless it lets requests choose them
+	for _, name := range provider.SelectionArgs() {
+		if _, ok := args[name]; ok && !slices.Contains(workflow.ProviderArgs(), name) {
+			slog.Warn("dropping provider arg not allowed by workflow", "id", id, "workflowId", workflowId, "arg", name)
+			delete(args, name)
+		}
+	}
+
 	params := executor.NewExecutorParams(
 		id,
 		query,
-		executor.WithTransport(h.transport),
+		executor.WithTransport(tr),
 		executor.WithVectorStore(h.vectorStore),
 		executor.WithArgs(args),
 	)
 
-	res := workflow.Execute(ctx, params)
+	// the workflow is cancelled once a client cancels the trace
+	execCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go h.watchCancel(execCtx, id, cancel)
+
+	res := workflow.ExecuteWithCheckpoints(execCtx, params, cp)
+	if res.Err != nil && ctx.Err() != nil {
+		// the worker is shutting down, the task is retried from its checkpoint
+		interrupted = true
+		slog.Warn("workflow execution interrupted", "id", id, "err", res.Err)
+
+		trace.Usage = tracker.Records()
+		trace.Spans = recorder.Spans()
+		trace.SpansDropped = recorder.Dropped()
+		h.setTrace(context.WithoutCancel(ctx), trace, retention)
+		return fmt.Errorf("workflow execution interrupted: %w", ctx.Err())
+	}
+	if res.Err != nil && errors.Is(context.Cause(execCtx), transport.ErrTraceCancelled) {
+		slog.Info("workflow execution cancelled", "id", id)
+		ms.Send(ctx, transport.MessageStreamPayload{
+			Content: "task cancelled",
+			Status:  "CANCELLED",
+		})
+
+		trace.CompletedAt = time.Now().UnixNano()
+		trace.Status = transport.TraceStatusCancelled
+		trace.Usage = tracker.Records()
+		trace.Spans = recorder.Spans()
+		trace.SpansDropped = recorder.Dropped()
+		h.setTrace(ctx, trace, retention)
+		h.addUsage(ctx, trace)
+
+		return nil
+	}
 	if res.Err != nil {
 		ms.Send(ctx, transport.MessageStreamPayload{
 			Content: "workflow execution failed",
@@ -161,14 +344,20 @@ func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 
 		trace.CompletedAt = time.Now().UnixNano()
 		trace.Status = transport.TraceStatusFailed
-		err = h.transport.SetTrace(ctx, trace)
-		if err != nil {
-			slog.Error("failed to set trace", "id", id, "err", err)
-		}
+		trace.Usage = tracker.Records()
+		trace.Spans = recorder.Spans()
+		trace.SpansDropped = recorder.Dropped()
+		h.setTrace(ctx, trace, retention)
+		h.addUsage(ctx, trace)
 
 		return fmt.Errorf("workflow execution failed: %w", asynq.SkipRetry)
 	}
 
+	// the turn is added before the client is done, so that it is part of the next turn
+	if sessionID != "" {
+		h.addSessionTurn(ctx, sessionID, workflow, query, ms)
+	}
+
 	err = ms.Send(ctx, transport.MessageStreamPayload{
 		Content: "task finished",
 		Status:  "DONE",
@@ -179,10 +368,111 @@ func (h TaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 
 	trace.CompletedAt = time.Now().UnixNano()
 	trace.Status = transport.TraceStatusCompleted
-	err = h.transport.SetTrace(ctx, trace)
+	trace.Usage = tracker.Records()
+	trace.Spans = recorder.Spans()
+	trace.SpansDropped = recorder.Dropped()
+	h.setTrace(ctx, trace, retention)
+	h.addUsage(ctx, trace)
+
+	return nil
+}
+
+// traceStatus returns the name of the status of a trace, as used in metrics.
+func traceStatus(status int) string {
+	switch status {
+	case transport.TraceStatusRunning:
+		return "running"
+	case transport.TraceStatusCompleted:
+		return "completed"
+	case transport.TraceStatusFailed:
+		return "failed"
+	case transport.TraceStatusCancelled:
+		return "cancelled"
+	}
+	return "unspecified"
+}
+
+// watchCancel cancels the execution of the trace id with ErrTraceCancelled,
+// once a client cancels the trace. It returns when ctx is done.
+func (h TaskHandler) watchCancel(ctx context.Context, id string, cancel context.CancelCauseFunc) {
+	err := h.transport.WaitCancel(ctx, id)
+	if err == nil {
+		slog.Info("trace cancelled by client", "id", id)
+		cancel(transport.ErrTraceCancelled)
+		return
+	}
+	if ctx.Err() == nil {
+		slog.Warn("failed to watch trace cancellation", "id", id, "err", err)
+	}
+}
+
+// setTrace stores the trace, which is kept for retention after it completed.
+// Running traces are kept for at least TraceExpiry, so that they do not expire
+// before the request completes.
+func (h TaskHandler) setTrace(ctx context.Context, trace *transport.RequestTrace, retention time.Duration) {
+	if trace.CompletedAt != 0 {
+		trace.ExpiresAt = time.Unix(0, trace.CompletedAt).Add(retention).UnixNano()
+	} else {
+		trace.ExpiresAt = time.Now().Add(max(retention, transport.TraceExpiry)).UnixNano()
+	}
+
+	if err := h.transport.SetTrace(ctx, trace); err != nil {
+		slog.Error("failed to set trace", "id", trace.ID, "err", err)
+	}
+}
+
+// addUsage accounts the usage of a finished trace to its user and workflow,
+// for the month and the day it was started in.
+func (h TaskHandler) addUsage(ctx context.Context, trace *transport.RequestTrace) {
+	totals := trace.Usage.Total()
+	totals.Requests = 1
+
+	ctx = context.WithoutCancel(ctx)
+	startedAt := time.Unix(0, trace.StartedAt)
+	scopes := transport.UsageScopes(trace.User, trace.Workflow)
+	for _, period := range []string{transport.UsagePeriod(startedAt), transport.UsageDay(startedAt)} {
+		if err := h.transport.AddUsage(ctx, period, scopes, totals); err != nil {
+			slog.Error("failed to add usage", "id", trace.ID, "period", period, "err", err)
+		}
+	}
+}
+
+// addSessionTurn adds the query and the answer streamed by the workflow to the
+// history of the session, which is then limited by the history policy of the workflow.
+func (h TaskHandler) addSessionTurn(ctx context.Context, id string, workflow *executor.Workflow, query string, ms transport.MessageStream) {
+	answer, err := ms.Text(ctx)
 	if err != nil {
-		slog.Error("failed to set trace", "id", id, "err", err)
+		slog.Error("failed to read answer of session turn", "session", id, "err", err)
+		return
 	}
 
-	return nil
+	// turns of the session may finish at the same time, neither must be lost
+	err = h.transport.UpdateSession(ctx, id, func(s *transport.Session) error {
+		if err := workflow.HistoryPolicy().AddTurn(ctx, s, query, answer); err != nil {
+			slog.Warn("failed to apply session history policy", "session", id, "err", err)
+		}
+		s.UpdatedAt = time.Now().UnixNano()
+		return nil
+	})
+	if err != nil {
+		slog.Error("failed to save session", "session", id, "err", err)
+	}
+}
+
+// releaseSlots releases the running slots the server acquired for the trace,
+// when it admitted the request.
+func (h TaskHandler) releaseSlots(ctx context.Context, trace *transport.RequestTrace) {
+	ctx = context.WithoutCancel(ctx)
+	scopes := transport.UsageScopes(trace.User, trace.Workflow)
+	if err := h.transport.ReleaseSlots(ctx, scopes, trace.ID); err != nil {
+		slog.Warn("failed to release running slots", "id", trace.ID, "err", err)
+	}
+}
+
+func (h TaskHandler) deleteCheckpoint(ctx context.Context, id string) {
+	// the task context may already be done, the checkpoint must be removed regardless
+	ctx = context.WithoutCancel(ctx)
+	if err := h.transport.DeleteCheckpoint(ctx, id); err != nil {
+		slog.Warn("failed to delete checkpoint", "id", id, "err", err)
+	}
 }
@@ -0,0 +1,102 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/alan-mat/awe/internal/proto"
+	"github.com/alan-mat/awe/internal/transport"
+	"github.com/hibiken/asynq"
+)
+
+func TestQueuedTaskIsCancelled(t *testing.T) {
+	ctx := context.Background()
+	tr := transport.NewMemoryTransport()
+	q := NewMemoryQueue()
+
+	task, err := NewExecuteTask(ctx, &pb.ExecuteRequest{WorkflowId: "test.unknown", Query: "q", User: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue(task, asynq.TaskID("t1")); err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := q.GetTask("t1")
+	if err != nil {
+		t.Fatalf("failed to get queued task: %v", err)
+	}
+	if user, _ := TaskUser(queued); user != "alice" {
+		t.Errorf("user = %q, want alice", user)
+	}
+	if _, err := q.GetTask("t2"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrTaskNotFound)
+	}
+
+	if err := tr.CancelTrace(ctx, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	// the workflow does not exist, the task would fail if it was started
+	if err := NewTaskHandler(tr, nil).ProcessTaskWithID(ctx, "t1", queued); err != nil {
+		t.Fatalf("failed to process task: %v", err)
+	}
+
+	trace, err := tr.GetTrace(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Status != transport.TraceStatusCancelled {
+		t.Errorf("status = %d, want %d", trace.Status, transport.TraceStatusCancelled)
+	}
+}
+
+func TestTraceRetention(t *testing.T) {
+	ctx := context.Background()
+	tr := transport.NewMemoryTransport()
+	h := NewTaskHandler(tr, nil)
+
+	started := time.Now().Add(-time.Hour)
+	trace := &transport.RequestTrace{ID: "t1", StartedAt: started.UnixNano(), Status: transport.TraceStatusRunning}
+	earliest := time.Now().Add(transport.TraceExpiry).UnixNano()
+	h.setTrace(ctx, trace, time.Minute)
+	if trace.ExpiresAt < earliest {
+		t.Errorf("running trace expires at %d, want at least %d", trace.ExpiresAt, earliest)
+	}
+	if _, err := tr.GetTrace(ctx, "t1"); err != nil {
+		t.Fatalf("failed to get running trace: %v", err)
+	}
+
+	completed := time.Now()
+	trace.CompletedAt = completed.UnixNano()
+	trace.Status = transport.TraceStatusCompleted
+	h.setTrace(ctx, trace, time.Minute)
+	if want := completed.Add(time.Minute).UnixNano(); trace.ExpiresAt != want {
+		t.Errorf("completed trace expires at %d, want %d", trace.ExpiresAt, want)
+	}
+	if _, err := tr.GetTrace(ctx, "t1"); err != nil {
+		t.Fatalf("failed to get completed trace: %v", err)
+	}
+}
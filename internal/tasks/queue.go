@@ -0,0 +1,218 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alan-mat/awe/internal/telemetry"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+var (
+	ErrQueueFull    = errors.New("task queue is full")
+	ErrTaskNotFound = errors.New("task not found")
+)
+
+const memoryQueueSize = 1024
+
+// Enqueuer enqueues tasks to be processed by a worker.
+// It is implemented by *asynq.Client and *MemoryQueue.
+type Enqueuer interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+// Inspector looks up tasks which were enqueued and are not processed yet,
+// or are being processed. It is implemented by *MemoryQueue and AsynqInspector.
+type Inspector interface {
+	// GetTask returns the task id, or ErrTaskNotFound if it is not in the queue.
+	GetTask(id string) (*asynq.Task, error)
+}
+
+type memoryTask struct {
+	id   string
+	task *asynq.Task
+}
+
+// MemoryQueue is an in-process task queue used in place of asynq,
+// when running the server and worker in a single process.
+// Tasks are not persisted and are lost when the process exits.
+type MemoryQueue struct {
+	tasks  chan *memoryTask
+	active atomic.Int64
+
+	// queued holds the tasks until they are processed, see GetTask
+	mu     sync.Mutex
+	queued map[string]*asynq.Task
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		tasks:  make(chan *memoryTask, memoryQueueSize),
+		queued: make(map[string]*asynq.Task),
+	}
+}
+
+func (q *MemoryQueue) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	id := uuid.NewString()
+	for _, opt := range opts {
+		if opt.Type() == asynq.TaskIDOpt {
+			id = opt.Value().(string)
+		}
+	}
+
+	q.mu.Lock()
+	q.queued[id] = task
+	q.mu.Unlock()
+
+	select {
+	case q.tasks <- &memoryTask{id: id, task: task}:
+	default:
+		q.remove(id)
+		return nil, ErrQueueFull
+	}
+
+	return &asynq.TaskInfo{
+		ID:      id,
+		Queue:   "default",
+		Type:    task.Type(),
+		Payload: task.Payload(),
+		State:   asynq.TaskStatePending,
+	}, nil
+}
+
+// Run processes enqueued tasks using the given handler with
+// the given amount of concurrent workers. It blocks until ctx is cancelled.
+func (q *MemoryQueue) Run(ctx context.Context, handler *TaskHandler, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case mt := <-q.tasks:
+					q.active.Add(1)
+					err := handler.ProcessTaskWithID(ctx, mt.id, mt.task)
+					q.active.Add(-1)
+					q.remove(mt.id)
+					if err != nil {
+						slog.Error("failed to process task", "id", mt.id, "type", mt.task.Type(), "err", err)
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (q *MemoryQueue) GetTask(id string) (*asynq.Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	task, ok := q.queued[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (q *MemoryQueue) remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.queued, id)
+}
+
+// AsynqInspector looks up the tasks of the default asynq queue.
+type AsynqInspector struct {
+	inspector *asynq.Inspector
+}
+
+func NewAsynqInspector(i *asynq.Inspector) AsynqInspector {
+	return AsynqInspector{inspector: i}
+}
+
+// GetTask returns the task id, unless it completed or was archived after failing.
+func (i AsynqInspector) GetTask(id string) (*asynq.Task, error) {
+	info, err := i.inspector.GetTaskInfo("default", id)
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.State == asynq.TaskStateCompleted || info.State == asynq.TaskStateArchived {
+		return nil, ErrTaskNotFound
+	}
+	return asynq.NewTask(info.Type, info.Payload), nil
+}
+
+// Stats returns the number of pending and active tasks of the queue,
+// see telemetry.QueueFunc.
+func (q *MemoryQueue) Stats() (map[string]map[string]int, error) {
+	return map[string]map[string]int{
+		"default": {
+			"pending": len(q.tasks),
+			"active":  int(q.active.Load()),
+		},
+	}, nil
+}
+
+// InspectQueues returns a telemetry.QueueFunc reporting the number
+// of tasks of all asynq queues by state.
+func InspectQueues(i *asynq.Inspector) telemetry.QueueFunc {
+	return func() (map[string]map[string]int, error) {
+		queues, err := i.Queues()
+		if err != nil {
+			return nil, err
+		}
+
+		stats := make(map[string]map[string]int, len(queues))
+		for _, queue := range queues {
+			info, err := i.GetQueueInfo(queue)
+			if err != nil {
+				return nil, err
+			}
+			stats[queue] = map[string]int{
+				"pending":   info.Pending,
+				"active":    info.Active,
+				"scheduled": info.Scheduled,
+				"retry":     info.Retry,
+				"archived":  info.Archived,
+			}
+		}
+		return stats, nil
+	}
+}
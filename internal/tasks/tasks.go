@@ -22,10 +22,16 @@
 package tasks
 
 import (
+	"context"
 	"encoding/json"
+	"maps"
 
 	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/auth"
 	pb "github.com/alan-mat/awe/internal/proto"
+	"github.com/alan-mat/awe/internal/session"
+	"github.com/alan-mat/awe/internal/telemetry"
+	"github.com/alan-mat/awe/internal/transport"
 	"github.com/hibiken/asynq"
 )
 
@@ -38,21 +44,31 @@ const (
 	TypeChat    = "awe:chat"
 	TypeSearch  = "awe:search"
 	TypeExecute = "awe:execute"
+	TypeSession = "awe:session"
 )
 
 type chatTaskPayload struct {
-	Query   string
-	User    string
-	History []*api.ChatMessage
-	Args    map[string]string
+	Query     string
+	User      string
+	Principal *auth.Principal
+	History   []*api.ChatMessage
+	Args      map[string]string
+
+	// TraceContext continues the trace of the request in the worker.
+	TraceContext map[string]string `json:",omitempty"`
 }
 
-func NewChatTask(req *pb.ChatRequest) (*asynq.Task, error) {
+// NewChatTask returns the task of a chat request. If the request is authenticated,
+// the principal p replaces the user of the request. The trace context of ctx is
+// passed on to the worker, see telemetry.Inject.
+func NewChatTask(ctx context.Context, req *pb.ChatRequest, p *auth.Principal) (*asynq.Task, error) {
 	tp := chatTaskPayload{
-		Query:   req.Query,
-		User:    req.User,
-		History: api.ParseChatHistory(req.History),
-		Args:    req.Args,
+		Query:        req.Query,
+		User:         requestUser(req.User, p),
+		Principal:    p,
+		History:      api.ParseChatHistory(req.History),
+		Args:         req.Args,
+		TraceContext: telemetry.Inject(ctx),
 	}
 	payload, err := json.Marshal(tp)
 	if err != nil {
@@ -62,16 +78,22 @@ func NewChatTask(req *pb.ChatRequest) (*asynq.Task, error) {
 }
 
 type searchTaskPayload struct {
-	Query string
-	User  string
-	Args  map[string]string
+	Query     string
+	User      string
+	Principal *auth.Principal
+	Args      map[string]string
+
+	TraceContext map[string]string `json:",omitempty"`
 }
 
-func NewSearchTask(req *pb.SearchRequest) (*asynq.Task, error) {
+// NewSearchTask returns the task of a search request, see NewChatTask.
+func NewSearchTask(ctx context.Context, req *pb.SearchRequest, p *auth.Principal) (*asynq.Task, error) {
 	tp := searchTaskPayload{
-		Query: req.Query,
-		User:  req.User,
-		Args:  req.Args,
+		Query:        req.Query,
+		User:         requestUser(req.User, p),
+		Principal:    p,
+		Args:         req.Args,
+		TraceContext: telemetry.Inject(ctx),
 	}
 	payload, err := json.Marshal(tp)
 	if err != nil {
@@ -84,17 +106,26 @@ type executeTaskPayload struct {
 	WorkflowId string
 	Query      string
 	User       string
+	Principal  *auth.Principal
 	History    []*api.ChatMessage
 	Args       map[string]string
+
+	// SessionID is the session the turn is added to, for session tasks.
+	SessionID string
+
+	TraceContext map[string]string `json:",omitempty"`
 }
 
-func NewExecuteTask(req *pb.ExecuteRequest) (*asynq.Task, error) {
+// NewExecuteTask returns the task of an execute request, see NewChatTask.
+func NewExecuteTask(ctx context.Context, req *pb.ExecuteRequest, p *auth.Principal) (*asynq.Task, error) {
 	tp := executeTaskPayload{
-		WorkflowId: req.WorkflowId,
-		Query:      req.Query,
-		User:       req.User,
-		History:    api.ParseChatHistory(req.History),
-		Args:       req.Args,
+		WorkflowId:   req.WorkflowId,
+		Query:        req.Query,
+		User:         requestUser(req.User, p),
+		Principal:    p,
+		History:      api.ParseChatHistory(req.History),
+		Args:         req.Args,
+		TraceContext: telemetry.Inject(ctx),
 	}
 	payload, err := json.Marshal(tp)
 	if err != nil {
@@ -102,3 +133,49 @@ func NewExecuteTask(req *pb.ExecuteRequest) (*asynq.Task, error) {
 	}
 	return asynq.NewTask(TypeExecute, payload), nil
 }
+
+// NewSessionTask returns the task of a turn of the chat session s, which runs
+// the workflow of the session with its history and args. The args of the
+// request take precedence over those of the session.
+func NewSessionTask(ctx context.Context, s *transport.Session, req *pb.SessionRequest, p *auth.Principal) (*asynq.Task, error) {
+	args := make(map[string]string, len(s.Args)+len(req.Args))
+	maps.Copy(args, s.Args)
+	maps.Copy(args, req.Args)
+
+	tp := executeTaskPayload{
+		WorkflowId:   s.Workflow,
+		Query:        req.Query,
+		User:         s.User,
+		Principal:    p,
+		History:      session.History(s),
+		Args:         args,
+		SessionID:    s.ID,
+		TraceContext: telemetry.Inject(ctx),
+	}
+	payload, err := json.Marshal(tp)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeSession, payload), nil
+}
+
+// requestUser returns the user of the principal p, or the user
+// given by the client if the request is not authenticated.
+func requestUser(user string, p *auth.Principal) string {
+	if p != nil {
+		return p.User
+	}
+	return user
+}
+
+// TaskUser returns the user of the request of task t, which is
+// the user of the trace the task creates once it is processed.
+func TaskUser(t *asynq.Task) (string, error) {
+	var p struct {
+		User string
+	}
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return "", err
+	}
+	return p.User, nil
+}
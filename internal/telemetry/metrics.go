@@ -0,0 +1,145 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package telemetry
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	taskDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "awe_task_duration_seconds",
+		Help:    "Duration of the tasks processed by the worker.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+	}, []string{"type", "workflow", "status"})
+
+	nodeDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "awe_node_duration_seconds",
+		Help:    "Duration of the workflow nodes executed by the worker, including retries.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 15),
+	}, []string{"module", "operator", "status"})
+
+	providerRequests = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "awe_provider_requests_total",
+		Help: "Number of provider calls by status, 'ok' or 'error'.",
+	}, []string{"provider", "capability", "status"})
+
+	providerDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "awe_provider_request_duration_seconds",
+		Help:    "Duration of provider calls, completion streams end once fully received.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 15),
+	}, []string{"provider", "capability"})
+
+	providerTokens = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "awe_provider_tokens_total",
+		Help: "Number of tokens reported by providers, by direction 'input' or 'output'.",
+	}, []string{"provider", "model", "direction"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		queueCollector,
+	)
+}
+
+// Handler returns the handler exposing the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// NewMetricsServer returns a server exposing the metrics at /metrics on addr.
+func NewMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// QueueFunc returns the number of tasks of every queue by state, e.g. 'pending' or 'active'.
+type QueueFunc func() (map[string]map[string]int, error)
+
+// SetQueueFunc sets the function reporting the depth of the task queues,
+// which is called whenever the metrics are collected.
+func SetQueueFunc(f QueueFunc) {
+	queueCollector.mu.Lock()
+	defer queueCollector.mu.Unlock()
+	queueCollector.f = f
+}
+
+var queueCollector = &queueDepthCollector{
+	desc: prometheus.NewDesc(
+		"awe_queue_tasks",
+		"Number of tasks in the task queue by state.",
+		[]string{"queue", "state"}, nil,
+	),
+}
+
+type queueDepthCollector struct {
+	mu   sync.Mutex
+	f    QueueFunc
+	desc *prometheus.Desc
+}
+
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	f := c.f
+	c.mu.Unlock()
+	if f == nil {
+		return
+	}
+
+	queues, err := f()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for queue, states := range queues {
+		for state, n := range states {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), queue, state)
+		}
+	}
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsCountServedRequests(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		serve func()
+		want  []string
+	}{
+		{
+			name: "task",
+			serve: func() {
+				_, task := StartTask(ctx, nil, "test:served", "t1")
+				task.End("chat", "completed", nil)
+			},
+			want: []string{`awe_task_duration_seconds_count{status="completed",type="test:served",workflow="chat"} 1`},
+		},
+		{
+			name: "node",
+			serve: func() {
+				_, node := StartNode(ctx, "search", "test.Served")
+				node.End("run", nil)
+				_, node = StartNode(ctx, "search", "test.Served")
+				node.End("run", errors.New("failed"))
+			},
+			want: []string{
+				`awe_node_duration_seconds_count{module="test.Served",operator="run",status="ok"} 1`,
+				`awe_node_duration_seconds_count{module="test.Served",operator="run",status="error"} 1`,
+			},
+		},
+		{
+			name: "provider calls",
+			serve: func() {
+				for range 2 {
+					_, call := StartProviderCall(ctx, "served", "lm", "small")
+					call.End("", 10, 4, nil)
+				}
+				_, call := StartProviderCall(ctx, "served", "lm", "small")
+				call.End("large", 5, 0, errors.New("failed"))
+			},
+			want: []string{
+				`awe_provider_requests_total{capability="lm",provider="served",status="ok"} 2`,
+				`awe_provider_requests_total{capability="lm",provider="served",status="error"} 1`,
+				`awe_provider_request_duration_seconds_count{capability="lm",provider="served"} 3`,
+				`awe_provider_tokens_total{direction="input",model="small",provider="served"} 20`,
+				`awe_provider_tokens_total{direction="output",model="small",provider="served"} 8`,
+				`awe_provider_tokens_total{direction="input",model="large",provider="served"} 5`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.serve()
+
+			metrics := scrape(t)
+			for _, line := range tt.want {
+				if !strings.Contains(metrics, "\n"+line+"\n") {
+					t.Errorf("metrics do not contain %s", line)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueMetrics(t *testing.T) {
+	SetQueueFunc(func() (map[string]map[string]int, error) {
+		return map[string]map[string]int{"default": {"pending": 3}}, nil
+	})
+	defer SetQueueFunc(nil)
+
+	if line := `awe_queue_tasks{queue="default",state="pending"} 3`; !strings.Contains(scrape(t), "\n"+line+"\n") {
+		t.Errorf("metrics do not contain %s", line)
+	}
+}
+
+// scrape returns the metrics exposed by Handler.
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	return string(body)
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Task is a task being processed by the worker.
+type Task struct {
+	span  trace.Span
+	typ   string
+	start time.Time
+}
+
+// StartTask starts the span of the task of type typ with the trace id,
+// continuing the trace of the request injected into carrier.
+func StartTask(ctx context.Context, carrier map[string]string, typ, id string) (context.Context, *Task) {
+	ctx, s := Start(Extract(ctx, carrier), "process "+typ,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			TraceIDKey.String(id),
+			attribute.String("awe.task.type", typ),
+		),
+	)
+	return ctx, &Task{span: s, typ: typ, start: time.Now()}
+}
+
+// End ends the task of the workflow, which finished with the given status,
+// e.g. 'completed' or 'failed'.
+func (t *Task) End(workflow, status string, err error) {
+	taskDuration.WithLabelValues(t.typ, workflow, status).Observe(time.Since(t.start).Seconds())
+
+	t.span.SetAttributes(
+		attribute.String("awe.workflow", workflow),
+		attribute.String("awe.task.status", status),
+	)
+	End(t.span, err)
+}
+
+// Node is a workflow node being executed.
+type Node struct {
+	span   trace.Span
+	module string
+	start  time.Time
+}
+
+// StartNode starts the span of the node name of the module.
+func StartNode(ctx context.Context, name, module string) (context.Context, *Node) {
+	ctx, s := Start(ctx, "node "+name, trace.WithAttributes(
+		attribute.String("awe.node", name),
+		attribute.String("awe.module", module),
+	))
+	return ctx, &Node{span: s, module: module, start: time.Now()}
+}
+
+// End ends the node, which was executed with the operator.
+func (n *Node) End(operator string, err error) {
+	nodeDuration.WithLabelValues(n.module, operator, status(err)).Observe(time.Since(n.start).Seconds())
+
+	n.span.SetAttributes(attribute.String("awe.operator", operator))
+	End(n.span, err)
+}
+
+// ProviderCall is a call of a provider.
+type ProviderCall struct {
+	span                        trace.Span
+	provider, capability, model string
+	start                       time.Time
+}
+
+// StartProviderCall starts the span of a call of the provider used as capability.
+func StartProviderCall(ctx context.Context, provider, capability, model string) (context.Context, *ProviderCall) {
+	ctx, s := Start(ctx, "provider "+provider+" "+capability,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("awe.provider", provider),
+			attribute.String("awe.capability", capability),
+		),
+	)
+	return ctx, &ProviderCall{
+		span:       s,
+		provider:   provider,
+		capability: capability,
+		model:      model,
+		start:      time.Now(),
+	}
+}
+
+// End ends the call, with the model and tokens reported by the provider.
+// The model of the call is kept if model is empty.
+func (c *ProviderCall) End(model string, inputTokens, outputTokens int, err error) {
+	providerRequests.WithLabelValues(c.provider, c.capability, status(err)).Inc()
+	providerDuration.WithLabelValues(c.provider, c.capability).Observe(time.Since(c.start).Seconds())
+
+	if model == "" {
+		model = c.model
+	}
+	if inputTokens > 0 {
+		providerTokens.WithLabelValues(c.provider, model, "input").Add(float64(inputTokens))
+	}
+	if outputTokens > 0 {
+		providerTokens.WithLabelValues(c.provider, model, "output").Add(float64(outputTokens))
+	}
+
+	c.span.SetAttributes(
+		attribute.String("awe.model", model),
+		attribute.Int("awe.tokens.input", inputTokens),
+		attribute.Int("awe.tokens.output", outputTokens),
+	)
+	End(c.span, err)
+}
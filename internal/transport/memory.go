@@ -0,0 +1,557 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alan-mat/awe/internal/usage"
+)
+
+// memoryPruneInterval is the minimum interval between two prunings
+// of the expired entries of a MemoryTransport.
+const memoryPruneInterval = time.Second
+
+// MemoryTransport is an in-process Transport implementation which keeps
+// traces and message streams in memory. It is intended for local development,
+// testing and running AWE without Redis.
+type MemoryTransport struct {
+	mu          sync.Mutex
+	prunedAt    time.Time
+	traces      map[string]*memoryTrace
+	streams     map[string]*memoryStreamLog
+	checkpoints map[string][]byte
+	usage       map[string]usage.Totals
+	requests    map[string]*memoryCounter
+	slots       map[string]map[string]time.Time
+	sessions    map[string][]byte
+	cancels     map[string]chan struct{}
+	clients     map[string]int
+}
+
+type memoryCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+type memoryTrace struct {
+	trace     RequestTrace
+	expiresAt time.Time
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{
+		traces:      make(map[string]*memoryTrace),
+		streams:     make(map[string]*memoryStreamLog),
+		checkpoints: make(map[string][]byte),
+		usage:       make(map[string]usage.Totals),
+		requests:    make(map[string]*memoryCounter),
+		slots:       make(map[string]map[string]time.Time),
+		sessions:    make(map[string][]byte),
+		cancels:     make(map[string]chan struct{}),
+		clients:     make(map[string]int),
+	}
+}
+
+func (t *MemoryTransport) SetTrace(ctx context.Context, trace *RequestTrace) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneExpired()
+	t.traces[trace.ID] = &memoryTrace{
+		trace:     *trace,
+		expiresAt: traceExpiresAt(trace),
+	}
+	return nil
+}
+
+func (t *MemoryTransport) GetTrace(ctx context.Context, traceId string) (*RequestTrace, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneExpired()
+	mt, ok := t.traces[traceId]
+	if !ok || time.Now().After(mt.expiresAt) {
+		return nil, fmt.Errorf("failed to retrieve trace with id '%s': not found", traceId)
+	}
+
+	trace := mt.trace
+	return &trace, nil
+}
+
+func (t *MemoryTransport) ListTraces(ctx context.Context, filter TraceFilter, cursor string, limit int) ([]*RequestTrace, string, error) {
+	var c *traceCursor
+	if cursor != "" {
+		parsed, err := parseTraceCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		c = &parsed
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneExpired()
+	traces := make([]*RequestTrace, 0)
+	now := time.Now()
+	for _, mt := range t.traces {
+		if now.After(mt.expiresAt) || !filter.Matches(&mt.trace) {
+			continue
+		}
+		if c != nil && !c.after(mt.trace.StartedAt, mt.trace.ID, 1) {
+			continue
+		}
+		trace := mt.trace
+		trace.Spans = nil
+		traces = append(traces, &trace)
+	}
+
+	slices.SortFunc(traces, func(a, b *RequestTrace) int {
+		return cmp.Or(
+			cmp.Compare(b.StartedAt, a.StartedAt),
+			cmp.Compare(b.ID, a.ID),
+		)
+	})
+
+	if len(traces) <= limit {
+		return traces, "", nil
+	}
+	traces = traces[:limit]
+	return traces, newTraceCursor(traces[limit-1]), nil
+}
+
+func (t *MemoryTransport) SetCheckpoint(ctx context.Context, id string, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.checkpoints[id] = slices.Clone(data)
+	return nil
+}
+
+func (t *MemoryTransport) GetCheckpoint(ctx context.Context, id string) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneExpired()
+	data, ok := t.checkpoints[id]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+	return slices.Clone(data), nil
+}
+
+func (t *MemoryTransport) DeleteCheckpoint(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.checkpoints, id)
+	return nil
+}
+
+// SetSession stores a copy of the session, sessions don't expire in memory.
+func (t *MemoryTransport) SetSession(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions[session.ID] = data
+	return nil
+}
+
+func (t *MemoryTransport) GetSession(ctx context.Context, id string) (*Session, error) {
+	t.mu.Lock()
+	data, ok := t.sessions[id]
+	t.mu.Unlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session with id '%s': %w", id, err)
+	}
+	return &session, nil
+}
+
+func (t *MemoryTransport) UpdateSession(ctx context.Context, id string, update func(*Session) error) error {
+	for {
+		t.mu.Lock()
+		data, ok := t.sessions[id]
+		t.mu.Unlock()
+		if !ok {
+			return ErrSessionNotFound
+		}
+
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal session with id '%s': %w", id, err)
+		}
+		if err := update(&session); err != nil {
+			return err
+		}
+		updated, err := json.Marshal(&session)
+		if err != nil {
+			return fmt.Errorf("failed to marshal session: %w", err)
+		}
+
+		// the session is only stored if it did not change while it was updated
+		t.mu.Lock()
+		current, ok := t.sessions[id]
+		if ok && bytes.Equal(current, data) {
+			t.sessions[id] = updated
+			t.mu.Unlock()
+			return nil
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *MemoryTransport) DeleteSession(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, id)
+	return nil
+}
+
+func (t *MemoryTransport) CancelTrace(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.cancelChan(id)
+	select {
+	case <-c:
+	default:
+		close(c)
+	}
+	return nil
+}
+
+func (t *MemoryTransport) IsCancelled(ctx context.Context, id string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.cancelChan(id):
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (t *MemoryTransport) WaitCancel(ctx context.Context, id string) error {
+	t.mu.Lock()
+	c := t.cancelChan(id)
+	t.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c:
+		return nil
+	}
+}
+
+// cancelChan returns the channel closed once the request id is cancelled.
+// The caller must hold t.mu.
+func (t *MemoryTransport) cancelChan(id string) chan struct{} {
+	c, ok := t.cancels[id]
+	if !ok {
+		c = make(chan struct{})
+		t.cancels[id] = c
+	}
+	return c
+}
+
+func (t *MemoryTransport) AttachClient(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clients[id] += 1
+	return nil
+}
+
+func (t *MemoryTransport) DetachClient(ctx context.Context, id string) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := max(t.clients[id]-1, 0)
+	if n == 0 {
+		delete(t.clients, id)
+	} else {
+		t.clients[id] = n
+	}
+	return n, nil
+}
+
+func (t *MemoryTransport) AddUsage(ctx context.Context, period string, scopes []UsageScope, totals usage.Totals) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, scope := range scopes {
+		key := scope.key(period)
+		u := t.usage[key]
+		u.Requests += totals.Requests
+		u.Calls += totals.Calls
+		u.InputTokens += totals.InputTokens
+		u.OutputTokens += totals.OutputTokens
+		u.Units += totals.Units
+		u.Cost += totals.Cost
+		t.usage[key] = u
+	}
+	return nil
+}
+
+func (t *MemoryTransport) GetUsage(ctx context.Context, period string, scope UsageScope) (usage.Totals, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.usage[scope.key(period)], nil
+}
+
+func (t *MemoryTransport) AcquireRequests(ctx context.Context, limits []QuotaLimit, window time.Duration) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for key, c := range t.requests {
+		if now.After(c.expiresAt) {
+			delete(t.requests, key)
+		}
+	}
+
+	for i, l := range limits {
+		if c, ok := t.requests[l.Scope.requestsKey(window, now)]; ok && c.count >= l.Limit {
+			return i, nil
+		}
+	}
+	for _, l := range limits {
+		key := l.Scope.requestsKey(window, now)
+		c, ok := t.requests[key]
+		if !ok {
+			c = &memoryCounter{expiresAt: now.Add(window)}
+			t.requests[key] = c
+		}
+		c.count++
+	}
+	return -1, nil
+}
+
+func (t *MemoryTransport) AcquireSlots(ctx context.Context, limits []QuotaLimit, id string, timeout time.Duration) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for i, l := range limits {
+		slots := t.slots[l.Scope.slotsKey()]
+		for slotID, expiresAt := range slots {
+			if now.After(expiresAt) {
+				delete(slots, slotID)
+			}
+		}
+		if _, held := slots[id]; !held && len(slots) >= l.Limit {
+			return i, nil
+		}
+	}
+	for _, l := range limits {
+		key := l.Scope.slotsKey()
+		slots, ok := t.slots[key]
+		if !ok {
+			slots = make(map[string]time.Time)
+			t.slots[key] = slots
+		}
+		slots[id] = now.Add(timeout)
+	}
+	return -1, nil
+}
+
+func (t *MemoryTransport) ReleaseSlots(ctx context.Context, scopes []UsageScope, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, scope := range scopes {
+		key := scope.slotsKey()
+		delete(t.slots[key], id)
+		if len(t.slots[key]) == 0 {
+			delete(t.slots, key)
+		}
+	}
+	return nil
+}
+
+// GetMessageStream returns the stream of the request id. Streams are read by the
+// server before the worker creates the trace of the request, streams of requests
+// without a trace are therefore kept until TraceExpiry after they were created.
+func (t *MemoryTransport) GetMessageStream(id string) (MessageStream, error) {
+	if len(id) == 0 {
+		return nil, fmt.Errorf("invalid stream ID")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pruneExpired()
+	log, ok := t.streams[id]
+	if !ok {
+		log = newMemoryStreamLog(time.Now().Add(TraceExpiry))
+		t.streams[id] = log
+	}
+
+	ms := &MemoryStream{
+		id:  fmt.Sprintf("awe:stream:%s", id),
+		log: log,
+	}
+	return ms, nil
+}
+
+// pruneExpired removes expired traces along with their message streams, checkpoints
+// and cancellations, as well as the expired streams of requests without a trace.
+// Pruning runs at most once per memoryPruneInterval.
+// The caller must hold t.mu.
+func (t *MemoryTransport) pruneExpired() {
+	now := time.Now()
+	if now.Sub(t.prunedAt) < memoryPruneInterval {
+		return
+	}
+	t.prunedAt = now
+
+	for id, mt := range t.traces {
+		if now.After(mt.expiresAt) {
+			t.deleteRequest(id)
+		}
+	}
+	for id, log := range t.streams {
+		if _, ok := t.traces[id]; !ok && now.After(log.expiresAt) {
+			t.deleteRequest(id)
+		}
+	}
+}
+
+// deleteRequest removes everything stored for the request id.
+// The caller must hold t.mu.
+func (t *MemoryTransport) deleteRequest(id string) {
+	delete(t.traces, id)
+	delete(t.streams, id)
+	delete(t.checkpoints, id)
+	delete(t.cancels, id)
+}
+
+type memoryStreamLog struct {
+	mu       sync.Mutex
+	messages []MessageStreamPayload
+
+	// expiresAt applies as long as the request has no trace
+	expiresAt time.Time
+
+	// notify is closed and replaced whenever a message is appended
+	notify chan struct{}
+}
+
+func newMemoryStreamLog(expiresAt time.Time) *memoryStreamLog {
+	return &memoryStreamLog{
+		messages:  make([]MessageStreamPayload, 0),
+		expiresAt: expiresAt,
+		notify:    make(chan struct{}),
+	}
+}
+
+func (l *memoryStreamLog) append(payload MessageStreamPayload) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.messages = append(l.messages, payload)
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// MemoryStream is a MessageStream backed by a MemoryTransport.
+// Each MemoryStream keeps its own read offset, like a RedisStream does.
+type MemoryStream struct {
+	id     string
+	offset int
+
+	log *memoryStreamLog
+}
+
+// Send fails if ctx is done, like a RedisStream does, so that
+// cancelled workflows don't write to the stream.
+func (s *MemoryStream) Send(ctx context.Context, payload MessageStreamPayload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.log.append(payload)
+	return nil
+}
+
+func (s *MemoryStream) Recv(ctx context.Context) (*MessageStreamPayload, error) {
+	for {
+		s.log.mu.Lock()
+		if s.offset < len(s.log.messages) {
+			payload := s.log.messages[s.offset]
+			s.offset += 1
+			s.log.mu.Unlock()
+			return &payload, nil
+		}
+		notify := s.log.notify
+		s.log.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+func (s *MemoryStream) Text(ctx context.Context) (string, error) {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+
+	var text string
+	for _, payload := range s.log.messages {
+		if payload.Status == "OK" {
+			text += payload.Content
+		}
+	}
+	return text, nil
+}
+
+func (s *MemoryStream) Len(ctx context.Context) (int, error) {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+
+	return len(s.log.messages), nil
+}
+
+func (s *MemoryStream) GetID() string {
+	return s.id
+}
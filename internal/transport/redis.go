@@ -24,8 +24,14 @@ package transport
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
 
+	"github.com/alan-mat/awe/internal/usage"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -39,21 +45,123 @@ func NewRedisTransport(rdb *redis.Client) *RedisTransport {
 	}
 }
 
+// Traces are indexed in sorted sets of their ids scored by their start in
+// microseconds, which are exact as float scores. The expiry index holds the
+// indexed traces scored by their expiry in milliseconds, to remove expired
+// traces from the other indexes.
+const (
+	traceIndexKey       = "awe:traces"
+	traceExpiryIndexKey = "awe:traces:expiry"
+
+	// tracePruneBatch is the maximum of expired traces removed
+	// from the indexes whenever a trace is set.
+	tracePruneBatch = 100
+)
+
+var traceStatuses = []int{
+	TraceStatusUnspecified,
+	TraceStatusRunning,
+	TraceStatusCompleted,
+	TraceStatusFailed,
+	TraceStatusCancelled,
+}
+
+func traceUserIndexKey(user string) string {
+	return "awe:traces:user:" + user
+}
+
+func traceWorkflowIndexKey(workflow string) string {
+	return "awe:traces:workflow:" + workflow
+}
+
+func traceStatusIndexKey(status int) string {
+	return "awe:traces:status:" + strconv.Itoa(status)
+}
+
+// indexedTrace is the member of a trace in the expiry index,
+// holding what is needed to remove it from the other indexes.
+type indexedTrace struct {
+	ID       string `json:"id"`
+	User     string `json:"user,omitempty"`
+	Workflow string `json:"workflow,omitempty"`
+}
+
 func (t RedisTransport) SetTrace(ctx context.Context, trace *RequestTrace) error {
 	key := fmt.Sprintf("awe:trace:%s", trace.ID)
-	_, err := t.rdb.HSet(ctx, key, trace).Result()
+	expiresAt := traceExpiresAt(trace)
+
+	member, err := json.Marshal(indexedTrace{ID: trace.ID, User: trace.User, Workflow: trace.Workflow})
 	if err != nil {
-		return fmt.Errorf("failed to set trace: %w", err)
+		return fmt.Errorf("failed to marshal trace index: %w", err)
 	}
 
-	_, err = t.rdb.Expire(ctx, key, TraceExpiry).Result()
+	_, err = t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, trace)
+		pipe.ExpireAt(ctx, key, expiresAt)
+
+		z := redis.Z{Score: float64(trace.StartedAt / 1000), Member: trace.ID}
+		pipe.ZAdd(ctx, traceIndexKey, z)
+		if trace.User != "" {
+			pipe.ZAdd(ctx, traceUserIndexKey(trace.User), z)
+		}
+		if trace.Workflow != "" {
+			pipe.ZAdd(ctx, traceWorkflowIndexKey(trace.Workflow), z)
+		}
+		for _, status := range traceStatuses {
+			if status != trace.Status {
+				pipe.ZRem(ctx, traceStatusIndexKey(status), trace.ID)
+			}
+		}
+		pipe.ZAdd(ctx, traceStatusIndexKey(trace.Status), z)
+
+		pipe.ZAdd(ctx, traceExpiryIndexKey, redis.Z{Score: float64(expiresAt.UnixMilli()), Member: member})
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to set trace expiry: %w", err)
+		return fmt.Errorf("failed to set trace: %w", err)
 	}
 
+	if err := t.pruneTraceIndexes(ctx); err != nil {
+		slog.Warn("failed to prune trace indexes", "err", err)
+	}
 	return nil
 }
 
+// pruneTraceIndexes removes expired traces from the indexes.
+func (t RedisTransport) pruneTraceIndexes(ctx context.Context) error {
+	members, err := t.rdb.ZRangeByScore(ctx, traceExpiryIndexKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Count: tracePruneBatch,
+	}).Result()
+	if err != nil || len(members) == 0 {
+		return err
+	}
+
+	_, err = t.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, member := range members {
+			pipe.ZRem(ctx, traceExpiryIndexKey, member)
+
+			var it indexedTrace
+			if err := json.Unmarshal([]byte(member), &it); err != nil {
+				continue
+			}
+			pipe.ZRem(ctx, traceIndexKey, it.ID)
+			if it.User != "" {
+				pipe.ZRem(ctx, traceUserIndexKey(it.User), it.ID)
+			}
+			if it.Workflow != "" {
+				pipe.ZRem(ctx, traceWorkflowIndexKey(it.Workflow), it.ID)
+			}
+			for _, status := range traceStatuses {
+				pipe.ZRem(ctx, traceStatusIndexKey(status), it.ID)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
 func (t RedisTransport) GetTrace(ctx context.Context, traceId string) (*RequestTrace, error) {
 	key := fmt.Sprintf("awe:trace:%s", traceId)
 	var trace RequestTrace
@@ -65,6 +173,456 @@ func (t RedisTransport) GetTrace(ctx context.Context, traceId string) (*RequestT
 	return &trace, nil
 }
 
+// traceListFields are the fields of listed traces, which don't include spans.
+var traceListFields = []string{
+	"id", "status", "started_at", "completed_at", "query", "user",
+	"workflow", "workflow_version", "usage", "spans_dropped", "expires_at",
+}
+
+// traceListExpiry bounds how long the intersection of the indexes
+// of a filter is kept, in case it is not removed after listing.
+const traceListExpiry = time.Minute
+
+// ListTraces reads the index of the user, workflow and status of the filter,
+// or their intersection if several are set, and reads the traces in batches
+// narrowing the range of scores to the last one read.
+func (t RedisTransport) ListTraces(ctx context.Context, filter TraceFilter, cursor string, limit int) ([]*RequestTrace, string, error) {
+	var c *traceCursor
+	if cursor != "" {
+		parsed, err := parseTraceCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		c = &parsed
+	}
+
+	var keys []string
+	if filter.User != "" {
+		keys = append(keys, traceUserIndexKey(filter.User))
+	}
+	if filter.Workflow != "" {
+		keys = append(keys, traceWorkflowIndexKey(filter.Workflow))
+	}
+	if filter.Status != TraceStatusUnspecified {
+		keys = append(keys, traceStatusIndexKey(filter.Status))
+	}
+
+	key := traceIndexKey
+	switch {
+	case len(keys) == 1:
+		key = keys[0]
+	case len(keys) > 1:
+		key = "awe:traces:list:" + uuid.NewString()
+		_, err := t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			// all indexes score a trace by its start
+			pipe.ZInterStore(ctx, key, &redis.ZStore{Keys: keys, Aggregate: "MIN"})
+			pipe.Expire(ctx, key, traceListExpiry)
+			return nil
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list traces: %w", err)
+		}
+		defer func() {
+			if err := t.rdb.Del(context.WithoutCancel(ctx), key).Err(); err != nil {
+				slog.Warn("failed to remove trace list", "key", key, "err", err)
+			}
+		}()
+	}
+
+	// scores are bounded inclusively, the exact bounds are applied by the filter
+	rng := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: int64(max(limit, 50))}
+	if filter.StartedAfter != 0 {
+		rng.Min = strconv.FormatInt(filter.StartedAfter/1000, 10)
+	}
+	if filter.StartedBefore != 0 {
+		rng.Max = strconv.FormatInt(filter.StartedBefore/1000, 10)
+	}
+	if c != nil && (filter.StartedBefore == 0 || c.startedAt < filter.StartedBefore) {
+		rng.Max = strconv.FormatInt(c.startedAt/1000, 10)
+	}
+
+	traces := make([]*RequestTrace, 0, limit)
+	for {
+		zs, err := t.rdb.ZRevRangeByScoreWithScores(ctx, key, rng).Result()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list traces: %w", err)
+		}
+
+		cmds, err := t.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, z := range zs {
+				pipe.HMGet(ctx, fmt.Sprintf("awe:trace:%v", z.Member), traceListFields...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list traces: %w", err)
+		}
+
+		for i, cmd := range cmds {
+			var trace RequestTrace
+			if err := cmd.(*redis.SliceCmd).Scan(&trace); err != nil {
+				return nil, "", fmt.Errorf("failed to retrieve trace with id '%v': %w", zs[i].Member, err)
+			}
+			// expired traces stay indexed until they are pruned
+			if trace.ID == "" || !filter.Matches(&trace) {
+				continue
+			}
+			if c != nil && !c.after(trace.StartedAt, trace.ID, 1000) {
+				continue
+			}
+
+			traces = append(traces, &trace)
+			if len(traces) == limit {
+				return traces, newTraceCursor(&trace), nil
+			}
+		}
+
+		if int64(len(zs)) < rng.Count {
+			return traces, "", nil
+		}
+
+		// the next batch starts at the last score read, skipping the traces
+		// with that score which were read already
+		last := zs[len(zs)-1].Score
+		n := 0
+		for i := len(zs) - 1; i >= 0 && zs[i].Score == last; i-- {
+			n++
+		}
+		if next := strconv.FormatFloat(last, 'f', -1, 64); next == rng.Max {
+			rng.Offset += int64(n)
+		} else {
+			rng.Max = next
+			rng.Offset = int64(n)
+		}
+	}
+}
+
+func (t RedisTransport) SetCheckpoint(ctx context.Context, id string, data []byte) error {
+	key := fmt.Sprintf("awe:checkpoint:%s", id)
+	_, err := t.rdb.Set(ctx, key, data, TraceExpiry).Result()
+	if err != nil {
+		return fmt.Errorf("failed to set checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (t RedisTransport) GetCheckpoint(ctx context.Context, id string) ([]byte, error) {
+	key := fmt.Sprintf("awe:checkpoint:%s", id)
+	data, err := t.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCheckpointNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve checkpoint with id '%s': %w", id, err)
+	}
+	return data, nil
+}
+
+func (t RedisTransport) DeleteCheckpoint(ctx context.Context, id string) error {
+	key := fmt.Sprintf("awe:checkpoint:%s", id)
+	_, err := t.rdb.Del(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (t RedisTransport) SetSession(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	key := fmt.Sprintf("awe:session:%s", session.ID)
+	_, err = t.rdb.Set(ctx, key, data, SessionExpiry).Result()
+	if err != nil {
+		return fmt.Errorf("failed to set session: %w", err)
+	}
+	return nil
+}
+
+func (t RedisTransport) GetSession(ctx context.Context, id string) (*Session, error) {
+	key := fmt.Sprintf("awe:session:%s", id)
+	data, err := t.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve session with id '%s': %w", id, err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session with id '%s': %w", id, err)
+	}
+	return &session, nil
+}
+
+// sessionUpdateAttempts is the maximum of attempts of UpdateSession,
+// if the session keeps being changed by others.
+const sessionUpdateAttempts = 10
+
+// UpdateSession watches the key of the session while it is updated,
+// the update is retried if the key changed before it was stored.
+func (t RedisTransport) UpdateSession(ctx context.Context, id string, update func(*Session) error) error {
+	key := fmt.Sprintf("awe:session:%s", id)
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retrieve session with id '%s': %w", id, err)
+		}
+
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal session with id '%s': %w", id, err)
+		}
+		if err := update(&session); err != nil {
+			return err
+		}
+		data, err = json.Marshal(&session)
+		if err != nil {
+			return fmt.Errorf("failed to marshal session: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, SessionExpiry)
+			return nil
+		})
+		return err
+	}
+
+	for range sessionUpdateAttempts {
+		err := t.rdb.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to update session with id '%s': changed by too many concurrent updates", id)
+}
+
+func (t RedisTransport) DeleteSession(ctx context.Context, id string) error {
+	key := fmt.Sprintf("awe:session:%s", id)
+	_, err := t.rdb.Del(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// CancelTrace sets a cancellation key, which is seen by requests whose processing
+// starts later, and notifies the requests processed at the moment.
+func (t RedisTransport) CancelTrace(ctx context.Context, id string) error {
+	key := fmt.Sprintf("awe:cancel:%s", id)
+	_, err := t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, 1, TraceExpiry)
+		pipe.Publish(ctx, key, 1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel trace: %w", err)
+	}
+	return nil
+}
+
+func (t RedisTransport) IsCancelled(ctx context.Context, id string) (bool, error) {
+	key := fmt.Sprintf("awe:cancel:%s", id)
+	n, err := t.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check cancellation: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (t RedisTransport) WaitCancel(ctx context.Context, id string) error {
+	key := fmt.Sprintf("awe:cancel:%s", id)
+	sub := t.rdb.Subscribe(ctx, key)
+	defer sub.Close()
+
+	// the subscription must be confirmed before checking the key,
+	// otherwise a cancellation in between is missed
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to cancellation: %w", err)
+	}
+
+	n, err := t.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check cancellation: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sub.Channel():
+		return nil
+	}
+}
+
+func (t RedisTransport) AttachClient(ctx context.Context, id string) error {
+	key := fmt.Sprintf("awe:clients:%s", id)
+	_, err := t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, TraceExpiry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach client: %w", err)
+	}
+	return nil
+}
+
+// detachClientScript decrements the count of clients in KEYS[1] and returns
+// the remaining count. The key is removed once no clients are left, counts
+// of expired keys, which become negative, are treated as no clients.
+var detachClientScript = redis.NewScript(`
+local n = redis.call('DECR', KEYS[1])
+if n <= 0 then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+return n
+`)
+
+func (t RedisTransport) DetachClient(ctx context.Context, id string) (int, error) {
+	key := fmt.Sprintf("awe:clients:%s", id)
+	n, err := detachClientScript.Run(ctx, t.rdb, []string{key}).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to detach client: %w", err)
+	}
+	return n, nil
+}
+
+func (t RedisTransport) AddUsage(ctx context.Context, period string, scopes []UsageScope, totals usage.Totals) error {
+	_, err := t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, scope := range scopes {
+			key := scope.key(period)
+			pipe.HIncrBy(ctx, key, "requests", totals.Requests)
+			pipe.HIncrBy(ctx, key, "calls", totals.Calls)
+			pipe.HIncrBy(ctx, key, "input_tokens", totals.InputTokens)
+			pipe.HIncrBy(ctx, key, "output_tokens", totals.OutputTokens)
+			pipe.HIncrBy(ctx, key, "units", totals.Units)
+			pipe.HIncrByFloat(ctx, key, "cost", totals.Cost)
+			pipe.Expire(ctx, key, usageExpiry(period))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add usage: %w", err)
+	}
+	return nil
+}
+
+func (t RedisTransport) GetUsage(ctx context.Context, period string, scope UsageScope) (usage.Totals, error) {
+	var u struct {
+		Requests     int64   `redis:"requests"`
+		Calls        int64   `redis:"calls"`
+		InputTokens  int64   `redis:"input_tokens"`
+		OutputTokens int64   `redis:"output_tokens"`
+		Units        int64   `redis:"units"`
+		Cost         float64 `redis:"cost"`
+	}
+	err := t.rdb.HGetAll(ctx, scope.key(period)).Scan(&u)
+	if err != nil {
+		return usage.Totals{}, fmt.Errorf("failed to retrieve usage: %w", err)
+	}
+	return usage.Totals(u), nil
+}
+
+// acquireRequestsScript counts a request in each of KEYS, unless the count
+// of any key reached its limit in ARGV[1+i], and returns the 0-based index
+// of the exceeded limit or -1. ARGV[1] is the window in milliseconds.
+var acquireRequestsScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	if tonumber(redis.call('GET', key) or '0') >= tonumber(ARGV[1 + i]) then
+		return i - 1
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('INCR', key)
+	redis.call('PEXPIRE', key, ARGV[1])
+end
+return -1
+`)
+
+func (t RedisTransport) AcquireRequests(ctx context.Context, limits []QuotaLimit, window time.Duration) (int, error) {
+	if len(limits) == 0 {
+		return -1, nil
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, len(limits))
+	args := make([]any, 0, len(limits)+1)
+	args = append(args, window.Milliseconds())
+	for _, l := range limits {
+		keys = append(keys, l.Scope.requestsKey(window, now))
+		args = append(args, l.Limit)
+	}
+
+	i, err := acquireRequestsScript.Run(ctx, t.rdb, keys, args...).Int()
+	if err != nil {
+		return -1, fmt.Errorf("failed to count request: %w", err)
+	}
+	return i, nil
+}
+
+// acquireSlotsScript adds the request ARGV[4] to each of the sorted sets of
+// slots in KEYS, unless the set already holds the limit of slots in ARGV[4+i],
+// and returns the 0-based index of the exceeded limit or -1. Slots are scored
+// by the time they expire at, slots expired at ARGV[1] are removed first.
+// ARGV[2] is the expiry of the added slots and ARGV[3] the timeout of the sets.
+var acquireSlotsScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', ARGV[1])
+	if not redis.call('ZSCORE', key, ARGV[4]) and redis.call('ZCARD', key) >= tonumber(ARGV[4 + i]) then
+		return i - 1
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('ZADD', key, ARGV[2], ARGV[4])
+	redis.call('PEXPIRE', key, ARGV[3])
+end
+return -1
+`)
+
+func (t RedisTransport) AcquireSlots(ctx context.Context, limits []QuotaLimit, id string, timeout time.Duration) (int, error) {
+	if len(limits) == 0 {
+		return -1, nil
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, len(limits))
+	args := make([]any, 0, len(limits)+4)
+	args = append(args, now.UnixMilli(), now.Add(timeout).UnixMilli(), timeout.Milliseconds(), id)
+	for _, l := range limits {
+		keys = append(keys, l.Scope.slotsKey())
+		args = append(args, l.Limit)
+	}
+
+	i, err := acquireSlotsScript.Run(ctx, t.rdb, keys, args...).Int()
+	if err != nil {
+		return -1, fmt.Errorf("failed to acquire slots: %w", err)
+	}
+	return i, nil
+}
+
+func (t RedisTransport) ReleaseSlots(ctx context.Context, scopes []UsageScope, id string) error {
+	_, err := t.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, scope := range scopes {
+			pipe.ZRem(ctx, scope.slotsKey(), id)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release slots: %w", err)
+	}
+	return nil
+}
+
 func (t *RedisTransport) GetMessageStream(id string) (MessageStream, error) {
 	if len(id) == 0 {
 		return nil, fmt.Errorf("invalid stream ID")
@@ -155,6 +713,14 @@ func (s *RedisStream) Text(ctx context.Context) (string, error) {
 	return text, nil
 }
 
+func (s *RedisStream) Len(ctx context.Context) (int, error) {
+	n, err := s.rdb.XLen(ctx, s.id).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
 func (s *RedisStream) GetID() string {
 	return s.id
 }
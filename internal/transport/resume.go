@@ -0,0 +1,111 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ResumeTransport wraps a Transport for a request which is resumed from a
+// checkpoint. Messages which were sent to the request's stream after the
+// checkpoint was taken are not sent again, as long as the resumed execution
+// sends identical messages in the same order. On the first differing
+// message, all further messages are sent as usual.
+type ResumeTransport struct {
+	Transport
+
+	id    string
+	dedup *streamDedup
+}
+
+// NewResumeTransport creates a ResumeTransport for the request with the given id.
+// offset is the number of messages in the stream when the checkpoint was taken.
+func NewResumeTransport(ctx context.Context, t Transport, id string, offset int) (*ResumeTransport, error) {
+	ms, err := t.GetMessageStream(id)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := ms.Len(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream length: %w", err)
+	}
+
+	pending := make([]MessageStreamPayload, 0, max(total-offset, 0))
+	for i := range total {
+		payload, err := ms.Recv(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sent messages: %w", err)
+		}
+		if i >= offset {
+			pending = append(pending, *payload)
+		}
+	}
+
+	rt := &ResumeTransport{
+		Transport: t,
+		id:        id,
+		dedup:     &streamDedup{pending: pending},
+	}
+	return rt, nil
+}
+
+func (t *ResumeTransport) GetMessageStream(id string) (MessageStream, error) {
+	ms, err := t.Transport.GetMessageStream(id)
+	if err != nil || id != t.id {
+		return ms, err
+	}
+	return &resumeStream{MessageStream: ms, dedup: t.dedup}, nil
+}
+
+type streamDedup struct {
+	mu      sync.Mutex
+	pending []MessageStreamPayload
+}
+
+// skip reports whether payload was already sent before resuming.
+func (d *streamDedup) skip(payload MessageStreamPayload) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.pending) > 0 && d.pending[0] == payload {
+		d.pending = d.pending[1:]
+		return true
+	}
+	d.pending = nil
+	return false
+}
+
+type resumeStream struct {
+	MessageStream
+
+	dedup *streamDedup
+}
+
+func (s *resumeStream) Send(ctx context.Context, payload MessageStreamPayload) error {
+	if s.dedup.skip(payload) {
+		return nil
+	}
+	return s.MessageStream.Send(ctx, payload)
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+// SessionExpiry is how long a session is kept after its last turn.
+var SessionExpiry = time.Hour * 24 * 30
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a conversation with a workflow, whose history is kept
+// by the server instead of being sent by the client with every turn.
+type Session struct {
+	ID   string `json:"id"`
+	User string `json:"user"`
+	// Workflow is the workflow reference ('name' or 'name@version') of the session.
+	Workflow string `json:"workflow"`
+	// Args are passed to the workflow in every turn, before the args of the turn.
+	Args map[string]string `json:"args,omitempty"`
+
+	History []*api.ChatMessage `json:"history,omitempty"`
+	// Summary summarizes the messages removed from the history, if the
+	// history policy of the workflow summarizes them.
+	Summary string `json:"summary,omitempty"`
+
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
+}
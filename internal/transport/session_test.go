@@ -0,0 +1,88 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+func TestMemoryUpdateSessionKeepsConcurrentUpdates(t *testing.T) {
+	tr := NewMemoryTransport()
+	ctx := context.Background()
+	if err := tr.SetSession(ctx, &Session{ID: "s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := tr.UpdateSession(ctx, "s", func(s *Session) error {
+				s.History = append(s.History, &api.ChatMessage{Role: api.RoleUser, Content: "q"})
+				return nil
+			})
+			if err != nil {
+				t.Errorf("failed to update session: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s, err := tr.GetSession(ctx, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.History) != 20 {
+		t.Errorf("history has %d messages, want 20", len(s.History))
+	}
+}
+
+func TestMemoryUpdateSessionErrors(t *testing.T) {
+	tr := NewMemoryTransport()
+	ctx := context.Background()
+
+	err := tr.UpdateSession(ctx, "missing", func(s *Session) error { return nil })
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrSessionNotFound)
+	}
+
+	if err := tr.SetSession(ctx, &Session{ID: "s", Summary: "before"}); err != nil {
+		t.Fatal(err)
+	}
+	errUpdate := errors.New("update failed")
+	err = tr.UpdateSession(ctx, "s", func(s *Session) error {
+		s.Summary = "after"
+		return errUpdate
+	})
+	if !errors.Is(err, errUpdate) {
+		t.Errorf("err = %v, want %v", err, errUpdate)
+	}
+	if s, _ := tr.GetSession(ctx, "s"); s.Summary != "before" {
+		t.Errorf("session stored despite failed update, summary = %q", s.Summary)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TraceFilter selects the traces returned by ListTraces,
+// fields which are not set match all traces.
+type TraceFilter struct {
+	User     string
+	Workflow string
+	Status   int
+
+	// StartedAfter and StartedBefore limit the start of traces,
+	// in Unix nanoseconds, to [StartedAfter, StartedBefore).
+	StartedAfter  int64
+	StartedBefore int64
+}
+
+// Matches reports whether the trace matches the filter.
+func (f TraceFilter) Matches(trace *RequestTrace) bool {
+	return (f.User == "" || trace.User == f.User) &&
+		(f.Workflow == "" || trace.Workflow == f.Workflow) &&
+		(f.Status == TraceStatusUnspecified || trace.Status == f.Status) &&
+		(f.StartedAfter == 0 || trace.StartedAt >= f.StartedAfter) &&
+		(f.StartedBefore == 0 || trace.StartedAt < f.StartedBefore)
+}
+
+// traceExpiresAt returns when the trace expires, which is
+// TraceExpiry from now if the trace has no expiry set.
+func traceExpiresAt(trace *RequestTrace) time.Time {
+	if trace.ExpiresAt == 0 {
+		return time.Now().Add(TraceExpiry)
+	}
+	return time.Unix(0, trace.ExpiresAt)
+}
+
+// traceCursor is the position of the last trace of a page,
+// traces are listed by descending start and ID.
+type traceCursor struct {
+	startedAt int64
+	id        string
+}
+
+// after reports whether the trace started at startedAt with the id is listed
+// after the cursor, comparing start times in the given unit of nanoseconds.
+func (c traceCursor) after(startedAt int64, id string, unit int64) bool {
+	a, b := startedAt/unit, c.startedAt/unit
+	return a < b || (a == b && id < c.id)
+}
+
+func (c traceCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.startedAt, 10) + ":" + c.id))
+}
+
+func newTraceCursor(trace *RequestTrace) string {
+	return traceCursor{startedAt: trace.StartedAt, id: trace.ID}.String()
+}
+
+func parseTraceCursor(s string) (traceCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return traceCursor{}, ErrInvalidCursor
+	}
+
+	startedAt, id, ok := strings.Cut(string(data), ":")
+	if !ok || id == "" {
+		return traceCursor{}, ErrInvalidCursor
+	}
+	c := traceCursor{id: id}
+	if c.startedAt, err = strconv.ParseInt(startedAt, 10, 64); err != nil {
+		return traceCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
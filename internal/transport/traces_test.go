@@ -0,0 +1,135 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/span"
+)
+
+func TestParseTraceCursor(t *testing.T) {
+	want := traceCursor{startedAt: 1700000000123456789, id: "a:b"}
+	got, err := parseTraceCursor(want.String())
+	if err != nil {
+		t.Fatalf("failed to parse cursor: %v", err)
+	}
+	if got != want {
+		t.Errorf("cursor = %+v, want %+v", got, want)
+	}
+
+	invalid := []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("1700000000")),
+		base64.RawURLEncoding.EncodeToString([]byte("1700000000:")),
+		base64.RawURLEncoding.EncodeToString([]byte("start:id")),
+	}
+	for _, s := range invalid {
+		if _, err := parseTraceCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("parseTraceCursor(%q) err = %v, want %v", s, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestTraceFilterMatches(t *testing.T) {
+	trace := &RequestTrace{ID: "t", User: "alice", Workflow: "chat", Status: TraceStatusCompleted, StartedAt: 100}
+
+	tests := []struct {
+		name   string
+		filter TraceFilter
+		want   bool
+	}{
+		{name: "empty", filter: TraceFilter{}, want: true},
+		{name: "all fields", filter: TraceFilter{User: "alice", Workflow: "chat", Status: TraceStatusCompleted, StartedAfter: 100, StartedBefore: 101}, want: true},
+		{name: "user", filter: TraceFilter{User: "bob"}},
+		{name: "workflow", filter: TraceFilter{Workflow: "search"}},
+		{name: "status", filter: TraceFilter{Status: TraceStatusRunning}},
+		{name: "started after", filter: TraceFilter{StartedAfter: 101}},
+		{name: "started before", filter: TraceFilter{StartedBefore: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(trace); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryListTracesPages(t *testing.T) {
+	tr := NewMemoryTransport()
+	ctx := context.Background()
+
+	// traces t0 to t2 share their start, as do t3 to t5
+	for i := range 7 {
+		trace := &RequestTrace{
+			ID:        fmt.Sprintf("t%d", i),
+			User:      "alice",
+			StartedAt: int64(i / 3),
+			Spans:     span.Spans{{ID: 1}},
+		}
+		if i == 6 {
+			trace.User = "bob"
+		}
+		if err := tr.SetTrace(ctx, trace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []string
+	var pages int
+	cursor := ""
+	for {
+		traces, next, err := tr.ListTraces(ctx, TraceFilter{User: "alice"}, cursor, 2)
+		if err != nil {
+			t.Fatalf("failed to list traces: %v", err)
+		}
+		for _, trace := range traces {
+			if trace.Spans != nil {
+				t.Errorf("listed trace %s has spans", trace.ID)
+			}
+			ids = append(ids, trace.ID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if want := []string{"t5", "t4", "t3", "t2", "t1", "t0"}; !slices.Equal(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if pages != 3 {
+		t.Errorf("pages = %d, want 3", pages)
+	}
+
+	if _, _, err := tr.ListTraces(ctx, TraceFilter{}, "invalid!", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidCursor)
+	}
+}
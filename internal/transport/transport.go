@@ -30,16 +30,83 @@ import (
 	"time"
 
 	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/span"
+	"github.com/alan-mat/awe/internal/usage"
 )
 
 var (
 	TraceExpiry = time.Hour * 24
+
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+	// ErrTraceCancelled is the cause of the cancellation of workflows, whose trace was cancelled by a client.
+	ErrTraceCancelled = errors.New("trace cancelled")
 )
 
 type Transport interface {
 	GetMessageStream(id string) (MessageStream, error)
 	SetTrace(ctx context.Context, trace *RequestTrace) error
 	GetTrace(ctx context.Context, traceId string) (*RequestTrace, error)
+	// ListTraces returns up to limit traces matching filter, most recently
+	// started first. Listing starts after the cursor returned with the previous
+	// page, or with the first trace if cursor is empty. The returned cursor is
+	// empty once there are no more traces. Listed traces don't include their
+	// spans. Limit must be positive, an invalid cursor fails with ErrInvalidCursor.
+	ListTraces(ctx context.Context, filter TraceFilter, cursor string, limit int) ([]*RequestTrace, string, error)
+
+	// SetCheckpoint stores the execution checkpoint of a request,
+	// the data is opaque to the transport.
+	SetCheckpoint(ctx context.Context, id string, data []byte) error
+	// GetCheckpoint returns ErrCheckpointNotFound if no checkpoint exists.
+	GetCheckpoint(ctx context.Context, id string) ([]byte, error)
+	DeleteCheckpoint(ctx context.Context, id string) error
+
+	// AddUsage adds totals to the usage of each of the scopes in period,
+	// see UsagePeriod and UsageDay.
+	AddUsage(ctx context.Context, period string, scopes []UsageScope, totals usage.Totals) error
+	// GetUsage returns the usage of scope in period,
+	// which is zero if no usage was added.
+	GetUsage(ctx context.Context, period string, scope UsageScope) (usage.Totals, error)
+
+	// AcquireRequests counts a request in the current window of each of the scopes,
+	// unless the limit of requests was already counted in the window of any of them,
+	// in which case the request is counted in none. Windows start at multiples
+	// of window since the Unix epoch. It returns the index of the exceeded limit,
+	// or -1 if the request was counted.
+	AcquireRequests(ctx context.Context, limits []QuotaLimit, window time.Duration) (int, error)
+	// AcquireSlots holds one slot of each of the scopes for the request id, unless
+	// the limit of slots of any of them is held, in which case none is acquired.
+	// Slots are released by ReleaseSlots, or after timeout. It returns the index
+	// of the exceeded limit, or -1 if the slots were acquired.
+	AcquireSlots(ctx context.Context, limits []QuotaLimit, id string, timeout time.Duration) (int, error)
+	// ReleaseSlots releases the slots held by the request id in each of the scopes.
+	ReleaseSlots(ctx context.Context, scopes []UsageScope, id string) error
+
+	SetSession(ctx context.Context, session *Session) error
+	// GetSession returns ErrSessionNotFound if the session does not exist.
+	GetSession(ctx context.Context, id string) (*Session, error)
+	// UpdateSession applies update to the session id and stores the result,
+	// so that concurrent updates of a session are not lost. update is applied
+	// again if the session was changed meanwhile, it must not have other effects
+	// than changing the session. It returns ErrSessionNotFound if the session
+	// does not exist, and the error of update, if any, without storing the session.
+	UpdateSession(ctx context.Context, id string, update func(*Session) error) error
+	DeleteSession(ctx context.Context, id string) error
+
+	// CancelTrace requests the cancellation of the request id,
+	// which is also seen by requests whose processing starts later.
+	CancelTrace(ctx context.Context, id string) error
+	// IsCancelled reports whether the cancellation of the request id was requested.
+	IsCancelled(ctx context.Context, id string) (bool, error)
+	// WaitCancel blocks until the cancellation of the request id is requested,
+	// returning nil, or until ctx is done, returning its error.
+	WaitCancel(ctx context.Context, id string) error
+
+	// AttachClient counts a client following the request id.
+	AttachClient(ctx context.Context, id string) error
+	// DetachClient stops counting a client following the request id,
+	// and returns the amount of clients still following it.
+	DetachClient(ctx context.Context, id string) (int, error)
 }
 
 type MessageStream interface {
@@ -52,6 +119,9 @@ type MessageStream interface {
 	// Note this will not retrieve any Documents sent in the stream
 	Text(ctx context.Context) (string, error)
 
+	// Len returns the number of messages sent to the stream
+	Len(ctx context.Context) (int, error)
+
 	GetID() string
 }
 
@@ -85,6 +155,22 @@ type RequestTrace struct {
 	CompletedAt int64  `redis:"completed_at"`
 	Query       string `redis:"query"`
 	User        string `redis:"user"`
+
+	// Workflow and WorkflowVersion identify the
+	// workflow definition which served the request.
+	Workflow        string `redis:"workflow"`
+	WorkflowVersion string `redis:"workflow_version"`
+
+	// Usage holds the provider calls made by the request.
+	Usage usage.Records `redis:"usage"`
+	// Spans holds the workflow nodes executed for the request,
+	// SpansDropped counts those exceeding span.MaxSpans.
+	Spans        span.Spans `redis:"spans"`
+	SpansDropped int        `redis:"spans_dropped"`
+
+	// ExpiresAt is when the trace is removed, in Unix nanoseconds.
+	// Traces without expiry are kept for TraceExpiry after their last update.
+	ExpiresAt int64 `redis:"expires_at"`
 }
 
 type TraceStatus int
@@ -94,16 +180,25 @@ const (
 	TraceStatusRunning
 	TraceStatusCompleted
 	TraceStatusFailed
+	TraceStatusCancelled
 )
 
-func ProcessCompletionStream(ctx context.Context, ms MessageStream, cs api.CompletionStream) (string, error) {
-	var acc, sink string
+// ProcessCompletionStream sends the content of a completion stream to the
+// message stream and returns the completion. If the completion stream fails,
+// an error message is sent and the completion received so far is returned
+// along with the error.
+func ProcessCompletionStream(ctx context.Context, ms MessageStream, cs api.CompletionStream) (*api.Completion, error) {
+	var acc string
 	msgId := 0
+	c := &api.Completion{}
 
 	for {
-		chunk, err := cs.Recv()
+		ev, err := cs.Recv()
 		if errors.Is(err, io.EOF) {
-			return sink, nil
+			if c.Truncated() {
+				slog.Warn("completion stopped at token limit", "stream", ms.GetID(), "model", c.Model)
+			}
+			return c, nil
 		}
 
 		if err != nil {
@@ -112,13 +207,16 @@ func ProcessCompletionStream(ctx context.Context, ms MessageStream, cs api.Compl
 				Status:  "ERR",
 				Content: "something went wrong",
 			})
-			return sink, err
+			return c, err
 		}
 
-		acc += chunk
-		sink += chunk
+		c.Add(ev)
+		if ev.Type != api.StreamEventContent {
+			continue
+		}
 
-		if strings.TrimSpace(chunk) == "" {
+		acc += ev.Content
+		if strings.TrimSpace(ev.Content) == "" {
 			continue
 		}
 
@@ -0,0 +1,115 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// UsageExpiry is how long the usage of a month is kept after it was last added to.
+var UsageExpiry = time.Hour * 24 * 400
+
+// DailyUsageExpiry is how long the usage of a day is kept after it was last added to.
+var DailyUsageExpiry = time.Hour * 24 * 35
+
+// UsageScope selects the requests whose usage is summed up. Requests are
+// accounted to their user, their workflow and the pair of both.
+type UsageScope struct {
+	User     string
+	Workflow string
+}
+
+// UsageScopes returns the scopes a request of user and workflow is accounted to.
+// Requests without a user are only accounted to their workflow.
+func UsageScopes(user, workflow string) []UsageScope {
+	scopes := []UsageScope{{Workflow: workflow}}
+	if user != "" {
+		scopes = append(scopes, UsageScope{User: user}, UsageScope{User: user, Workflow: workflow})
+	}
+	return scopes
+}
+
+// QuotaLimit is the limit of requests or running requests of a scope.
+type QuotaLimit struct {
+	Scope UsageScope
+	Limit int
+}
+
+func (s UsageScope) key(period string) string {
+	return "awe:usage:" + period + s.suffix()
+}
+
+// suffix identifies the scope in keys. User and workflow are escaped,
+// so that names containing ':' can not be taken for another scope.
+func (s UsageScope) suffix() string {
+	var suffix string
+	if s.User != "" {
+		suffix += ":user:" + url.QueryEscape(s.User)
+	}
+	if s.Workflow != "" {
+		suffix += ":workflow:" + url.QueryEscape(s.Workflow)
+	}
+	return suffix
+}
+
+// UsagePeriod returns the monthly period of t, which is its month in UTC
+// in the form 'YYYY-MM'.
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format(usageMonthLayout)
+}
+
+// UsageDay returns the daily period of t, which is its day in UTC
+// in the form 'YYYY-MM-DD'.
+func UsageDay(t time.Time) string {
+	return t.UTC().Format(usageDayLayout)
+}
+
+const (
+	usageMonthLayout = "2006-01"
+	usageDayLayout   = "2006-01-02"
+)
+
+// ParseUsagePeriod returns the start of a monthly or daily period.
+func ParseUsagePeriod(period string) (time.Time, error) {
+	if len(period) == len(usageDayLayout) {
+		return time.Parse(usageDayLayout, period)
+	}
+	return time.Parse(usageMonthLayout, period)
+}
+
+func usageExpiry(period string) time.Duration {
+	if len(period) == len(usageDayLayout) {
+		return DailyUsageExpiry
+	}
+	return UsageExpiry
+}
+
+func (s UsageScope) requestsKey(window time.Duration, now time.Time) string {
+	n := now.UnixNano() / int64(window)
+	return fmt.Sprintf("awe:quota:requests:%d:%d%s", window.Milliseconds(), n, s.suffix())
+}
+
+func (s UsageScope) slotsKey() string {
+	return "awe:quota:slots" + s.suffix()
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alan-mat/awe/internal/usage"
+)
+
+func TestUsageScopeKeysAreDistinct(t *testing.T) {
+	scopes := []UsageScope{
+		{User: "alice", Workflow: "chat"},
+		{User: "alice:workflow:chat"},
+		{User: "alice:workflow", Workflow: "chat"},
+		{Workflow: "chat"},
+		{User: "alice"},
+	}
+
+	seen := make(map[string]UsageScope, len(scopes))
+	for _, scope := range scopes {
+		key := scope.key("2025-01")
+		if prev, ok := seen[key]; ok {
+			t.Errorf("scopes %+v and %+v share the key %s", prev, scope, key)
+		}
+		seen[key] = scope
+	}
+
+	if got, want := (UsageScope{User: "alice", Workflow: "chat"}).key("2025-01"), "awe:usage:2025-01:user:alice:workflow:chat"; got != want {
+		t.Errorf("key = %s, want %s", got, want)
+	}
+}
+
+func TestMemoryUsageIsAccountedPerScope(t *testing.T) {
+	tr := NewMemoryTransport()
+	ctx := context.Background()
+
+	requests := []struct {
+		user, workflow string
+		totals         usage.Totals
+	}{
+		{"alice", "chat", usage.Totals{Requests: 1, Calls: 2, InputTokens: 100, Cost: 0.5}},
+		{"alice", "search", usage.Totals{Requests: 1, Calls: 1, OutputTokens: 10}},
+		{"bob", "chat", usage.Totals{Requests: 1, Calls: 3, InputTokens: 50, Cost: 0.25}},
+		{"", "chat", usage.Totals{Requests: 1, Units: 4}},
+	}
+	for _, r := range requests {
+		if err := tr.AddUsage(ctx, "2025-01", UsageScopes(r.user, r.workflow), r.totals); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		scope UsageScope
+		want  usage.Totals
+	}{
+		{UsageScope{User: "alice"}, usage.Totals{Requests: 2, Calls: 3, InputTokens: 100, OutputTokens: 10, Cost: 0.5}},
+		{UsageScope{User: "bob"}, usage.Totals{Requests: 1, Calls: 3, InputTokens: 50, Cost: 0.25}},
+		{UsageScope{Workflow: "chat"}, usage.Totals{Requests: 3, Calls: 5, InputTokens: 150, Units: 4, Cost: 0.75}},
+		{UsageScope{User: "alice", Workflow: "chat"}, usage.Totals{Requests: 1, Calls: 2, InputTokens: 100, Cost: 0.5}},
+		{UsageScope{User: "bob", Workflow: "search"}, usage.Totals{}},
+	}
+	for _, tt := range tests {
+		got, err := tr.GetUsage(ctx, "2025-01", tt.scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("usage of %+v = %+v, want %+v", tt.scope, got, tt.want)
+		}
+	}
+
+	if got, _ := tr.GetUsage(ctx, "2025-02", UsageScope{User: "alice"}); got != (usage.Totals{}) {
+		t.Errorf("usage of another period = %+v, want none", got)
+	}
+}
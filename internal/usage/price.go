@@ -0,0 +1,87 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package usage
+
+import "sync"
+
+// Price is the price of a provider or model.
+type Price struct {
+	// Input and Output are prices per million tokens.
+	Input  float64
+	Output float64
+	// Call is the price per call, Unit the price per unit, see Record.Units.
+	Call float64
+	Unit float64
+}
+
+// Cost returns the cost of the usage r.
+func (p Price) Cost(r Record) float64 {
+	return float64(r.InputTokens)*p.Input/1e6 +
+		float64(r.OutputTokens)*p.Output/1e6 +
+		float64(r.Calls)*p.Call +
+		float64(r.Units)*p.Unit
+}
+
+// Prices maps providers and models to their price. Keys are either
+// 'provider/model', a model name, or a provider name, which are looked
+// up in that order. Models are matched exactly, as reported by the provider.
+type Prices map[string]Price
+
+// Lookup returns the price of the model of a provider.
+func (p Prices) Lookup(provider, model string) (Price, bool) {
+	keys := []string{provider}
+	if model != "" {
+		keys = []string{provider + "/" + model, model, provider}
+	}
+
+	for _, key := range keys {
+		if price, ok := p[key]; ok {
+			return price, true
+		}
+	}
+
+	return Price{}, false
+}
+
+// Cost returns the cost of the usage r, which is zero if r is not priced.
+func (p Prices) Cost(r Record) float64 {
+	price, _ := p.Lookup(r.Provider, r.Model)
+	return price.Cost(r)
+}
+
+var (
+	pricesLock sync.RWMutex
+	prices     Prices
+)
+
+// SetPrices sets the prices used to price tracked usage.
+func SetPrices(p Prices) {
+	pricesLock.Lock()
+	defer pricesLock.Unlock()
+	prices = p
+}
+
+func currentPrices() Prices {
+	pricesLock.RLock()
+	defer pricesLock.RUnlock()
+	return prices
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+// Package usage accounts for the provider calls made while serving a request.
+//
+// A Tracker is attached to the context of a request. Every provider call
+// started with StartCall is added to the tracker once it ends, along with the
+// tokens and other units reported for the call by the provider.
+package usage
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// Record is the usage of a provider and model for one capability.
+type Record struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model,omitempty"`
+	Capability string `json:"capability"`
+
+	Calls        int `json:"calls"`
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+	// Units counts usage billed in other units than tokens,
+	// e.g. pages of parsed documents or search units of rerankers.
+	Units int `json:"units,omitempty"`
+
+	Cost float64 `json:"cost,omitempty"`
+}
+
+func (r *Record) add(o Record) {
+	r.Calls += o.Calls
+	r.InputTokens += o.InputTokens
+	r.OutputTokens += o.OutputTokens
+	r.Units += o.Units
+	r.Cost += o.Cost
+}
+
+// Records is the usage of a request, it is stored as JSON.
+type Records []Record
+
+// Total sums up the records.
+func (rs Records) Total() Totals {
+	var t Totals
+	for _, r := range rs {
+		t.Calls += int64(r.Calls)
+		t.InputTokens += int64(r.InputTokens)
+		t.OutputTokens += int64(r.OutputTokens)
+		t.Units += int64(r.Units)
+		t.Cost += r.Cost
+	}
+	return t
+}
+
+func (rs Records) MarshalBinary() ([]byte, error) {
+	if rs == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Record(rs))
+}
+
+// ScanRedis decodes records stored in a redis hash.
+func (rs *Records) ScanRedis(s string) error {
+	if s == "" {
+		*rs = nil
+		return nil
+	}
+	return json.Unmarshal([]byte(s), (*[]Record)(rs))
+}
+
+// Totals is the summed up usage of a number of requests.
+type Totals struct {
+	Requests     int64
+	Calls        int64
+	InputTokens  int64
+	OutputTokens int64
+	Units        int64
+	Cost         float64
+}
+
+// Tracker accumulates the usage of a request. It is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	records map[recordKey]*Record
+}
+
+type recordKey struct {
+	provider, model, capability string
+}
+
+// NewTracker returns a tracker holding the given records,
+// e.g. the usage of a previous attempt of the request.
+func NewTracker(records ...Record) *Tracker {
+	t := &Tracker{records: make(map[recordKey]*Record)}
+	for _, r := range records {
+		t.Add(r)
+	}
+	return t
+}
+
+// Add adds r to the record of the same provider, model and capability.
+func (t *Tracker) Add(r Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := recordKey{r.Provider, r.Model, r.Capability}
+	rec, ok := t.records[key]
+	if !ok {
+		rec = &Record{Provider: r.Provider, Model: r.Model, Capability: r.Capability}
+		t.records[key] = rec
+	}
+	rec.add(r)
+}
+
+// Records returns the usage tracked so far, priced with the current prices.
+func (t *Tracker) Records() Records {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prices := currentPrices()
+	records := make(Records, 0, len(t.records))
+	for _, r := range t.records {
+		rec := *r
+		rec.Cost = prices.Cost(rec)
+		records = append(records, rec)
+	}
+
+	slices.SortFunc(records, func(a, b Record) int {
+		return cmp.Or(
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.Model, b.Model),
+			cmp.Compare(a.Capability, b.Capability),
+		)
+	})
+	return records
+}
+
+type trackerKey struct{}
+
+type callKey struct{}
+
+// WithTracker returns a context whose provider calls are tracked by t.
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext returns the tracker of ctx, or nil if its calls are not tracked.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+// Call is a provider call in progress. All methods of a nil Call do nothing,
+// so calls need not be checked for being tracked.
+type Call struct {
+	mu      sync.Mutex
+	rec     Record
+	tracker *Tracker
+	ended   bool
+}
+
+// StartCall starts a call of the provider used as capability. The returned
+// context passes the call on to the provider, which may Report its usage.
+// The call is nil if ctx has no tracker.
+func StartCall(ctx context.Context, provider, capability, model string) (context.Context, *Call) {
+	t := FromContext(ctx)
+	if t == nil {
+		return ctx, nil
+	}
+
+	c := &Call{
+		tracker: t,
+		rec: Record{
+			Provider:   provider,
+			Model:      model,
+			Capability: capability,
+			Calls:      1,
+		},
+	}
+	return context.WithValue(ctx, callKey{}, c), c
+}
+
+// Add adds the tokens and units of r to the call.
+// The model of the call is replaced by the model of r, if set.
+func (c *Call) Add(r Record) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.Model != "" {
+		c.rec.Model = r.Model
+	}
+	c.rec.InputTokens += r.InputTokens
+	c.rec.OutputTokens += r.OutputTokens
+	c.rec.Units += r.Units
+}
+
+// Record returns the usage of the call so far.
+func (c *Call) Record() Record {
+	if c == nil {
+		return Record{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rec
+}
+
+// End adds the call to its tracker. Calling End more than once has no effect.
+func (c *Call) End() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ended {
+		return
+	}
+	c.ended = true
+	c.tracker.Add(c.rec)
+}
+
+// Report adds the usage r to the provider call of ctx. Providers report
+// the tokens and units used by calls which return them.
+func Report(ctx context.Context, r Record) {
+	c, _ := ctx.Value(callKey{}).(*Call)
+	c.Add(r)
+}
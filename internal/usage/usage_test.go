@@ -0,0 +1,107 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package usage
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestTrackerAggregatesCalls(t *testing.T) {
+	SetPrices(Prices{
+		"openai":      {Input: 1, Output: 2},
+		"cohere":      {Unit: 0.5},
+		"openai/mini": {Input: 0.5},
+	})
+	defer SetPrices(nil)
+
+	type call struct {
+		provider, capability, model string
+		reports                     []Record
+	}
+	tests := []struct {
+		name  string
+		calls []call
+		want  Records
+	}{
+		{
+			name: "calls of the same model",
+			calls: []call{
+				{"openai", "lm", "gpt", []Record{{InputTokens: 1000, OutputTokens: 100}}},
+				{"openai", "lm", "gpt", []Record{{InputTokens: 500}, {OutputTokens: 400}}},
+			},
+			want: Records{{Provider: "openai", Model: "gpt", Capability: "lm", Calls: 2, InputTokens: 1500, OutputTokens: 500, Cost: 0.0025}},
+		},
+		{
+			name: "model reported by the provider",
+			calls: []call{
+				{"openai", "lm", "", []Record{{Model: "mini", InputTokens: 2000}}},
+				{"openai", "lm", "mini", nil},
+			},
+			want: Records{{Provider: "openai", Model: "mini", Capability: "lm", Calls: 2, InputTokens: 2000, Cost: 0.001}},
+		},
+		{
+			name: "providers and capabilities",
+			calls: []call{
+				{"openai", "lm", "gpt", []Record{{OutputTokens: 1000}}},
+				{"cohere", "reranker", "", []Record{{Units: 3}}},
+				{"openai", "embedder", "gpt", nil},
+			},
+			want: Records{
+				{Provider: "cohere", Capability: "reranker", Calls: 1, Units: 3, Cost: 1.5},
+				{Provider: "openai", Model: "gpt", Capability: "embedder", Calls: 1},
+				{Provider: "openai", Model: "gpt", Capability: "lm", Calls: 1, OutputTokens: 1000, Cost: 0.002},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker()
+			ctx := WithTracker(context.Background(), tracker)
+			for _, c := range tt.calls {
+				callCtx, call := StartCall(ctx, c.provider, c.capability, c.model)
+				for _, r := range c.reports {
+					Report(callCtx, r)
+				}
+				call.End()
+				// ending a call again does not count it twice
+				call.End()
+			}
+
+			if got := tracker.Records(); !slices.Equal(got, tt.want) {
+				t.Errorf("records = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartCallWithoutTracker(t *testing.T) {
+	ctx, call := StartCall(context.Background(), "openai", "lm", "gpt")
+	if call != nil {
+		t.Fatalf("call = %+v, want nil", call)
+	}
+	// calls which are not tracked do nothing
+	Report(ctx, Record{InputTokens: 1})
+	call.End()
+}
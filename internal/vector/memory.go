@@ -0,0 +1,190 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package vector
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/alan-mat/awe/internal/api"
+)
+
+// memoryDefaultLimit mirrors the default result limit used by Qdrant
+// when no limit is given.
+const memoryDefaultLimit = 10
+
+// MemoryStore is an in-process Store implementation, which performs a
+// brute-force cosine similarity search over all points of a collection.
+// It is intended for local development and testing.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]*memoryCollection
+}
+
+type memoryCollection struct {
+	dimensions uint
+	points     map[string]*Point
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		collections: make(map[string]*memoryCollection),
+	}
+}
+
+func (s *MemoryStore) CollectionExists(ctx context.Context, collectionName string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.collections[collectionName]
+	return exists, nil
+}
+
+func (s *MemoryStore) CreateCollection(ctx context.Context, collection Collection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.collections[collection.Name]; exists {
+		return fmt.Errorf("collection '%s' already exists", collection.Name)
+	}
+
+	s.collections[collection.Name] = &memoryCollection{
+		dimensions: collection.Dimensions,
+		points:     make(map[string]*Point),
+	}
+	return nil
+}
+
+func (s *MemoryStore) Upsert(ctx context.Context, collectionName string, points []*Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, exists := s.collections[collectionName]
+	if !exists {
+		return fmt.Errorf("collection '%s' does not exist", collectionName)
+	}
+
+	for _, point := range points {
+		if c.dimensions != 0 && uint(len(point.Vector)) != c.dimensions {
+			return fmt.Errorf("point '%s' has %d dimensions, collection '%s' expects %d",
+				point.ID, len(point.Vector), collectionName, c.dimensions)
+		}
+		c.points[point.ID] = point
+	}
+	return nil
+}
+
+func (s *MemoryStore) Query(ctx context.Context, params *QueryParams) ([]*api.ScoredDocument, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, exists := s.collections[params.collection]
+	if !exists {
+		return nil, fmt.Errorf("collection '%s' does not exist", params.collection)
+	}
+
+	type scoredPoint struct {
+		point *Point
+		score float64
+	}
+	scored := make([]scoredPoint, 0, len(c.points))
+	for _, point := range c.points {
+		if !matchesFilters(point, params.filters) {
+			continue
+		}
+		scored = append(scored, scoredPoint{
+			point: point,
+			score: cosineSimilarity(params.query, point.Vector),
+		})
+	}
+
+	slices.SortFunc(scored, func(a, b scoredPoint) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	limit := memoryDefaultLimit
+	if params.limit > 0 {
+		limit = int(params.limit)
+	}
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	scoredDocs := make([]*api.ScoredDocument, 0, len(scored))
+	for _, sp := range scored {
+		doc := &api.ScoredDocument{
+			Score: sp.score,
+		}
+
+		if params.withPayload {
+			if content, ok := sp.point.Payload["text"].(string); ok {
+				doc.Content = content
+			}
+			if title, ok := sp.point.Payload["title"].(string); ok {
+				doc.Title = title
+			}
+			if url, ok := sp.point.Payload["source_url"].(string); ok {
+				doc.Url = url
+			}
+		}
+
+		scoredDocs = append(scoredDocs, doc)
+	}
+
+	return scoredDocs, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func matchesFilters(point *Point, filters []*QueryMatch) bool {
+	for _, filter := range filters {
+		value, ok := point.Payload[filter.Key].(string)
+		if !ok || value != filter.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
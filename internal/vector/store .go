@@ -37,10 +37,12 @@ var (
 
 const (
 	StoreTypeQdrant = iota
+	StoreTypeMemory
 )
 
 var storeTypeMap = map[string]StoreType{
 	"qdrant": StoreTypeQdrant,
+	"memory": StoreTypeMemory,
 }
 
 type StoreType int
@@ -72,6 +74,8 @@ func NewStore(storeName string) (Store, error) {
 		}
 
 		return store, nil
+	case StoreTypeMemory:
+		return NewMemoryStore(), nil
 	default:
 		return nil, ErrInvalidStoreType
 	}
@@ -26,10 +26,21 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/alan-mat/awe/internal/transport"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"github.com/alan-mat/awe/internal/auth"
+	pb "github.com/alan-mat/awe/internal/proto"
+	"github.com/alan-mat/awe/internal/registry"
+	"github.com/alan-mat/awe/internal/telemetry"
+	"github.com/alan-mat/awe/internal/transport"
+	"github.com/alan-mat/awe/internal/usage"
 )
 
 type messageResponseFunc[T any] func(msg *transport.MessageStreamPayload, traceID string) *T
@@ -45,6 +56,10 @@ func handleMessageStream[T any](
 	for {
 		msg, err := tstream.Recv(ctx)
 
+		if err != nil && ctx.Err() != nil {
+			// the client is gone, there is no one left to respond to
+			return status.FromContextError(ctx.Err()).Err()
+		}
 		if err != nil {
 			slog.Warn("failed to read from stream", "stream", traceID)
 			readFails += 1
@@ -60,6 +75,10 @@ func handleMessageStream[T any](
 		switch msg.Status {
 		case "ERR":
 			return status.Errorf(codes.Internal, "message stream failed")
+		case "DENIED":
+			return status.Error(codes.PermissionDenied, msg.Content)
+		case "CANCELLED":
+			return status.Errorf(codes.Canceled, "trace was cancelled")
 		case "DONE":
 			slog.Debug("message stream done", "trace", traceID)
 			return nil
@@ -71,3 +90,120 @@ func handleMessageStream[T any](
 		}
 	}
 }
+
+func usageTotalsToProto(t usage.Totals) *pb.UsageTotals {
+	return &pb.UsageTotals{
+		Requests:     t.Requests,
+		Calls:        t.Calls,
+		InputTokens:  t.InputTokens,
+		OutputTokens: t.OutputTokens,
+		Units:        t.Units,
+		Cost:         t.Cost,
+	}
+}
+
+// traceIDHeader is the header metadata holding the trace id of streamed requests.
+// It is sent as soon as the request is enqueued, so that clients can cancel the
+// request before its first message.
+const traceIDHeader = "x-trace-id"
+
+// enqueue enqueues the task of a request of user to workflow, once the request is
+// admitted by the quotas, and returns its trace id. Errors are status errors.
+// The task is created by newTask with the context of the enqueue span,
+// whose trace is continued by the worker.
+func (s Server) enqueue(stream grpc.ServerStream, newTask func(context.Context) (*asynq.Task, error), user, workflow string) (string, error) {
+	traceID := uuid.NewString()
+	ctx, span := telemetry.Start(stream.Context(), "enqueue",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			telemetry.TraceIDKey.String(traceID),
+			attribute.String("awe.workflow", workflow),
+		),
+	)
+	var err error
+	defer func() { telemetry.End(span, err) }()
+
+	if err = s.admit(ctx, traceID, user, workflow); err != nil {
+		return "", err
+	}
+
+	t, err := newTask(ctx)
+	if err != nil {
+		s.releaseSlots(ctx, transport.UsageScopes(user, workflow), traceID)
+		slog.Error("failed to create task", "err", err)
+		return "", status.Errorf(codes.Internal, "internal server error")
+	}
+	span.SetAttributes(attribute.String("awe.task.type", t.Type()))
+
+	info, err := s.queue.Enqueue(t, asynq.TaskID(traceID))
+	if err != nil {
+		s.releaseSlots(ctx, transport.UsageScopes(user, workflow), traceID)
+		slog.Error(err.Error())
+		return "", status.Errorf(codes.Internal, "internal server error")
+	}
+	slog.Info("enqueued task successfully", "id", info.ID)
+
+	// the header can only be sent once per stream, later turns of
+	// sessions return the trace id in their messages only
+	stream.SendHeader(metadata.Pairs(traceIDHeader, info.ID))
+	return info.ID, nil
+}
+
+// follow counts the client of ctx as following the trace, if CancelOnDisconnect
+// is set. The returned function must be called once the client stops following
+// the trace, which is cancelled if the client disconnected as the last one.
+func (s Server) follow(ctx context.Context, traceID string) func() {
+	if !s.config.CancelOnDisconnect {
+		return func() {}
+	}
+
+	if err := s.transport.AttachClient(ctx, traceID); err != nil {
+		slog.Warn("failed to attach client to trace", "id", traceID, "err", err)
+		return func() {}
+	}
+
+	return func() {
+		// the context of the client is done once it disconnected
+		bg := context.WithoutCancel(ctx)
+		n, err := s.transport.DetachClient(bg, traceID)
+		if err != nil {
+			slog.Warn("failed to detach client from trace", "id", traceID, "err", err)
+			return
+		}
+		if n > 0 || ctx.Err() == nil {
+			return
+		}
+
+		slog.Info("all clients disconnected, cancelling trace", "id", traceID)
+		if err := s.transport.CancelTrace(bg, traceID); err != nil {
+			slog.Error("failed to cancel trace", "id", traceID, "err", err)
+		}
+	}
+}
+
+// workflowName returns the name of the workflow referenced by ref, without its version.
+func workflowName(ref string) string {
+	name, _ := registry.ParseWorkflowRef(ref)
+	return name
+}
+
+// requestUser returns the user of the authenticated principal of ctx,
+// or the user given by the client if authentication is disabled.
+func requestUser(ctx context.Context, user string) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.User
+	}
+	return user
+}
+
+// canRead reports whether the principal of ctx may read the traces and usage
+// of user. Admins may read those of all users, including the usage of workflows
+// across users, which is read with an empty user.
+func canRead(ctx context.Context, user string) bool {
+	p := auth.FromContext(ctx)
+	if p == nil {
+		// authentication is disabled
+		return true
+	}
+	return p.Admin || (user != "" && p.User == user)
+}
@@ -0,0 +1,527 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/alan-mat/awe/internal/auth"
+	pb "github.com/alan-mat/awe/internal/proto"
+)
+
+// maxRequestBodySize limits the size of HTTP request bodies.
+const maxRequestBodySize = 4 << 20
+
+// sseKeepAlive is the interval of comments sent on idle event streams,
+// so that proxies don't close them while a workflow is running.
+const sseKeepAlive = 15 * time.Second
+
+var (
+	jsonMarshal   = protojson.MarshalOptions{UseProtoNames: true}
+	jsonUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// gateway serves the AWEService as HTTP/JSON endpoints. Streaming calls respond
+// with server-sent events, each holding a response message as JSON. The gateway
+// calls the gRPC handlers of the server, so requests are handled the same way.
+type gateway struct {
+	srv         *Server
+	auth        *auth.Authenticator
+	corsOrigins []string
+}
+
+func newGateway(s *Server, a *auth.Authenticator) http.Handler {
+	g := &gateway{
+		srv:         s,
+		auth:        a,
+		corsOrigins: s.config.CORSOrigins,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat", g.chat)
+	mux.HandleFunc("POST /v1/search", g.search)
+	mux.HandleFunc("POST /v1/execute", g.execute)
+	mux.HandleFunc("GET /v1/traces", g.listTraces)
+	mux.HandleFunc("GET /v1/traces/{id}", g.trace)
+	mux.HandleFunc("GET /v1/traces/{id}/attach", g.attach)
+	mux.HandleFunc("POST /v1/traces/{id}/cancel", g.cancel)
+	mux.HandleFunc("GET /v1/usage", g.usage)
+	mux.HandleFunc("POST /v1/sessions", g.createSession)
+	mux.HandleFunc("GET /v1/sessions/{id}", g.getSession)
+	mux.HandleFunc("DELETE /v1/sessions/{id}", g.deleteSession)
+	mux.HandleFunc("POST /v1/sessions/{id}/chat", g.chatSession)
+
+	return g.cors(g.authenticate(mux))
+}
+
+func (g *gateway) chat(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ChatRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	stream := newSSEStream[pb.ChatResponse](w, r)
+	stream.finish(g.srv.Chat(req, stream))
+}
+
+func (g *gateway) search(w http.ResponseWriter, r *http.Request) {
+	req := &pb.SearchRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	stream := newSSEStream[pb.SearchResponse](w, r)
+	stream.finish(g.srv.Search(req, stream))
+}
+
+func (g *gateway) execute(w http.ResponseWriter, r *http.Request) {
+	req := &pb.ExecuteRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	stream := newSSEStream[pb.ExecuteResponse](w, r)
+	stream.finish(g.srv.Execute(req, stream))
+}
+
+func (g *gateway) attach(w http.ResponseWriter, r *http.Request) {
+	req := &pb.AttachRequest{TraceId: r.PathValue("id")}
+	stream := newSSEStream[pb.ExecuteResponse](w, r)
+	stream.finish(g.srv.Attach(req, stream))
+}
+
+func (g *gateway) trace(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.srv.Trace(r.Context(), &pb.TraceRequest{TraceId: r.PathValue("id")})
+	writeResponse(w, resp, err)
+}
+
+// listTraces takes the fields of a ListTracesRequest as query parameters. The status
+// is given by its name, and the start times as RFC 3339 timestamps or unix nanoseconds.
+func (g *gateway) listTraces(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := &pb.ListTracesRequest{
+		User:       q.Get("user"),
+		WorkflowId: q.Get("workflow_id"),
+		PageToken:  q.Get("page_token"),
+	}
+
+	if s := q.Get("status"); s != "" {
+		st, ok := pb.TraceStatus_value[strings.ToUpper(s)]
+		if !ok {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid status '%s'", s))
+			return
+		}
+		req.Status = pb.TraceStatus(st)
+	}
+
+	var err error
+	if req.StartedAfter, err = parseTime(q.Get("started_after")); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid started_after: %v", err))
+		return
+	}
+	if req.StartedBefore, err = parseTime(q.Get("started_before")); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid started_before: %v", err))
+		return
+	}
+
+	if s := q.Get("page_size"); s != "" {
+		size, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid page_size '%s'", s))
+			return
+		}
+		req.PageSize = int32(size)
+	}
+
+	resp, err := g.srv.ListTraces(r.Context(), req)
+	writeResponse(w, resp, err)
+}
+
+// parseTime parses a RFC 3339 timestamp or unix nanoseconds,
+// returning unix nanoseconds. An empty string is 0.
+func parseTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ns, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano(), nil
+}
+
+func (g *gateway) cancel(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.srv.Cancel(r.Context(), &pb.CancelRequest{TraceId: r.PathValue("id")})
+	writeResponse(w, resp, err)
+}
+
+func (g *gateway) usage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	resp, err := g.srv.Usage(r.Context(), &pb.UsageRequest{
+		User:       q.Get("user"),
+		WorkflowId: q.Get("workflow_id"),
+		Period:     q.Get("period"),
+	})
+	writeResponse(w, resp, err)
+}
+
+func (g *gateway) createSession(w http.ResponseWriter, r *http.Request) {
+	req := &pb.CreateSessionRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	resp, err := g.srv.CreateSession(r.Context(), req)
+	writeResponse(w, resp, err)
+}
+
+func (g *gateway) getSession(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.srv.GetSession(r.Context(), &pb.GetSessionRequest{SessionId: r.PathValue("id")})
+	writeResponse(w, resp, err)
+}
+
+func (g *gateway) deleteSession(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.srv.DeleteSession(r.Context(), &pb.DeleteSessionRequest{SessionId: r.PathValue("id")})
+	writeResponse(w, resp, err)
+}
+
+// chatSession runs a single turn of a session, the gateway has no
+// bidirectional streams.
+func (g *gateway) chatSession(w http.ResponseWriter, r *http.Request) {
+	req := &pb.SessionRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	req.SessionId = r.PathValue("id")
+	stream := newSSEStream[pb.SessionResponse](w, r)
+	stream.finish(g.srv.sessionTurn(req, stream))
+}
+
+// authenticate passes the principal of requests on in their context, like
+// the gRPC interceptors of the authenticator.
+func (g *gateway) authenticate(next http.Handler) http.Handler {
+	if g.auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := g.auth.AuthenticateRequest(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			if errors.Is(err, auth.ErrNoCredentials) {
+				writeError(w, status.Error(codes.Unauthenticated, "missing credentials"))
+			} else {
+				writeError(w, status.Error(codes.Unauthenticated, "invalid credentials"))
+			}
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// cors allows browsers to call the gateway from the configured origins,
+// preflight requests are answered before authentication.
+func (g *gateway) cors(next http.Handler) http.Handler {
+	if len(g.corsOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (slices.Contains(g.corsOrigins, "*") || slices.Contains(g.corsOrigins, origin)) {
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				h.Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+				h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key")
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, req proto.Message) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err))
+		return false
+	}
+	if err := jsonUnmarshal.Unmarshal(body, req); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, resp proto.Message, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	data, err := jsonMarshal.Marshal(resp)
+	if err != nil {
+		writeError(w, status.Error(codes.Internal, "internal server error"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// writeError writes the status of err as a JSON google.rpc.Status,
+// with the HTTP status code corresponding to its code.
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			seconds := math.Ceil(ri.RetryDelay.AsDuration().Seconds())
+			w.Header().Set("Retry-After", fmt.Sprint(int(seconds)))
+		}
+	}
+
+	data, _ := jsonMarshal.Marshal(st.Proto())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	w.Write(data)
+}
+
+var httpStatusCodes = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+}
+
+func httpStatus(c codes.Code) int {
+	if s, ok := httpStatusCodes[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// sseStream implements the server side of a gRPC server stream as
+// server-sent events, so that the gRPC handlers can serve HTTP requests.
+// Messages are sent as 'message' events, the end of the stream as a 'done'
+// event and failures after the stream started as an 'error' event.
+type sseStream[T any] struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+
+	mu      sync.Mutex
+	started bool
+	// closed is set once the stream finished, nothing is written after,
+	// as the response writer may no longer be used once the handler returned
+	closed bool
+	stop   chan struct{}
+}
+
+func newSSEStream[T any](w http.ResponseWriter, r *http.Request) *sseStream[T] {
+	flusher, _ := w.(http.Flusher)
+	return &sseStream[T]{
+		w:       w,
+		flusher: flusher,
+		ctx:     r.Context(),
+		stop:    make(chan struct{}),
+	}
+}
+
+func (s *sseStream[T]) Send(m *T) error {
+	msg, ok := any(m).(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot send message of type %T", m)
+	}
+	data, err := jsonMarshal.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.writeEvent("message", data)
+}
+
+func (s *sseStream[T]) writeEvent(event string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("stream already finished")
+	}
+	if !s.started {
+		s.start()
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+// start writes the headers of the event stream, it must be called with mu held.
+func (s *sseStream[T]) start() {
+	s.started = true
+
+	h := s.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+
+	go s.keepAlive()
+}
+
+func (s *sseStream[T]) keepAlive() {
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				return
+			}
+			if _, err := io.WriteString(s.w, ": keep-alive\n\n"); err == nil {
+				s.flush()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *sseStream[T]) flush() {
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+// finish ends the stream with the result err of the handler. Failures before
+// the stream started are written as error responses with an HTTP status code.
+func (s *sseStream[T]) finish(err error) {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	defer s.close()
+
+	if err != nil && !started {
+		writeError(s.w, err)
+		return
+	}
+
+	if err != nil {
+		data, _ := jsonMarshal.Marshal(status.Convert(err).Proto())
+		if err := s.writeEvent("error", data); err != nil {
+			slog.Debug("failed to write error event", "err", err)
+		}
+		return
+	}
+
+	s.writeEvent("done", []byte("{}"))
+}
+
+// close stops writing to the stream, including keep-alives.
+func (s *sseStream[T]) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+}
+
+func (s *sseStream[T]) Context() context.Context { return s.ctx }
+
+func (s *sseStream[T]) SendMsg(m any) error {
+	msg, ok := m.(*T)
+	if !ok {
+		return fmt.Errorf("cannot send message of type %T", m)
+	}
+	return s.Send(msg)
+}
+
+func (s *sseStream[T]) RecvMsg(m any) error    { return io.EOF }
+func (s *sseStream[T]) SetTrailer(metadata.MD) {}
+
+// SetHeader sets the metadata as HTTP headers, unless the stream has started.
+func (s *sseStream[T]) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.setHeader(md)
+}
+
+// SendHeader sets the metadata as HTTP headers and starts the stream.
+// Failures after it are sent as 'error' events.
+func (s *sseStream[T]) SendHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.setHeader(md); err != nil {
+		return err
+	}
+	s.start()
+	s.flush()
+	return nil
+}
+
+// setHeader must be called with mu held.
+func (s *sseStream[T]) setHeader(md metadata.MD) error {
+	if s.started {
+		return errors.New("stream already started")
+	}
+	for k, values := range md {
+		for _, v := range values {
+			s.w.Header().Add(k, v)
+		}
+	}
+	return nil
+}
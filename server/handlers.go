@@ -23,11 +23,16 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"time"
 
+	"github.com/hibiken/asynq"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/alan-mat/awe/internal/auth"
 	pb "github.com/alan-mat/awe/internal/proto"
 	"github.com/alan-mat/awe/internal/tasks"
 	"github.com/alan-mat/awe/internal/transport"
@@ -36,19 +41,13 @@ import (
 func (s Server) Chat(req *pb.ChatRequest, stream pb.AWEService_ChatServer) error {
 	slog.Debug("received chat request", "user", req.User, "query", req.Query, "history", req.GetHistory(), "args", req.GetArgs())
 
-	t, err := tasks.NewChatTask(req)
-	if err != nil {
-		slog.Error(err.Error())
-		return status.Errorf(codes.Internal, "internal server error")
+	newTask := func(ctx context.Context) (*asynq.Task, error) {
+		return tasks.NewChatTask(ctx, req, auth.FromContext(ctx))
 	}
-
-	info, err := s.asynqClient.Enqueue(t)
+	traceID, err := s.enqueue(stream, newTask, requestUser(stream.Context(), req.User), tasks.DefaultWorkflowChat)
 	if err != nil {
-		slog.Error(err.Error())
-		return status.Errorf(codes.Internal, "internal server error")
+		return err
 	}
-	slog.Info("enqueued task successfully", "id", info.ID)
-	traceID := info.ID
 
 	tstream, err := s.transport.GetMessageStream(traceID)
 	if err != nil {
@@ -65,6 +64,7 @@ func (s Server) Chat(req *pb.ChatRequest, stream pb.AWEService_ChatServer) error
 		}
 	}
 
+	defer s.follow(stream.Context(), traceID)()
 	err = handleMessageStream(stream.Context(), traceID, tstream, stream, respFunc)
 	return err
 }
@@ -72,19 +72,13 @@ func (s Server) Chat(req *pb.ChatRequest, stream pb.AWEService_ChatServer) error
 func (s Server) Search(req *pb.SearchRequest, stream pb.AWEService_SearchServer) error {
 	slog.Debug("received search request", "user", req.User, "query", req.Query, "args", req.GetArgs())
 
-	t, err := tasks.NewSearchTask(req)
-	if err != nil {
-		slog.Error(err.Error())
-		return status.Errorf(codes.Internal, "internal server error")
+	newTask := func(ctx context.Context) (*asynq.Task, error) {
+		return tasks.NewSearchTask(ctx, req, auth.FromContext(ctx))
 	}
-
-	info, err := s.asynqClient.Enqueue(t)
+	traceID, err := s.enqueue(stream, newTask, requestUser(stream.Context(), req.User), tasks.DefaultWorkflowSearch)
 	if err != nil {
-		slog.Error(err.Error())
-		return status.Errorf(codes.Internal, "internal server error")
+		return err
 	}
-	slog.Info("enqueued task successfully", "id", info.ID)
-	traceID := info.ID
 
 	tstream, err := s.transport.GetMessageStream(traceID)
 	if err != nil {
@@ -105,6 +99,7 @@ func (s Server) Search(req *pb.SearchRequest, stream pb.AWEService_SearchServer)
 		}
 	}
 
+	defer s.follow(stream.Context(), traceID)()
 	err = handleMessageStream(stream.Context(), traceID, tstream, stream, respFunc)
 	return err
 }
@@ -113,19 +108,13 @@ func (s Server) Execute(req *pb.ExecuteRequest, stream pb.AWEService_ExecuteServ
 	slog.Debug("received execute request", "workflowId", req.WorkflowId, "user", req.User,
 		"query", req.Query, "history", req.GetHistory(), "args", req.GetArgs())
 
-	t, err := tasks.NewExecuteTask(req)
-	if err != nil {
-		slog.Error(err.Error())
-		return status.Errorf(codes.Internal, "internal server error")
+	newTask := func(ctx context.Context) (*asynq.Task, error) {
+		return tasks.NewExecuteTask(ctx, req, auth.FromContext(ctx))
 	}
-
-	info, err := s.asynqClient.Enqueue(t)
+	traceID, err := s.enqueue(stream, newTask, requestUser(stream.Context(), req.User), workflowName(req.WorkflowId))
 	if err != nil {
-		slog.Error(err.Error())
-		return status.Errorf(codes.Internal, "internal server error")
+		return err
 	}
-	slog.Info("enqueued task successfully", "id", info.ID)
-	traceID := info.ID
 
 	tstream, err := s.transport.GetMessageStream(traceID)
 	if err != nil {
@@ -159,16 +148,74 @@ func (s Server) Execute(req *pb.ExecuteRequest, stream pb.AWEService_ExecuteServ
 		return resp
 	}
 
+	defer s.follow(stream.Context(), traceID)()
 	err = handleMessageStream(stream.Context(), traceID, tstream, stream, respFunc)
 	return err
 }
 
 func (s Server) Trace(ctx context.Context, req *pb.TraceRequest) (*pb.TraceResponse, error) {
 	trace, err := s.transport.GetTrace(ctx, req.TraceId)
-	if err != nil {
+	if err != nil || !canRead(ctx, trace.User) {
 		return nil, status.Errorf(codes.NotFound, "trace with given id does not exist")
 	}
+	return traceToProto(trace), nil
+}
+
+const (
+	defaultTracePageSize = 50
+	maxTracePageSize     = 1000
+)
+
+// ListTraces lists the traces of the authenticated user, unless an admin
+// lists those of another or all users.
+func (s Server) ListTraces(ctx context.Context, req *pb.ListTracesRequest) (*pb.ListTracesResponse, error) {
+	user := req.User
+	if p := auth.FromContext(ctx); p != nil && !p.Admin && user == "" {
+		user = p.User
+	}
+	if !canRead(ctx, user) {
+		return nil, status.Errorf(codes.PermissionDenied, "traces of other users can only be listed by admins")
+	}
+
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "page size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultTracePageSize
+	case pageSize > maxTracePageSize:
+		pageSize = maxTracePageSize
+	}
+
+	filter := transport.TraceFilter{
+		User:          user,
+		Workflow:      workflowName(req.WorkflowId),
+		Status:        int(req.Status),
+		StartedAfter:  req.StartedAfter,
+		StartedBefore: req.StartedBefore,
+	}
+	traces, next, err := s.transport.ListTraces(ctx, filter, req.PageToken, pageSize)
+	if errors.Is(err, transport.ErrInvalidCursor) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+	}
+	if err != nil {
+		slog.Error("failed to list traces", "err", err)
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
 
+	resp := &pb.ListTracesResponse{
+		Traces:        make([]*pb.TraceResponse, 0, len(traces)),
+		NextPageToken: next,
+	}
+	for _, trace := range traces {
+		// spans are left out of lists, they are read with Trace
+		trace.Spans = nil
+		resp.Traces = append(resp.Traces, traceToProto(trace))
+	}
+	return resp, nil
+}
+
+func traceToProto(trace *transport.RequestTrace) *pb.TraceResponse {
 	resp := &pb.TraceResponse{
 		TraceId:     trace.ID,
 		Status:      pb.TraceStatus(trace.Status),
@@ -176,13 +223,84 @@ func (s Server) Trace(ctx context.Context, req *pb.TraceRequest) (*pb.TraceRespo
 		CompletedAt: trace.CompletedAt,
 		Query:       trace.Query,
 		User:        trace.User,
+
+		WorkflowId:      trace.Workflow,
+		WorkflowVersion: trace.WorkflowVersion,
+
+		Usage:       make([]*pb.ProviderUsage, 0, len(trace.Usage)),
+		UsageTotals: usageTotalsToProto(trace.Usage.Total()),
+
+		Spans:        make([]*pb.Span, 0, len(trace.Spans)),
+		SpansDropped: int32(trace.SpansDropped),
+
+		ExpiresAt: trace.ExpiresAt,
+	}
+	for _, r := range trace.Usage {
+		resp.Usage = append(resp.Usage, &pb.ProviderUsage{
+			Provider:     r.Provider,
+			Model:        r.Model,
+			Capability:   r.Capability,
+			Calls:        int64(r.Calls),
+			InputTokens:  int64(r.InputTokens),
+			OutputTokens: int64(r.OutputTokens),
+			Units:        int64(r.Units),
+			Cost:         r.Cost,
+		})
+	}
+	for _, sp := range trace.Spans {
+		resp.Spans = append(resp.Spans, &pb.Span{
+			SpanId:      int32(sp.ID),
+			ParentId:    int32(sp.ParentID),
+			Name:        sp.Name,
+			Module:      sp.Module,
+			Operator:    sp.Operator,
+			StartedAt:   sp.StartedAt,
+			CompletedAt: sp.CompletedAt,
+			Query:       sp.Query,
+			Args:        sp.Args,
+			Outputs:     sp.Outputs,
+			Route:       sp.Route,
+			Attempts:    int32(sp.Attempts),
+			Error:       sp.Error,
+		})
+	}
+	return resp
+}
+
+func (s Server) Usage(ctx context.Context, req *pb.UsageRequest) (*pb.UsageResponse, error) {
+	if req.User == "" && req.WorkflowId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user or workflow id must be given")
+	}
+	if !canRead(ctx, req.User) {
+		return nil, status.Errorf(codes.PermissionDenied, "usage of other users can only be read by admins")
+	}
+
+	period := req.Period
+	if period == "" {
+		period = transport.UsagePeriod(time.Now())
+	} else if _, err := transport.ParseUsagePeriod(period); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "period must be a month 'YYYY-MM' or a day 'YYYY-MM-DD'")
+	}
+
+	scope := transport.UsageScope{User: req.User, Workflow: req.WorkflowId}
+	totals, err := s.transport.GetUsage(ctx, period, scope)
+	if err != nil {
+		slog.Error("failed to retrieve usage", "user", req.User, "workflowId", req.WorkflowId, "period", period, "err", err)
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	resp := &pb.UsageResponse{
+		User:       req.User,
+		WorkflowId: req.WorkflowId,
+		Period:     period,
+		Totals:     usageTotalsToProto(totals),
 	}
 	return resp, nil
 }
 
 func (s Server) Attach(req *pb.AttachRequest, stream pb.AWEService_AttachServer) error {
 	trace, err := s.transport.GetTrace(stream.Context(), req.TraceId)
-	if err != nil {
+	if err != nil || !canRead(stream.Context(), trace.User) {
 		return status.Errorf(codes.NotFound, "trace with given id does not exist")
 	}
 
@@ -191,6 +309,7 @@ func (s Server) Attach(req *pb.AttachRequest, stream pb.AWEService_AttachServer)
 		slog.Error("failed to retrieve stream", "id", trace.ID)
 		return status.Errorf(codes.Internal, "internal server error")
 	}
+	stream.SendHeader(metadata.Pairs(traceIDHeader, trace.ID))
 
 	if trace.Status != transport.TraceStatusRunning {
 		text, err := tstream.Text(stream.Context())
@@ -238,6 +357,47 @@ func (s Server) Attach(req *pb.AttachRequest, stream pb.AWEService_AttachServer)
 		return resp
 	}
 
+	defer s.follow(stream.Context(), trace.ID)()
 	err = handleMessageStream(stream.Context(), trace.ID, tstream, stream, respFunc)
 	return err
 }
+
+// Cancel cancels a running request, or a queued request which has no trace yet.
+// Queued requests are skipped by the worker once it sees the cancellation.
+func (s Server) Cancel(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	trace, err := s.transport.GetTrace(ctx, req.TraceId)
+	if err != nil || trace.ID == "" {
+		trace, err = s.queuedTrace(req.TraceId)
+	}
+	if err != nil || !canRead(ctx, trace.User) {
+		return nil, status.Errorf(codes.NotFound, "trace with given id does not exist")
+	}
+	if trace.Status != transport.TraceStatusRunning {
+		return nil, status.Errorf(codes.FailedPrecondition, "trace is not running")
+	}
+
+	if err := s.transport.CancelTrace(ctx, trace.ID); err != nil {
+		slog.Error("failed to cancel trace", "id", trace.ID, "err", err)
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+	slog.Info("cancelled trace", "id", trace.ID)
+
+	return &pb.CancelResponse{}, nil
+}
+
+// queuedTrace returns the trace of the request id as it is created by the
+// worker, if the task of the request is queued and has no trace yet.
+func (s Server) queuedTrace(id string) (*transport.RequestTrace, error) {
+	if s.inspector == nil {
+		return nil, tasks.ErrTaskNotFound
+	}
+	task, err := s.inspector.GetTask(id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := tasks.TaskUser(task)
+	if err != nil {
+		return nil, err
+	}
+	return &transport.RequestTrace{ID: id, User: user, Status: transport.TraceStatusRunning}, nil
+}
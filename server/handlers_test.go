@@ -0,0 +1,75 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alan-mat/awe/internal/auth"
+	pb "github.com/alan-mat/awe/internal/proto"
+	"github.com/alan-mat/awe/internal/tasks"
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+func TestCancelQueuedRequest(t *testing.T) {
+	tr := transport.NewMemoryTransport()
+	q := tasks.NewMemoryQueue()
+	s := NewWithBackend(ServerConfig{}, tr, q)
+
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{User: "alice"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{User: "bob"})
+
+	task, err := tasks.NewExecuteTask(alice, &pb.ExecuteRequest{WorkflowId: "chat", Query: "q"}, auth.FromContext(alice))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue(task, asynq.TaskID("t1")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		id   string
+		code codes.Code
+	}{
+		{"other user", bob, "t1", codes.NotFound},
+		{"unknown request", alice, "t2", codes.NotFound},
+		{"queued request", alice, "t1", codes.OK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Cancel(tt.ctx, &pb.CancelRequest{TraceId: tt.id})
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("code = %v, want %v (%v)", code, tt.code, err)
+			}
+		})
+	}
+
+	if cancelled, _ := tr.IsCancelled(context.Background(), "t1"); !cancelled {
+		t.Error("queued request not cancelled")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+// Limits restricts the requests of a user or workflow. Zero values are unlimited.
+type Limits struct {
+	// RequestsPerMinute is the number of requests accepted per minute.
+	RequestsPerMinute int
+	// Concurrent is the number of requests running at the same time.
+	Concurrent int
+	// DailyTokens is the number of input and output tokens used per day (UTC).
+	// Requests are rejected once the budget is used up, so the tokens of
+	// requests running at that time may exceed it.
+	DailyTokens int64
+}
+
+// QuotaConfig configures the limits enforced on incoming requests.
+type QuotaConfig struct {
+	// User are the limits of every user, unless replaced in Users.
+	// Requests without a user are only limited by their workflow.
+	User  Limits
+	Users map[string]Limits
+
+	// Workflows are the limits of all requests to a workflow, across all users.
+	Workflows map[string]Limits
+
+	// SlotTimeout is the time after which a request no longer counts as running,
+	// in case its worker exits without finishing it. Defaults to DefaultSlotTimeout.
+	SlotTimeout time.Duration
+}
+
+// DefaultSlotTimeout is the default QuotaConfig.SlotTimeout.
+const DefaultSlotTimeout = time.Hour
+
+// concurrencyRetryDelay is the retry delay of requests
+// rejected because too many requests are running.
+const concurrencyRetryDelay = time.Second
+
+func (c QuotaConfig) userLimits(user string) Limits {
+	if l, ok := c.Users[user]; ok {
+		return l
+	}
+	return c.User
+}
+
+type quotaScope struct {
+	scope  transport.UsageScope
+	limits Limits
+}
+
+// admit checks the quotas of a request of user to workflow, which is run with the
+// given trace id. If the request is admitted, it holds a running slot until it is
+// finished by the worker, or released with releaseSlots if it is not enqueued.
+// Rejected requests return a ResourceExhausted status with retry info.
+func (s Server) admit(ctx context.Context, id, user, workflow string) error {
+	scopes := make([]quotaScope, 0, 2)
+	if user != "" {
+		scopes = append(scopes, quotaScope{transport.UsageScope{User: user}, s.config.Quota.userLimits(user)})
+	}
+	if l, ok := s.config.Quota.Workflows[workflow]; ok {
+		scopes = append(scopes, quotaScope{transport.UsageScope{Workflow: workflow}, l})
+	}
+
+	now := time.Now()
+	for _, qs := range scopes {
+		if qs.limits.DailyTokens <= 0 {
+			continue
+		}
+		u, err := s.transport.GetUsage(ctx, transport.UsageDay(now), qs.scope)
+		if err != nil {
+			return quotaError(err)
+		}
+		if u.InputTokens+u.OutputTokens >= qs.limits.DailyTokens {
+			midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			return exhausted(qs.scope, "daily token budget used up", midnight.Sub(now))
+		}
+	}
+
+	timeout := s.config.Quota.SlotTimeout
+	if timeout <= 0 {
+		timeout = DefaultSlotTimeout
+	}
+
+	// the limits of all scopes are checked and counted at once,
+	// so that a request rejected by one scope is not counted by another
+	var slots, requests []quotaScope
+	for _, qs := range scopes {
+		if qs.limits.Concurrent > 0 {
+			slots = append(slots, qs)
+		}
+		if qs.limits.RequestsPerMinute > 0 {
+			requests = append(requests, qs)
+		}
+	}
+
+	slotLimits := make([]transport.QuotaLimit, 0, len(slots))
+	acquired := make([]transport.UsageScope, 0, len(slots))
+	for _, qs := range slots {
+		slotLimits = append(slotLimits, transport.QuotaLimit{Scope: qs.scope, Limit: qs.limits.Concurrent})
+		acquired = append(acquired, qs.scope)
+	}
+	i, err := s.transport.AcquireSlots(ctx, slotLimits, id, timeout)
+	if err != nil {
+		return quotaError(err)
+	}
+	if i >= 0 {
+		return exhausted(slots[i].scope, "too many running requests", concurrencyRetryDelay)
+	}
+
+	requestLimits := make([]transport.QuotaLimit, 0, len(requests))
+	for _, qs := range requests {
+		requestLimits = append(requestLimits, transport.QuotaLimit{Scope: qs.scope, Limit: qs.limits.RequestsPerMinute})
+	}
+	i, err = s.transport.AcquireRequests(ctx, requestLimits, time.Minute)
+	if err != nil {
+		s.releaseSlots(ctx, acquired, id)
+		return quotaError(err)
+	}
+	if i >= 0 {
+		s.releaseSlots(ctx, acquired, id)
+		return exhausted(requests[i].scope, "too many requests per minute", time.Minute-time.Duration(now.UnixNano()%int64(time.Minute)))
+	}
+
+	return nil
+}
+
+func (s Server) releaseSlots(ctx context.Context, scopes []transport.UsageScope, id string) {
+	if len(scopes) == 0 {
+		return
+	}
+	if err := s.transport.ReleaseSlots(context.WithoutCancel(ctx), scopes, id); err != nil {
+		slog.Error("failed to release quota slots", "id", id, "err", err)
+	}
+}
+
+func exhausted(scope transport.UsageScope, reason string, retryDelay time.Duration) error {
+	var msg string
+	if scope.User != "" {
+		msg = fmt.Sprintf("quota of user '%s' exceeded: %s", scope.User, reason)
+	} else {
+		msg = fmt.Sprintf("quota of workflow '%s' exceeded: %s", scope.Workflow, reason)
+	}
+	slog.Info("request rejected", "user", scope.User, "workflowId", scope.Workflow, "reason", reason)
+
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryDelay.Round(time.Millisecond)),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
+
+func quotaError(err error) error {
+	slog.Error("failed to check quota", "err", err)
+	return status.Errorf(codes.Internal, "internal server error")
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alan-mat/awe/internal/transport"
+	"github.com/alan-mat/awe/internal/usage"
+)
+
+func newQuotaServer(quota QuotaConfig) (*Server, *transport.MemoryTransport) {
+	t := transport.NewMemoryTransport()
+	return NewWithBackend(ServerConfig{Quota: quota}, t, nil), t
+}
+
+func assertAdmitted(t *testing.T, s *Server, id, user, workflow string) {
+	t.Helper()
+	if err := s.admit(context.Background(), id, user, workflow); err != nil {
+		t.Fatalf("request %s of %s to %s rejected: %v", id, user, workflow, err)
+	}
+}
+
+func assertRejected(t *testing.T, s *Server, id, user, workflow string) {
+	t.Helper()
+	err := s.admit(context.Background(), id, user, workflow)
+	st, _ := status.FromError(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("request %s of %s to %s: err = %v, want ResourceExhausted", id, user, workflow, err)
+	}
+
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if r, ok := d.(*errdetails.RetryInfo); ok {
+			retry = r
+		}
+	}
+	if retry == nil || retry.RetryDelay.AsDuration() <= 0 {
+		t.Errorf("request %s of %s to %s: missing retry delay in %v", id, user, workflow, st.Details())
+	}
+}
+
+func TestAdmitRequestsPerMinute(t *testing.T) {
+	s, _ := newQuotaServer(QuotaConfig{
+		User:      Limits{RequestsPerMinute: 2},
+		Users:     map[string]Limits{"admin": {}},
+		Workflows: map[string]Limits{"search": {RequestsPerMinute: 1}},
+	})
+
+	assertAdmitted(t, s, "1", "alice", "chat")
+	assertAdmitted(t, s, "2", "alice", "chat")
+	assertRejected(t, s, "3", "alice", "chat")
+	assertAdmitted(t, s, "4", "admin", "chat")
+	assertAdmitted(t, s, "5", "", "chat")
+
+	// requests rejected by the workflow are not counted for the user
+	assertAdmitted(t, s, "6", "bob", "search")
+	assertRejected(t, s, "7", "carol", "search")
+	assertRejected(t, s, "8", "carol", "search")
+	assertAdmitted(t, s, "9", "carol", "chat")
+	assertAdmitted(t, s, "10", "carol", "chat")
+}
+
+func TestAdmitConcurrent(t *testing.T) {
+	s, tr := newQuotaServer(QuotaConfig{
+		User:      Limits{Concurrent: 2, RequestsPerMinute: 2},
+		Workflows: map[string]Limits{"search": {Concurrent: 1}},
+	})
+	ctx := context.Background()
+
+	assertAdmitted(t, s, "1", "alice", "search")
+	// rejected by the workflow, without holding a slot of the user
+	assertRejected(t, s, "2", "alice", "search")
+	assertAdmitted(t, s, "3", "alice", "chat")
+	assertRejected(t, s, "4", "alice", "chat")
+
+	user := transport.UsageScope{User: "alice"}
+	if err := tr.ReleaseSlots(ctx, []transport.UsageScope{user}, "3"); err != nil {
+		t.Fatal(err)
+	}
+	// only admitted requests were counted, so the third one is rejected
+	// by the requests per minute and releases its slot
+	assertRejected(t, s, "5", "alice", "chat")
+	i, err := tr.AcquireSlots(ctx, []transport.QuotaLimit{{Scope: user, Limit: 2}}, "probe", time.Minute)
+	if err != nil || i != -1 {
+		t.Errorf("slot of rejected request held: index = %d, err = %v", i, err)
+	}
+}
+
+func TestAdmitDailyTokens(t *testing.T) {
+	s, tr := newQuotaServer(QuotaConfig{
+		User: Limits{DailyTokens: 100},
+	})
+	ctx := context.Background()
+
+	assertAdmitted(t, s, "1", "alice", "chat")
+
+	scopes := transport.UsageScopes("alice", "chat")
+	if err := tr.AddUsage(ctx, transport.UsageDay(time.Now()), scopes, usage.Totals{InputTokens: 60, OutputTokens: 40}); err != nil {
+		t.Fatal(err)
+	}
+	assertRejected(t, s, "2", "alice", "chat")
+	assertAdmitted(t, s, "3", "bob", "chat")
+}
@@ -22,15 +22,26 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/alan-mat/awe/internal/auth"
 	pb "github.com/alan-mat/awe/internal/proto"
+	"github.com/alan-mat/awe/internal/tasks"
+	"github.com/alan-mat/awe/internal/telemetry"
 	"github.com/alan-mat/awe/internal/transport"
 )
 
@@ -38,10 +49,77 @@ type ServerConfig struct {
 	ListenHost string
 	ListenPort int
 
+	// HTTPPort is the port of the HTTP/JSON gateway on ListenHost,
+	// which is disabled if not set.
+	HTTPPort int
+	// CORSOrigins are the origins browsers may call the HTTP gateway from,
+	// '*' allows all origins.
+	CORSOrigins []string
+	// MetricsPort is the port serving the Prometheus metrics at /metrics
+	// on ListenHost, which are not served if not set.
+	MetricsPort int
+
 	RedisAddr     string
 	RedisUsername string
 	RedisPassword string
 	RedisDB       int
+
+	Quota QuotaConfig
+
+	// CancelOnDisconnect cancels requests once all clients
+	// following them, with the request or Attach, disconnected.
+	CancelOnDisconnect bool
+
+	TLS TLSConfig
+
+	// Auth configures the authentication of clients, which is disabled if
+	// no API keys, JWT or client certificates are configured.
+	Auth []auth.Option
+}
+
+// TLSConfig configures TLS, which is disabled if no certificate is set.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile enables mutual TLS, clients must present a certificate
+	// signed by one of the CAs, unless ClientCertOptional is set.
+	ClientCAFile       string
+	ClientCertOptional bool
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+func (c TLSConfig) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file '%s'", c.ClientCAFile)
+		}
+
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+		if c.ClientCertOptional {
+			conf.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return conf, nil
 }
 
 func DefaultConfig() ServerConfig {
@@ -59,8 +137,10 @@ type Server struct {
 
 	rdb *redis.Client
 
-	transport   transport.Transport
-	asynqClient *asynq.Client
+	transport transport.Transport
+	queue     tasks.Enqueuer
+	// inspector looks up queued tasks, if the queue supports it
+	inspector tasks.Inspector
 }
 
 func New(config ServerConfig) *Server {
@@ -69,35 +149,141 @@ func New(config ServerConfig) *Server {
 	}
 }
 
-func (s Server) Serve() error {
+// NewWithBackend creates a Server which uses the given transport and task queue,
+// instead of connecting to Redis when serving.
+func NewWithBackend(config ServerConfig, t transport.Transport, q tasks.Enqueuer) *Server {
+	inspector, _ := q.(tasks.Inspector)
+	return &Server{
+		config:    config,
+		transport: t,
+		queue:     q,
+		inspector: inspector,
+	}
+}
+
+func (s *Server) Serve() error {
+	opts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	var tlsConfig *tls.Config
+	if s.config.TLS.enabled() {
+		var err error
+		tlsConfig, err = s.config.TLS.load()
+		if err != nil {
+			slog.Error("failed to configure TLS", "err", err)
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	authenticator, err := auth.New(s.config.Auth...)
+	if err != nil {
+		slog.Error("failed to configure authentication", "err", err)
+		return err
+	}
+	if authenticator != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+		)
+		if !s.config.TLS.enabled() {
+			slog.Warn("authentication is enabled without TLS, credentials are sent in plaintext")
+		}
+	} else {
+		slog.Warn("authentication is disabled, all clients may run all workflows")
+	}
+
 	lisAddr := fmt.Sprintf("%s:%d", s.config.ListenHost, s.config.ListenPort)
 	lis, err := net.Listen("tcp", lisAddr)
 	if err != nil {
 		slog.Error("failed to start server", "err", err)
+		return err
+	}
+
+	if s.transport == nil || s.queue == nil {
+		s.rdb = redis.NewClient(&redis.Options{
+			Addr:     s.config.RedisAddr,
+			Username: s.config.RedisUsername,
+			Password: s.config.RedisPassword,
+			DB:       s.config.RedisDB,
+		})
+		defer s.rdb.Close()
+
+		s.transport = transport.NewRedisTransport(s.rdb)
+
+		client := asynq.NewClientFromRedisClient(s.rdb)
+		defer client.Close()
+		s.queue = client
+
+		inspector := asynq.NewInspectorFromRedisClient(s.rdb)
+		s.inspector = tasks.NewAsynqInspector(inspector)
+		telemetry.SetQueueFunc(tasks.InspectQueues(inspector))
+	} else if q, ok := s.queue.(*tasks.MemoryQueue); ok {
+		telemetry.SetQueueFunc(q.Stats)
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     s.config.RedisAddr,
-		Username: s.config.RedisUsername,
-		Password: s.config.RedisPassword,
-		DB:       s.config.RedisDB,
-	})
-	defer rdb.Close()
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterAWEServiceServer(grpcServer, s)
 
-	t := transport.NewRedisTransport(rdb)
+	// the first server to fail stops the others
+	errc := make(chan error, 3)
 
-	client := asynq.NewClientFromRedisClient(rdb)
-	defer client.Close()
+	if s.config.HTTPPort != 0 {
+		httpAddr := fmt.Sprintf("%s:%d", s.config.ListenHost, s.config.HTTPPort)
+		httpLis, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			lis.Close()
+			slog.Error("failed to start http gateway", "err", err)
+			return err
+		}
+
+		// span names do not include the path, which holds ids of traces and sessions
+		handler := otelhttp.NewHandler(newGateway(s, authenticator), "gateway",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return "gateway " + r.Method
+			}),
+		)
+		httpServer := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		defer httpServer.Close()
+
+		slog.Info("HTTP gateway starting", "listener", httpAddr)
+		go func() {
+			if tlsConfig != nil {
+				httpServer.TLSConfig = tlsConfig.Clone()
+				errc <- httpServer.ServeTLS(httpLis, "", "")
+			} else {
+				errc <- httpServer.Serve(httpLis)
+			}
+		}()
+	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterAWEServiceServer(grpcServer, &Server{
-		rdb:         rdb,
-		transport:   t,
-		asynqClient: client,
-	})
+	if s.config.MetricsPort != 0 {
+		metricsAddr := fmt.Sprintf("%s:%d", s.config.ListenHost, s.config.MetricsPort)
+		metricsLis, err := net.Listen("tcp", metricsAddr)
+		if err != nil {
+			lis.Close()
+			slog.Error("failed to start metrics server", "err", err)
+			return err
+		}
+
+		metricsServer := telemetry.NewMetricsServer(metricsAddr)
+		defer metricsServer.Close()
+
+		slog.Info("Metrics server starting", "listener", metricsAddr)
+		go func() {
+			errc <- metricsServer.Serve(metricsLis)
+		}()
+	}
 
 	slog.Info("Server starting", "listener", lisAddr)
-	if err := grpcServer.Serve(lis); err != nil {
+	go func() {
+		errc <- grpcServer.Serve(lis)
+	}()
+
+	err = <-errc
+	grpcServer.Stop()
+	if err != nil {
 		slog.Error("failed to serve", "err", err)
 		return err
 	}
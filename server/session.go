@@ -0,0 +1,193 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alan-mat/awe/internal/api"
+	"github.com/alan-mat/awe/internal/auth"
+	pb "github.com/alan-mat/awe/internal/proto"
+	"github.com/alan-mat/awe/internal/tasks"
+	"github.com/alan-mat/awe/internal/transport"
+)
+
+func (s Server) CreateSession(ctx context.Context, req *pb.CreateSessionRequest) (*pb.Session, error) {
+	workflow := req.WorkflowId
+	if workflow == "" {
+		workflow = tasks.DefaultWorkflowChat
+	}
+
+	now := time.Now().UnixNano()
+	session := &transport.Session{
+		ID:        uuid.NewString(),
+		User:      requestUser(ctx, req.User),
+		Workflow:  workflow,
+		Args:      req.Args,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.transport.SetSession(ctx, session); err != nil {
+		slog.Error("failed to create session", "err", err)
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+	slog.Debug("created session", "id", session.ID, "user", session.User, "workflowId", session.Workflow)
+
+	return sessionToProto(session), nil
+}
+
+func (s Server) GetSession(ctx context.Context, req *pb.GetSessionRequest) (*pb.Session, error) {
+	session, err := s.getSession(ctx, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return sessionToProto(session), nil
+}
+
+func (s Server) DeleteSession(ctx context.Context, req *pb.DeleteSessionRequest) (*pb.DeleteSessionResponse, error) {
+	if _, err := s.getSession(ctx, req.SessionId); err != nil {
+		return nil, err
+	}
+	if err := s.transport.DeleteSession(ctx, req.SessionId); err != nil {
+		slog.Error("failed to delete session", "id", req.SessionId, "err", err)
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+	return &pb.DeleteSessionResponse{}, nil
+}
+
+// ChatSession runs the turns received on the stream one after another. The
+// responses of a turn end with a message with the status 'DONE', after which
+// the next turn is read. A failed turn ends the stream, the session is kept
+// and can be continued on a new stream.
+func (s Server) ChatSession(stream pb.AWEService_ChatSessionServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.sessionTurn(req, stream); err != nil {
+			return err
+		}
+
+		err = stream.Send(&pb.SessionResponse{
+			SessionId: req.SessionId,
+			Status:    "DONE",
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sessionTurn runs a single turn of a session and streams its responses.
+func (s Server) sessionTurn(req *pb.SessionRequest, stream grpc.ServerStreamingServer[pb.SessionResponse]) error {
+	slog.Debug("received session request", "session", req.SessionId, "query", req.Query, "args", req.GetArgs())
+
+	ctx := stream.Context()
+	session, err := s.getSession(ctx, req.SessionId)
+	if err != nil {
+		return err
+	}
+
+	newTask := func(ctx context.Context) (*asynq.Task, error) {
+		return tasks.NewSessionTask(ctx, session, req, auth.FromContext(ctx))
+	}
+	traceID, err := s.enqueue(stream, newTask, session.User, workflowName(session.Workflow))
+	if err != nil {
+		return err
+	}
+
+	tstream, err := s.transport.GetMessageStream(traceID)
+	if err != nil {
+		slog.Error("failed to retrieve stream", "id", traceID)
+		return status.Errorf(codes.Internal, "internal server error")
+	}
+
+	var respFunc messageResponseFunc[pb.SessionResponse] = func(msg *transport.MessageStreamPayload, traceID string) *pb.SessionResponse {
+		resp := &pb.SessionResponse{
+			MsgId:     int32(msg.ID),
+			SessionId: session.ID,
+			TraceId:   traceID,
+			Status:    msg.Status,
+		}
+
+		switch msg.Type {
+		case transport.MessageTypeContent:
+			resp.Payload = &pb.SessionResponse_Content{
+				Content: msg.Content,
+			}
+
+		case transport.MessageTypeDocument:
+			resp.Payload = &pb.SessionResponse_Document{
+				Document: &pb.Document{
+					Title:   msg.Document.Title,
+					Content: msg.Document.Content,
+					Source:  msg.Document.Source,
+				},
+			}
+		}
+
+		return resp
+	}
+
+	defer s.follow(ctx, traceID)()
+	return handleMessageStream(ctx, traceID, tstream, stream, respFunc)
+}
+
+// getSession returns the session with the given id, if the principal of ctx may read it.
+func (s Server) getSession(ctx context.Context, id string) (*transport.Session, error) {
+	session, err := s.transport.GetSession(ctx, id)
+	if errors.Is(err, transport.ErrSessionNotFound) || (err == nil && !canRead(ctx, session.User)) {
+		return nil, status.Errorf(codes.NotFound, "session with given id does not exist")
+	}
+	if err != nil {
+		slog.Error("failed to retrieve session", "id", id, "err", err)
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+	return session, nil
+}
+
+func sessionToProto(s *transport.Session) *pb.Session {
+	return &pb.Session{
+		SessionId:  s.ID,
+		User:       s.User,
+		WorkflowId: s.Workflow,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+		History:    api.ChatHistoryToProto(s.History),
+		Summary:    s.Summary,
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Alan Matykiewicz
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to use,
+// copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the
+// Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT
+// HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+// WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+// OTHER DEALINGS IN THE SOFTWARE.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alan-mat/awe/internal/config"
+	"github.com/alan-mat/awe/internal/registry"
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay debounces bursts of file events, e.g. editors
+// writing a file in several steps.
+const reloadDelay = 250 * time.Millisecond
+
+// WatchWorkflows watches the workflows at path and reloads them on change.
+// A reload only takes effect if all workflows parse and validate, otherwise
+// the error is logged and the current workflows stay registered. Tasks that
+// are already running finish on the workflow they started with.
+// It blocks until ctx is cancelled.
+func (w *Worker) WatchWorkflows(ctx context.Context, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to watch workflows: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch workflows: %w", err)
+	}
+	defer watcher.Close()
+
+	// watch the parent directory of single files, so that files replaced
+	// by a rename (as most editors do on save) are still picked up
+	dir, file := path, ""
+	if !info.IsDir() {
+		dir, file = filepath.Dir(path), filepath.Clean(path)
+	}
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch workflows: %w", err)
+	}
+	slog.Info("watching workflows", "path", path)
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if file != "" && filepath.Clean(event.Name) != file {
+				continue
+			}
+			if file == "" && !config.IsWorkflowFile(event.Name) {
+				continue
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("workflow watcher error", "err", err)
+		case <-timer.C:
+			reloadWorkflows(path)
+		}
+	}
+}
+
+func (w *Worker) watch(ctx context.Context) {
+	if w.workflowsPath == "" {
+		return
+	}
+	if err := w.WatchWorkflows(ctx, w.workflowsPath); err != nil {
+		slog.Error("failed to watch workflows", "err", err)
+	}
+}
+
+func reloadWorkflows(path string) {
+	workflows, err := config.LoadWorkflows(path)
+	if err != nil {
+		slog.Error("failed to reload workflows, keeping current workflows", "path", path, "err", err)
+		return
+	}
+	if err := registry.ReplaceWorkflows(workflows); err != nil {
+		slog.Error("failed to reload workflows, keeping current workflows", "path", path, "err", err)
+		return
+	}
+	slog.Info("reloaded workflows", "path", path, "workflows", registry.ListWorkflows())
+}
@@ -22,24 +22,25 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/alan-mat/awe/internal/config"
+	"github.com/alan-mat/awe/internal/modules"
+	"github.com/alan-mat/awe/internal/provider"
 	"github.com/alan-mat/awe/internal/registry"
 	"github.com/alan-mat/awe/internal/tasks"
+	"github.com/alan-mat/awe/internal/telemetry"
 	"github.com/alan-mat/awe/internal/transport"
+	"github.com/alan-mat/awe/internal/usage"
 	"github.com/alan-mat/awe/internal/vector"
 	"github.com/hibiken/asynq"
 
 	"github.com/redis/go-redis/v9"
-
-	_ "github.com/alan-mat/awe/internal/modules/generation"
-	_ "github.com/alan-mat/awe/internal/modules/indexing"
-	_ "github.com/alan-mat/awe/internal/modules/orchestration"
-	_ "github.com/alan-mat/awe/internal/modules/postretrieval"
-	_ "github.com/alan-mat/awe/internal/modules/preretrieval"
-	_ "github.com/alan-mat/awe/internal/modules/retrieval"
-	_ "github.com/alan-mat/awe/internal/modules/system"
 )
 
 type WorkerConfig struct {
@@ -52,6 +53,28 @@ type WorkerConfig struct {
 
 	QdrantHost string
 	QdrantPort int
+
+	// MetricsPort is the port serving the Prometheus metrics at /metrics,
+	// which are not served if not set.
+	MetricsPort int
+
+	// TraceRetention is how long traces are kept, unless their workflow
+	// sets a retention of its own. It defaults to transport.TraceExpiry.
+	TraceRetention time.Duration
+
+	// WatchWorkflows reloads the registered workflows whenever
+	// the workflow files change on disk.
+	WatchWorkflows bool
+
+	// Providers are the named provider instances, see provider.Configure.
+	Providers map[string]provider.Instance
+
+	// ProviderDefaults maps capabilities to the providers used when
+	// a node does not select one, e.g. 'lm' to 'ollama/llama3.1:8b'.
+	ProviderDefaults map[string]string
+
+	// Prices are used to price the usage of providers, see usage.Prices.
+	Prices usage.Prices
 }
 
 func DefaultConfig() WorkerConfig {
@@ -71,6 +94,9 @@ type Worker struct {
 
 	transport   transport.Transport
 	vectorStore vector.Store
+
+	workflowsPath string
+	ready         bool
 }
 
 func New(config WorkerConfig) *Worker {
@@ -79,9 +105,14 @@ func New(config WorkerConfig) *Worker {
 	}
 }
 
-func (w Worker) RegisterWorkflows(path string) error {
-	wc := config.ReadConfig(path)
-	workflows, err := config.ParseWorkflows(wc)
+// RegisterWorkflows registers the workflows found at path, which may be
+// a single workflows file or a directory of them.
+func (w *Worker) RegisterWorkflows(path string) error {
+	if err := w.setup(); err != nil {
+		return err
+	}
+
+	workflows, err := config.LoadWorkflows(path)
 	if err != nil {
 		return fmt.Errorf("failed to parse workflows config: %v", err)
 	}
@@ -90,10 +121,16 @@ func (w Worker) RegisterWorkflows(path string) error {
 	if err != nil {
 		return fmt.Errorf("failed to register workflows: %v", err)
 	}
+	w.workflowsPath = path
 	return nil
 }
 
 func (w *Worker) Start() error {
+	if err := w.setup(); err != nil {
+		return err
+	}
+	w.checkExecutors()
+
 	w.rdb = redis.NewClient(&redis.Options{
 		Addr:     w.config.RedisAddr,
 		Username: w.config.RedisUsername,
@@ -111,6 +148,10 @@ func (w *Worker) Start() error {
 
 	w.transport = transport.NewRedisTransport(w.rdb)
 
+	telemetry.SetQueueFunc(tasks.InspectQueues(asynq.NewInspectorFromRedisClient(w.rdb)))
+	stop := w.serveMetrics()
+	defer stop()
+
 	vs, err := vector.NewQdrantStore(w.config.QdrantHost, w.config.QdrantPort)
 	if err != nil {
 		return fmt.Errorf("failed to initialize vector store: %w", err)
@@ -118,9 +159,106 @@ func (w *Worker) Start() error {
 	w.vectorStore = vs
 	defer w.vectorStore.Close()
 
-	handler := tasks.NewTaskHandler(w.transport, w.vectorStore)
+	if w.config.WatchWorkflows {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.watch(ctx)
+	}
+
+	handler := tasks.NewTaskHandler(w.transport, w.vectorStore, tasks.WithTraceRetention(w.config.TraceRetention))
 	if err := w.asynqServer.Run(handler); err != nil {
 		return err
 	}
 	return nil
 }
+
+// RunInMemory runs the worker in-process, processing tasks from the given
+// memory queue. It uses the given transport and an in-memory vector store,
+// so neither Redis nor Qdrant are required. It blocks until ctx is cancelled.
+func (w *Worker) RunInMemory(ctx context.Context, q *tasks.MemoryQueue, t transport.Transport) error {
+	if err := w.setup(); err != nil {
+		return err
+	}
+	w.checkExecutors()
+
+	w.transport = t
+	w.vectorStore = vector.NewMemoryStore()
+	defer w.vectorStore.Close()
+
+	telemetry.SetQueueFunc(q.Stats)
+	stop := w.serveMetrics()
+	defer stop()
+
+	if w.config.WatchWorkflows {
+		go w.watch(ctx)
+	}
+
+	handler := tasks.NewTaskHandler(w.transport, w.vectorStore, tasks.WithTraceRetention(w.config.TraceRetention))
+	return q.Run(ctx, handler, w.config.Workers)
+}
+
+// serveMetrics serves the metrics on MetricsPort, if set,
+// until the returned function is called.
+func (w *Worker) serveMetrics() func() {
+	if w.config.MetricsPort == 0 {
+		return func() {}
+	}
+
+	srv := telemetry.NewMetricsServer(fmt.Sprintf(":%d", w.config.MetricsPort))
+	slog.Info("Metrics server starting", "listener", srv.Addr)
+	go func() {
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("failed to serve metrics", "err", err)
+		}
+	}()
+	return func() { srv.Close() }
+}
+
+// setup registers the modules and configures the providers,
+// which must happen before any workflow is parsed.
+func (w *Worker) setup() error {
+	if w.ready {
+		return nil
+	}
+
+	if err := modules.RegisterAll(); err != nil {
+		return fmt.Errorf("failed to register modules: %w", err)
+	}
+	if err := w.configureProviders(); err != nil {
+		return err
+	}
+	w.ready = true
+	return nil
+}
+
+// checkExecutors creates all registered executors,
+// reporting those which are unavailable and why.
+func (w *Worker) checkExecutors() {
+	available := make([]string, 0)
+	for _, s := range registry.InitExecutors() {
+		if s.Err != nil {
+			slog.Warn("executor unavailable", "name", s.Name, "providers", s.Meta.Capabilities, "err", s.Err)
+			continue
+		}
+		available = append(available, s.Name)
+	}
+	slog.Info("executors available", "names", available)
+}
+
+func (w *Worker) configureProviders() error {
+	if err := provider.Configure(w.config.Providers); err != nil {
+		return fmt.Errorf("invalid provider instances: %w", err)
+	}
+
+	defaults := make(map[provider.Capability]string, len(w.config.ProviderDefaults))
+	for c, ref := range w.config.ProviderDefaults {
+		defaults[provider.Capability(c)] = ref
+	}
+
+	if err := provider.SetDefaults(defaults); err != nil {
+		return fmt.Errorf("invalid provider defaults: %w", err)
+	}
+
+	usage.SetPrices(w.config.Prices)
+	return nil
+}